@@ -0,0 +1,151 @@
+package dns
+
+// A builder of messages in wire format.
+
+// Builder appends a message in wire format to a buffer, question by question
+// and record by record, without building a Msg first. The sections must be
+// built in order: adding to a section after adding to a later one returns
+// ErrSectionDone. Basic use pattern for building a reply:
+//
+//	b := dns.NewBuilder(buf[:0], dns.MsgHdr{Id: id, Response: true})
+//	b.EnableCompression()
+//	err := b.Question(dns.Question{Name: "www.example.org.", Qtype: dns.TypeA, Qclass: dns.ClassINET})
+//	...
+//	err = b.Answer(rr)
+//	...
+//	msg, err := b.Finish()
+type Builder struct {
+	msg         []byte
+	start       int // the offset of the message in msg
+	header      MsgHdr
+	section     int
+	counts      [4]uint16
+	compression *compressionTable
+}
+
+// NewBuilder returns a Builder that appends a message with header h to buf.
+// The rcode of h must fit in the 4 bits of the header, a larger one needs an
+// OPT RR with the extended rcode.
+func NewBuilder(buf []byte, h MsgHdr) *Builder {
+	b := &Builder{msg: buf, start: len(buf), header: h}
+	b.msg = append(b.msg, make([]byte, headerSize)...)
+	return b
+}
+
+// EnableCompression compresses the names of the questions and records that
+// are added after it is called, see PackDomainName.
+func (b *Builder) EnableCompression() {
+	if b.compression == nil {
+		b.compression = newCompressionTable()
+	}
+}
+
+// Question appends q to the question section.
+func (b *Builder) Question(q Question) error {
+	if err := b.advance(sectionQuestion); err != nil {
+		return err
+	}
+	msg := b.grow(len(q.Name) + 1 + 4)
+	n := b.names()
+	off, err := q.pack(msg, len(msg)-len(q.Name)-1-4, compressionMap{table: b.compression}, b.compression != nil)
+	if err != nil {
+		b.undo(len(q.Name)+1+4, n)
+		return err
+	}
+	b.msg = b.msg[:b.start+off]
+	b.counts[sectionQuestion]++
+	return nil
+}
+
+// Answer appends rr to the answer section.
+func (b *Builder) Answer(rr RR) error { return b.add(sectionAnswer, rr) }
+
+// Authority appends rr to the authority section.
+func (b *Builder) Authority(rr RR) error { return b.add(sectionAuthority, rr) }
+
+// Additional appends rr to the additional section.
+func (b *Builder) Additional(rr RR) error { return b.add(sectionAdditional, rr) }
+
+// Finish writes the header of the message and returns the buffer with the
+// message appended. The Builder must not be used after it.
+func (b *Builder) Finish() ([]byte, error) {
+	if b.header.Rcode < 0 || b.header.Rcode > 0xF {
+		return nil, ErrRcode
+	}
+	dh := Header{
+		Id:      b.header.Id,
+		Bits:    b.header.bits(),
+		Qdcount: b.counts[sectionQuestion],
+		Ancount: b.counts[sectionAnswer],
+		Nscount: b.counts[sectionAuthority],
+		Arcount: b.counts[sectionAdditional],
+	}
+	if _, err := dh.pack(b.msg[b.start:], 0, compressionMap{}, false); err != nil {
+		return nil, err
+	}
+	b.section = sectionDone
+	return b.msg, nil
+}
+
+// add appends rr to section sec.
+func (b *Builder) add(sec int, rr RR) error {
+	if rr == nil {
+		return &Error{err: "nil rr"}
+	}
+	if err := b.advance(sec); err != nil {
+		return err
+	}
+	l := rr.len() + 1
+	msg := b.grow(l)
+	n := b.names()
+	off, err := packRR(rr, msg, len(msg)-l, compressionMap{table: b.compression}, b.compression != nil)
+	if err != nil {
+		b.undo(l, n)
+		return err
+	}
+	b.msg = b.msg[:b.start+off]
+	b.counts[sec]++
+	return nil
+}
+
+// advance moves b to section sec.
+func (b *Builder) advance(sec int) error {
+	if b.section > sec {
+		return ErrSectionDone
+	}
+	if b.counts[sec] == 0xFFFF {
+		return &Error{err: "too many records in section"}
+	}
+	b.section = sec
+	return nil
+}
+
+// names returns the number of names in the compression table.
+func (b *Builder) names() int {
+	if b.compression == nil {
+		return 0
+	}
+	return len(b.compression.names)
+}
+
+// undo removes the n octets that were added to the buffer for a question or
+// record that failed to pack, and the names that were added to the
+// compression table since it held names of them, as those point into the
+// removed octets.
+func (b *Builder) undo(n, names int) {
+	b.msg = b.msg[:len(b.msg)-n]
+	if b.compression != nil {
+		b.compression.truncate(names)
+	}
+}
+
+// grow extends the buffer by n octets and returns the message in it.
+func (b *Builder) grow(n int) []byte {
+	if cap(b.msg)-len(b.msg) < n {
+		msg := make([]byte, len(b.msg), 2*cap(b.msg)+n)
+		copy(msg, b.msg)
+		b.msg = msg
+	}
+	b.msg = b.msg[:len(b.msg)+n]
+	return b.msg[b.start:]
+}
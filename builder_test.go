@@ -0,0 +1,111 @@
+package dns
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func TestBuilder(t *testing.T) {
+	m := new(Msg)
+	m.SetQuestion("www.example.org.", TypeA)
+	m.Response = true
+	m.Authoritative = true
+	m.Answer = []RR{
+		testRR("www.example.org. 300 IN CNAME web.example.org."),
+		testRR("web.example.org. 300 IN A 192.0.2.1"),
+	}
+	m.Ns = []RR{testRR("example.org. 300 IN NS ns.example.org.")}
+	m.Extra = []RR{testRR("ns.example.org. 300 IN A 192.0.2.53")}
+
+	for _, compress := range []bool{false, true} {
+		m.Compress = compress
+		want, err := m.Pack()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// A buffer with a prefix, like the length of a message over TCP.
+		b := NewBuilder([]byte{0, 0}, m.MsgHdr)
+		if compress {
+			b.EnableCompression()
+		}
+		if err := b.Question(m.Question[0]); err != nil {
+			t.Fatal(err)
+		}
+		for _, rr := range m.Answer {
+			if err := b.Answer(rr); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := b.Authority(m.Ns[0]); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Additional(m.Extra[0]); err != nil {
+			t.Fatal(err)
+		}
+		buf, err := b.Finish()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(buf[2:], want) {
+			t.Errorf("compress %t: got message\n%x, want\n%x", compress, buf[2:], want)
+		}
+	}
+}
+
+func TestBuilderErrors(t *testing.T) {
+	b := NewBuilder(nil, MsgHdr{Id: 1})
+	if err := b.Answer(testRR("example.org. IN A 192.0.2.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Question(Question{"example.org.", TypeA, ClassINET}); err != ErrSectionDone {
+		t.Errorf("got error %v adding a question after an answer", err)
+	}
+	if err := b.Answer(&A{Hdr: RR_Header{Name: "example.org", Rrtype: TypeA, Class: ClassINET}}); err == nil {
+		t.Error("added a record with a name that is not fully qualified")
+	}
+	buf, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Question) != 0 || len(m.Answer) != 1 || m.Id != 1 {
+		t.Errorf("got message %s", m)
+	}
+
+	b = NewBuilder(nil, MsgHdr{Rcode: RcodeBadCookie})
+	if _, err := b.Finish(); err != ErrRcode {
+		t.Errorf("got error %v for an extended rcode", err)
+	}
+}
+
+func TestBuilderErrorsCompression(t *testing.T) {
+	b := NewBuilder(nil, MsgHdr{Id: 1})
+	b.EnableCompression()
+	// The name is packed before the address is found to be invalid.
+	bad := &A{Hdr: RR_Header{Name: "x.example.org.", Rrtype: TypeA, Class: ClassINET}, A: net.IP{1, 2, 3}}
+	if err := b.Answer(bad); err == nil {
+		t.Fatal("added a record with an invalid address")
+	}
+	if err := b.Answer(testRR("x.example.org. IN A 192.0.2.1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Answer(testRR("y.example.org. IN A 192.0.2.2")); err != nil {
+		t.Fatal(err)
+	}
+	buf, err := b.Finish()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := new(Msg)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if len(m.Answer) != 2 || m.Answer[0].Header().Name != "x.example.org." || m.Answer[1].Header().Name != "y.example.org." {
+		t.Errorf("got message %s", m)
+	}
+}
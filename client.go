@@ -5,6 +5,7 @@ package dns
 import (
 	"bytes"
 	"context"
+	"crypto"
 	"crypto/tls"
 	"encoding/binary"
 	"io"
@@ -21,6 +22,10 @@ type Conn struct {
 	net.Conn                         // a net.Conn holding the connection
 	UDPSize        uint16            // minimum receive buffer for UDP messages
 	TsigSecret     map[string]string // secret(s) for Tsig map[<zonename>]<base64 secret>, zonename must be in canonical form (lowercase, fqdn, see RFC 4034 Section 6.2)
+	TsigProvider   TsigProvider      // looks up Tsig secrets at runtime, takes precedence over TsigSecret
+	Sig0Key        *KEY              // the KEY to sign messages without a TSIG with, using SIG(0)
+	Sig0Signer     crypto.Signer     // the private key of Sig0Key
+	Sig0Provider   Sig0KeyProvider   // looks up the KEY to verify SIG(0) signed messages with
 	tsigRequestMAC string
 }
 
@@ -38,6 +43,10 @@ type Client struct {
 	ReadTimeout    time.Duration     // net.Conn.SetReadTimeout value for connections, defaults to 2 seconds - overridden by Timeout when that value is non-zero
 	WriteTimeout   time.Duration     // net.Conn.SetWriteTimeout value for connections, defaults to 2 seconds - overridden by Timeout when that value is non-zero
 	TsigSecret     map[string]string // secret(s) for Tsig map[<zonename>]<base64 secret>, zonename must be in canonical form (lowercase, fqdn, see RFC 4034 Section 6.2)
+	TsigProvider   TsigProvider      // looks up Tsig secrets at runtime, takes precedence over TsigSecret
+	Sig0Key        *KEY              // the KEY to sign messages without a TSIG with, using SIG(0)
+	Sig0Signer     crypto.Signer     // the private key of Sig0Key
+	Sig0Provider   Sig0KeyProvider   // looks up the KEY to verify SIG(0) signed responses with
 	SingleInflight bool              // if true suppress multiple outstanding queries for the same Qname, Qtype and Qclass
 	group          singleflight
 }
@@ -175,6 +184,8 @@ func (c *Client) exchange(m *Msg, a string) (r *Msg, rtt time.Duration, err erro
 	}
 
 	co.TsigSecret = c.TsigSecret
+	co.TsigProvider = c.TsigProvider
+	co.Sig0Key, co.Sig0Signer, co.Sig0Provider = c.Sig0Key, c.Sig0Signer, c.Sig0Provider
 	t := time.Now()
 	// write with the appropriate write timeout
 	co.SetWriteDeadline(t.Add(c.getTimeoutForRequest(c.writeTimeout())))
@@ -193,7 +204,7 @@ func (c *Client) exchange(m *Msg, a string) (r *Msg, rtt time.Duration, err erro
 
 // ReadMsg reads a message from the connection co.
 // If the received message contains a TSIG record the transaction signature
-// is verified. This method always tries to return the message, however if an
+// is verified, as is a SIG(0) if Sig0Provider is set. This method always tries to return the message, however if an
 // error is returned there are no guarantees that the returned message is a
 // valid representation of the packet read.
 func (co *Conn) ReadMsg() (*Msg, error) {
@@ -210,11 +221,15 @@ func (co *Conn) ReadMsg() (*Msg, error) {
 		return m, err
 	}
 	if t := m.IsTsig(); t != nil {
-		if _, ok := co.TsigSecret[t.Hdr.Name]; !ok {
-			return m, ErrSecret
+		k, err := tsigLookup(tsigProvider(co.TsigProvider, co.TsigSecret), t)
+		if err != nil {
+			return m, err
 		}
 		// Need to work on the original message p, as that was used to calculate the tsig.
-		err = TsigVerify(p, co.TsigSecret[t.Hdr.Name], co.tsigRequestMAC, false)
+		return m, k.verify(p, co.tsigRequestMAC, false)
+	}
+	if sig := m.IsSig0(); sig != nil && co.Sig0Provider != nil {
+		return m, sig0Verify(co.Sig0Provider, sig, p)
 	}
 	return m, err
 }
@@ -342,17 +357,21 @@ func (co *Conn) Read(p []byte) (n int, err error) {
 
 // WriteMsg sends a message through the connection co.
 // If the message m contains a TSIG record the transaction
-// signature is calculated.
+// signature is calculated, otherwise it is signed with SIG(0) if
+// Sig0Key and Sig0Signer are set.
 func (co *Conn) WriteMsg(m *Msg) (err error) {
 	var out []byte
 	if t := m.IsTsig(); t != nil {
-		mac := ""
-		if _, ok := co.TsigSecret[t.Hdr.Name]; !ok {
-			return ErrSecret
+		var k tsigKey
+		if k, err = tsigLookup(tsigProvider(co.TsigProvider, co.TsigSecret), t); err != nil {
+			return err
 		}
-		out, mac, err = TsigGenerate(m, co.TsigSecret[t.Hdr.Name], co.tsigRequestMAC, false)
+		mac := ""
+		out, mac, err = k.generate(m, co.tsigRequestMAC, false)
 		// Set for the next read, although only used in zone transfers
 		co.tsigRequestMAC = mac
+	} else if co.Sig0Key != nil && co.Sig0Signer != nil {
+		out, err = sig0Sign(co.Sig0Key, co.Sig0Signer, m)
 	} else {
 		out, err = m.Pack()
 	}
@@ -0,0 +1,295 @@
+package dns
+
+import (
+	"strings"
+	"sync"
+)
+
+// Tables of the names in a message, used for name compression.
+
+// compressionMap holds the names packed in a message and their offsets, so
+// later names can point to them. It is either the map given to PackDomainName
+// or PackRR, or a compressionTable.
+type compressionMap struct {
+	ext   map[string]int
+	table *compressionTable
+}
+
+// compressionTable is an open addressing hash table of the names in a
+// message and their suffixes, with the offset they can be pointed to at.
+// The hash of a suffix is computed from its first label and the hash of the
+// rest of it, so the hashes of all suffixes of a name are computed in one
+// pass over it. The longest known suffix is then usually found with one or
+// two probes. The suffixes of names with escapes are kept under the keys the
+// compression map before it used, see mapKeys.
+//
+// The suffixes are substrings of the names that are packed, and the table is
+// reused by way of getCompressionTable and putCompressionTable, so once it has
+// grown it packs messages without allocating.
+type compressionTable struct {
+	names []compressionName
+	slots []int32 // index of the name plus one, 0 when empty
+
+	// Scratch space for the labels of a name, their offsets and the
+	// suffixes starting at them with their hashes.
+	lbs, offs []int
+	keys      []string
+	hashes    []uint32
+}
+
+type compressionName struct {
+	name string // in presentation format, or its key, see mapKeys
+	off  int
+	hash uint32
+}
+
+const (
+	compressionSeed     = 2166136261 // the FNV offset basis
+	minCompressionSlots = 64
+	maxCompressionSlots = 1 << 12 // larger tables are not reused
+)
+
+var compressionPool = sync.Pool{
+	New: func() interface{} { return newCompressionTable() },
+}
+
+// newCompressionTable returns an empty compressionTable.
+func newCompressionTable() *compressionTable {
+	return &compressionTable{
+		names:  make([]compressionName, 0, minCompressionSlots/2),
+		slots:  make([]int32, minCompressionSlots),
+		lbs:    make([]int, 0, 16),
+		offs:   make([]int, 0, 16),
+		keys:   make([]string, 0, 16),
+		hashes: make([]uint32, 0, 16),
+	}
+}
+
+// getCompressionTable returns an empty compressionTable from the pool.
+func getCompressionTable() *compressionTable {
+	return compressionPool.Get().(*compressionTable)
+}
+
+// putCompressionTable empties t and returns it to the pool.
+func putCompressionTable(t *compressionTable) {
+	if len(t.slots) > maxCompressionSlots {
+		return
+	}
+	// Clear the names as well, they keep the RRs of the message alive.
+	for i := range t.names {
+		t.names[i] = compressionName{}
+	}
+	for i := range t.slots {
+		t.slots[i] = 0
+	}
+	for i := range t.keys {
+		t.keys[i] = ""
+	}
+	t.names, t.keys = t.names[:0], t.keys[:0]
+	compressionPool.Put(t)
+}
+
+// suffixes sets t.keys to the suffixes of the name s that start at its labels
+// ls, see labelOffsets, and t.hashes to their hashes.
+func (t *compressionTable) suffixes(s string, ls []int) {
+	t.keys, t.hashes = t.keys[:0], t.hashes[:0]
+	for i := 0; i < len(ls)-1; i++ {
+		t.keys = append(t.keys, s[ls[i]:])
+		t.hashes = append(t.hashes, 0)
+	}
+	h := uint32(compressionSeed)
+	for i := len(ls) - 2; i >= 0; i-- {
+		h = compressionHash(h, s[ls[i]:ls[i+1]-1])
+		t.hashes[i] = h
+	}
+}
+
+// mapSuffixes sets t.keys to the keys of the suffixes of the name s with
+// escapes, see mapKeys, and t.hashes to their hashes.
+func (t *compressionTable) mapSuffixes(s string, compress bool) {
+	t.keys, t.hashes = mapKeys(s, compress, t.keys[:0]), t.hashes[:0]
+	for _, k := range t.keys {
+		t.hashes = append(t.hashes, keyHash(k))
+	}
+}
+
+// search returns the index of the first of t.keys that is in the table, and
+// its offset. The index is -1 if none is found.
+func (t *compressionTable) search() (int, int) {
+	for i, k := range t.keys {
+		if n, _ := t.find(k, t.hashes[i]); n >= 0 {
+			return i, t.names[n].off
+		}
+	}
+	return -1, -1
+}
+
+// insert adds the first end of t.keys to the table. The key i gets the offset
+// offs[i], unless it is already known or the offset does not fit in a
+// compression pointer. When offs is nil all keys get offset 0, which is
+// enough for computing lengths.
+func (t *compressionTable) insert(offs []int, end int) {
+	for i := 0; i < end; i++ {
+		off := 0
+		if offs != nil {
+			if off = offs[i]; off >= maxCompressionOffset {
+				return // the offsets only grow
+			}
+		}
+		n, slot := t.find(t.keys[i], t.hashes[i])
+		if n >= 0 {
+			continue
+		}
+		t.names = append(t.names, compressionName{name: t.keys[i], off: off, hash: t.hashes[i]})
+		t.slots[slot] = int32(len(t.names))
+		if 2*len(t.names) > len(t.slots) {
+			t.grow()
+		}
+	}
+}
+
+// find returns the index of name in t.names, or -1 and the slot to put it in.
+// The hash h must be the hash of name.
+func (t *compressionTable) find(name string, h uint32) (int, uint32) {
+	mask := uint32(len(t.slots) - 1)
+	for i := h & mask; ; i = (i + 1) & mask {
+		n := t.slots[i]
+		if n == 0 {
+			return -1, i
+		}
+		if c := &t.names[n-1]; c.hash == h && c.name == name {
+			return int(n - 1), i
+		}
+	}
+}
+
+// truncate removes the names added to t after it held n of them. Their slots
+// can be cleared without breaking the probe sequences of the names that stay,
+// those never run past a slot that was filled later.
+func (t *compressionTable) truncate(n int) {
+	mask := uint32(len(t.slots) - 1)
+	for k := len(t.names) - 1; k >= n; k-- {
+		i := t.names[k].hash & mask
+		for t.slots[i] != int32(k+1) {
+			i = (i + 1) & mask
+		}
+		t.slots[i] = 0
+		t.names[k] = compressionName{}
+	}
+	t.names = t.names[:n]
+}
+
+// grow doubles the number of slots in t.
+func (t *compressionTable) grow() {
+	t.slots = make([]int32, 2*len(t.slots))
+	mask := uint32(len(t.slots) - 1)
+	for n := range t.names {
+		i := t.names[n].hash & mask
+		for t.slots[i] != 0 {
+			i = (i + 1) & mask
+		}
+		t.slots[i] = int32(n + 1)
+	}
+}
+
+// compressionHash returns the FNV-1a hash of label, seeded with h, the hash of
+// the labels after it. It is taken four octets at a time, so the high bits are
+// folded into the low ones that pick the slot.
+func compressionHash(h uint32, label string) uint32 {
+	const prime = 16777619
+	h = (h ^ uint32(len(label))) * prime
+	for ; len(label) >= 4; label = label[4:] {
+		h = (h ^ (uint32(label[0]) | uint32(label[1])<<8 | uint32(label[2])<<16 | uint32(label[3])<<24)) * prime
+	}
+	for i := 0; i < len(label); i++ {
+		h = (h ^ uint32(label[i])) * prime
+	}
+	return h ^ h>>16
+}
+
+// keyHash returns the hash of the key k, as suffixes computes it for the
+// suffixes of a name.
+func keyHash(k string) uint32 {
+	var buf [16]int
+	ls := labelOffsets(k, buf[:0])
+	h := uint32(compressionSeed)
+	for i := len(ls) - 2; i >= 0; i-- {
+		h = compressionHash(h, k[ls[i]:ls[i+1]-1])
+	}
+	return h
+}
+
+// mapKeys appends to keys the keys under which the compression map that came
+// before compressionTable stored the suffixes of the name s with escapes, one
+// for each label, and returns the extended slice. The map unescaped a copy of
+// s in place while packing it, and keyed the suffixes on that copy, so after
+// an escape the keys are partly unescaped, with the last octets of s
+// repeated at their end. Names with escapes are compressed with these keys
+// to keep the packed messages the same.
+func mapKeys(s string, compress bool, keys []string) []string {
+	bs := []byte(s)
+	ls := len(bs)
+	key, fresh := s, true
+	begin := 0
+	for i := 0; i < ls; i++ {
+		if bs[i] == '\\' {
+			for j := i; j < ls-1; j++ {
+				bs[j] = bs[j+1]
+			}
+			ls--
+			if i+2 < ls && isDigit(bs[i]) && isDigit(bs[i+1]) && isDigit(bs[i+2]) {
+				bs[i] = dddToByte(bs[i:])
+				for j := i + 1; j < ls-2; j++ {
+					bs[j] = bs[j+2]
+				}
+				ls -= 2
+			}
+			fresh = false
+			continue
+		}
+		if bs[i] == '.' {
+			// Without compression the map kept s itself.
+			if compress && !fresh {
+				key, fresh = string(bs), true
+			}
+			keys = append(keys, key[begin:])
+			begin = i + 1
+		}
+	}
+	return keys
+}
+
+// labelOffsets appends to ls the offsets of the labels of the domain name s in
+// presentation format, followed by the offset just after the dot that ends the
+// last one, and returns the extended slice. Escaped dots do not end a label,
+// and neither does the end of s. The root label has no labels.
+func labelOffsets(s string, ls []int) []int {
+	if s == "." {
+		return ls
+	}
+	n, begin := len(ls), 0
+	if strings.IndexByte(s, '\\') < 0 {
+		for i := strings.IndexByte(s, '.'); i >= 0; i = strings.IndexByte(s[begin:], '.') {
+			ls = append(ls, begin)
+			begin += i + 1
+		}
+	} else {
+		for i := 0; i < len(s); i++ {
+			switch s[i] {
+			case '\\':
+				if i+3 < len(s) && isDigit(s[i+1]) && isDigit(s[i+2]) && isDigit(s[i+3]) {
+					i += 3
+				} else {
+					i++
+				}
+			case '.':
+				ls = append(ls, begin)
+				begin = i + 1
+			}
+		}
+	}
+	if len(ls) > n {
+		ls = append(ls, begin)
+	}
+	return ls
+}
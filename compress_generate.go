@@ -31,7 +31,7 @@ package dns
 // resolved.
 func getTypeStruct(t types.Type, scope *types.Scope) (*types.Struct, bool) {
 	st, ok := t.Underlying().(*types.Struct)
-	if !ok {
+	if !ok || st.NumFields() == 0 {
 		return nil, false
 	}
 	if st.Field(0).Type() == scope.Lookup("RR_Header").Type() {
@@ -101,7 +101,7 @@ Names:
 
 	// compressionLenHelperType - all types that have domain-name/cdomain-name can be used for compressing names
 
-	fmt.Fprint(b, "func compressionLenHelperType(c map[string]int, r RR) {\n")
+	fmt.Fprint(b, "func compressionLenHelperType(c *compressionTable, r RR) {\n")
 	fmt.Fprint(b, "switch x := r.(type) {\n")
 	for _, name := range domainTypes {
 		o := scope.Lookup(name)
@@ -137,7 +137,7 @@ Names:
 
 	// compressionLenSearchType - search cdomain-tags types for compressible names.
 
-	fmt.Fprint(b, "func compressionLenSearchType(c map[string]int, r RR) (int, bool) {\n")
+	fmt.Fprint(b, "func compressionLenSearchType(c *compressionTable, r RR) (int, bool) {\n")
 	fmt.Fprint(b, "switch x := r.(type) {\n")
 	for _, name := range cdomainTypes {
 		o := scope.Lookup(name)
@@ -0,0 +1,257 @@
+package dns
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestCompressionTable(t *testing.T) {
+	c := compressionMap{table: getCompressionTable()}
+	defer putCompressionTable(c.table)
+
+	msg := make([]byte, maxCompressionOffset+100)
+	tests := []struct {
+		name string
+		off  int
+		wire []byte
+	}{
+		{"www.example.org.", 12, []byte("\x03www\x07example\x03org\x00")},
+		{"mail.example.org.", 30, []byte("\x04mail\xc0\x10")},
+		{"example.org.", 40, []byte("\xc0\x10")},
+		{"mail.Example.org.", 50, []byte("\x04mail\x07Example\xc0\x18")}, // case is kept
+		{"a\\.b.org.", 70, []byte("\x03a.b\x03org\x00")},                 // as the map did, see mapKeys
+		{"x.a\\.b.org.", 80, []byte("\x01x\xc0\x46")},
+		{"a.b.c.", maxCompressionOffset - 4, []byte("\x01a\x01b\x01c\x00")},
+		{"x.a.b.c.", 100, []byte("\x01x\xff\xfc")},
+		{"y.c.", 110, []byte("\x01y\x01c\x00")}, // c. is beyond the offsets that can be pointed to
+	}
+
+	for _, tc := range tests {
+		off, _, err := packDomainName(tc.name, msg, tc.off, c, true)
+		if err != nil {
+			t.Fatalf("failed to pack %s: %v", tc.name, err)
+		}
+		if got := msg[tc.off:off]; !bytes.Equal(got, tc.wire) {
+			t.Errorf("packed %s as %x, want %x", tc.name, got, tc.wire)
+		}
+	}
+}
+
+func TestCompressionTableGrow(t *testing.T) {
+	c := getCompressionTable()
+	defer putCompressionTable(c)
+
+	// Enough names to grow the table a few times, all sharing a suffix.
+	names := make([]string, 1000)
+	for i := range names {
+		names[i] = fmt.Sprintf("host-%d.example.org.", i)
+		c.suffixes(names[i], labelOffsets(names[i], nil))
+		c.insert([]int{12 + 10*i, 20 + 10*i, 30}, len(c.keys))
+	}
+	for i, name := range names {
+		c.suffixes(name, labelOffsets(name, nil))
+		if k, off := c.search(); k != 0 || off != 12+10*i {
+			t.Errorf("found %s at label %d, offset %d, want label 0, offset %d", name, k, off, 12+10*i)
+		}
+	}
+	// The shared suffix keeps the offset it was first given.
+	c.suffixes("example.org.", labelOffsets("example.org.", nil))
+	if k, off := c.search(); k != 0 || off != 20 {
+		t.Errorf("found example.org. at label %d, offset %d, want label 0, offset 20", k, off)
+	}
+	c.suffixes("example.com.", labelOffsets("example.com.", nil))
+	if k, _ := c.search(); k != -1 {
+		t.Errorf("found example.com. at label %d", k)
+	}
+}
+
+func TestLabelOffsets(t *testing.T) {
+	tests := []struct {
+		name string
+		ls   []int
+	}{
+		{".", nil},
+		{"", nil},
+		{"org.", []int{0, 4}},
+		{"www.example.org.", []int{0, 4, 12, 16}},
+		{"a\\.b.org.", []int{0, 5, 9}},
+		{"a\\046b.org.", []int{0, 7, 11}},
+		{"a\\\\.org.", []int{0, 4, 8}},
+		{"www.example", []int{0, 4}},
+	}
+	for _, tc := range tests {
+		ls := labelOffsets(tc.name, nil)
+		if fmt.Sprint(ls) != fmt.Sprint(tc.ls) {
+			t.Errorf("labelOffsets(%q) = %v, want %v", tc.name, ls, tc.ls)
+		}
+	}
+}
+
+// TestCompressionMap compares packing names with a compressionTable with
+// packDomainNameMap, they must give the same octets.
+func TestCompressionMap(t *testing.T) {
+	r := rand.New(rand.NewSource(0))
+	labels := []string{"a", "b", "www", "Www", "mail", "example", "org", "com", "x-1",
+		"a\\.b", "c\\.d", "\\065bc", "x\\\\y", "\\000", "o\\rg"}
+	for _, start := range []int{12, maxCompressionOffset - 300} {
+		c := compressionMap{table: getCompressionTable()}
+		m := map[string]int{}
+		want := make([]byte, maxCompressionOffset+1000)
+		got := make([]byte, len(want))
+		off1, off2 := start, start
+		for off1 < len(want)-100 {
+			name := ""
+			for n := 1 + r.Intn(5); n > 0; n-- {
+				name += labels[r.Intn(len(labels))] + "."
+			}
+			compress := r.Intn(4) > 0
+			var err error
+			if off1, err = packDomainNameMap(name, want, off1, m, compress); err != nil {
+				t.Fatal(err)
+			}
+			if off2, _, err = packDomainName(name, got, off2, c, compress); err != nil {
+				t.Fatal(err)
+			}
+			if off1 != off2 {
+				t.Fatalf("packed %s up to offset %d, want %d", name, off2, off1)
+			}
+		}
+		if !bytes.Equal(got[:off2], want[:off1]) {
+			t.Errorf("start %d: packed names differ from the map", start)
+		}
+		putCompressionTable(c.table)
+	}
+}
+
+// TestCompressionEscapes checks names with escapes are compressed as the map
+// did, see mapKeys.
+func TestCompressionEscapes(t *testing.T) {
+	tests := []struct {
+		names []string
+		wire  string // of the last name
+	}{
+		{[]string{"example.com.", "\\065bc.com."}, "\x03Abc\x03com\x00"},
+		{[]string{"org.", "a\\.b.org."}, "\x03a.b\x03org\x00"},
+		{[]string{"a\\\\.org.", "org."}, "\x03org\x00"},
+		{[]string{"a\\.b.org.", "c\\.d.org."}, "\x03c.d\xc0\x10"},
+		{[]string{"a\\.b.org.", "x.a\\.b.org."}, "\x01x\xc0\x0c"},
+		{[]string{"b\\.c.", "a.b\\.c."}, "\x01a\xc0\x0c"},
+	}
+	for _, tc := range tests {
+		c := compressionMap{table: getCompressionTable()}
+		m := map[string]int{}
+		want := make([]byte, 512)
+		got := make([]byte, len(want))
+		off1, off2 := 12, 12
+		for i, name := range tc.names {
+			last1, last2 := off1, off2
+			var err error
+			if off1, err = packDomainNameMap(name, want, off1, m, true); err != nil {
+				t.Fatal(err)
+			}
+			if off2, _, err = packDomainName(name, got, off2, c, true); err != nil {
+				t.Fatal(err)
+			}
+			if i < len(tc.names)-1 {
+				continue
+			}
+			if w := string(want[last1:off1]); w != tc.wire {
+				t.Errorf("map packed %s as %q, want %q", name, w, tc.wire)
+			}
+			if g := string(got[last2:off2]); g != tc.wire {
+				t.Errorf("packed %s as %q, want %q", name, g, tc.wire)
+			}
+		}
+		putCompressionTable(c.table)
+	}
+
+	// The escape after the last label needs room as well.
+	if _, _, err := packDomainName("a\\.", make([]byte, 20), 20, compressionMap{}, true); err != ErrBuf {
+		t.Errorf("got error %v packing a\\. at the end of the buffer", err)
+	}
+}
+
+// packDomainNameMap is packDomainName as it was before compressionTable, with
+// a map of the packed names. It is kept to compare the output against.
+func packDomainNameMap(s string, msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+	ls := len(s)
+	if ls == 0 {
+		return off, nil
+	}
+	if s[ls-1] != '.' {
+		return len(msg), ErrFqdn
+	}
+	nameoffset, pointer := -1, -1
+	begin := 0
+	bs := []byte(s)
+	roBs, bsFresh, escapedDot := s, true, false
+	for i := 0; i < ls; i++ {
+		if bs[i] == '\\' {
+			for j := i; j < ls-1; j++ {
+				bs[j] = bs[j+1]
+			}
+			ls--
+			if i+2 < ls && isDigit(bs[i]) && isDigit(bs[i+1]) && isDigit(bs[i+2]) {
+				bs[i] = dddToByte(bs[i:])
+				for j := i + 1; j < ls-2; j++ {
+					bs[j] = bs[j+2]
+				}
+				ls -= 2
+			}
+			escapedDot = bs[i] == '.'
+			bsFresh = false
+			continue
+		}
+		if bs[i] == '.' {
+			if i > 0 && bs[i-1] == '.' && !escapedDot {
+				return len(msg), ErrRdata
+			}
+			if i-begin >= 1<<6 {
+				return len(msg), ErrRdata
+			}
+			if off+1 > len(msg) {
+				return len(msg), ErrBuf
+			}
+			msg[off] = byte(i - begin)
+			offset := off
+			off++
+			for j := begin; j < i; j++ {
+				if off+1 > len(msg) {
+					return len(msg), ErrBuf
+				}
+				msg[off] = bs[j]
+				off++
+			}
+			if compress && !bsFresh {
+				roBs = string(bs)
+				bsFresh = true
+			}
+			if roBs[begin:] != "." {
+				if p, ok := compression[roBs[begin:]]; !ok {
+					if offset < maxCompressionOffset {
+						compression[roBs[begin:]] = offset
+					}
+				} else if pointer == -1 && compress {
+					pointer, nameoffset = p, offset
+					break
+				}
+			}
+			begin = i + 1
+		}
+		escapedDot = false
+	}
+	if len(bs) == 1 && bs[0] == '.' {
+		return off, nil
+	}
+	if pointer != -1 {
+		binary.BigEndian.PutUint16(msg[nameoffset:], uint16(pointer^0xC000))
+		return nameoffset + 2, nil
+	}
+	if off < len(msg) {
+		msg[off] = 0
+	}
+	return off + 1, nil
+}
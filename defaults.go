@@ -130,6 +130,35 @@ func (dns *Msg) SetEdns0(udpsize uint16, do bool) *Msg {
 	return dns
 }
 
+// AddEDE appends an extended DNS error, RFC 8914, to the OPT RR of the
+// message. If the message has no OPT RR, one is added as with SetEdns0 with a
+// UDP size of DefaultMsgSize. A message can have more than one extended error.
+func (dns *Msg) AddEDE(code uint16, text string) *Msg {
+	o := dns.IsEdns0()
+	if o == nil {
+		dns.SetEdns0(DefaultMsgSize, false)
+		o = dns.Extra[len(dns.Extra)-1].(*OPT)
+	}
+	o.Option = append(o.Option, &EDNS0_EDE{InfoCode: code, ExtraText: text})
+	return dns
+}
+
+// EDE returns the extended DNS errors in the OPT RR of the message, in the
+// order they are in, or nil if there are none.
+func (dns *Msg) EDE() []*EDNS0_EDE {
+	o := dns.IsEdns0()
+	if o == nil {
+		return nil
+	}
+	var ede []*EDNS0_EDE
+	for _, e := range o.Option {
+		if e, ok := e.(*EDNS0_EDE); ok {
+			ede = append(ede, e)
+		}
+	}
+	return ede
+}
+
 // IsTsig checks if the message has a TSIG record as the last record
 // in the additional section. It returns the TSIG record found or nil.
 func (dns *Msg) IsTsig() *TSIG {
@@ -141,6 +170,18 @@ func (dns *Msg) IsTsig() *TSIG {
 	return nil
 }
 
+// IsSig0 checks if the message has a SIG(0) record, a SIG record that
+// covers type 0, as the last record in the additional section. It returns
+// the SIG record found or nil.
+func (dns *Msg) IsSig0() *SIG {
+	if len(dns.Extra) > 0 {
+		if sig, ok := dns.Extra[len(dns.Extra)-1].(*SIG); ok && sig.TypeCovered == 0 {
+			return sig
+		}
+	}
+	return nil
+}
+
 // IsEdns0 checks if the message has a EDNS0 (OPT) record, any EDNS0
 // record in the additional section will do. It returns the OPT record
 // found or nil.
@@ -166,7 +207,7 @@ func (dns *Msg) IsEdns0() *OPT {
 // label fits in 63 characters, but there is no length check for the entire
 // string s. I.e.  a domain name longer than 255 characters is considered valid.
 func IsDomainName(s string) (labels int, ok bool) {
-	_, labels, err := packDomainName(s, nil, 0, nil, false)
+	_, labels, err := packDomainName(s, nil, 0, compressionMap{}, false)
 	return labels, err == nil
 }
 
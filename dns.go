@@ -37,7 +37,7 @@ type RR interface {
 	// len returns the length (in octets) of the uncompressed RR in wire format.
 	len() int
 	// pack packs an RR into wire format.
-	pack([]byte, int, map[string]int, bool) (int, error)
+	pack([]byte, int, compressionMap, bool) (int, error)
 }
 
 // RR_Header is the header all DNS resource records share.
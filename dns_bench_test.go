@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"fmt"
 	"net"
 	"testing"
 )
@@ -202,6 +203,39 @@ func BenchmarkPackMsg(b *testing.B) {
 	}
 }
 
+func BenchmarkPackMsgMassive(b *testing.B) {
+	msg := new(Msg)
+	msg.SetQuestion("my.service.acme.", TypeSRV)
+	for i := 0; i < 250; i++ {
+		target := fmt.Sprintf("host-redis-%d.test.acme.com.node.dc1.consul.", i)
+		msg.Answer = append(msg.Answer, &SRV{Hdr: RR_Header{Name: "redis.service.consul.", Class: 1, Rrtype: TypeSRV, Ttl: 0x3c}, Port: 0x4c57, Target: target})
+		msg.Extra = append(msg.Extra, &CNAME{Hdr: RR_Header{Name: target, Class: 1, Rrtype: TypeCNAME, Ttl: 0x3c}, Target: fmt.Sprintf("fx.168.%d.", i)})
+	}
+	msg.Compress = true
+	buf := make([]byte, MaxMsgSize)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = msg.PackBuffer(buf)
+	}
+}
+
+func BenchmarkMsgLengthMassive(b *testing.B) {
+	msg := new(Msg)
+	msg.SetQuestion("my.service.acme.", TypeSRV)
+	for i := 0; i < 250; i++ {
+		target := fmt.Sprintf("host-redis-%d.test.acme.com.node.dc1.consul.", i)
+		msg.Answer = append(msg.Answer, &SRV{Hdr: RR_Header{Name: "redis.service.consul.", Class: 1, Rrtype: TypeSRV, Ttl: 0x3c}, Port: 0x4c57, Target: target})
+		msg.Extra = append(msg.Extra, &CNAME{Hdr: RR_Header{Name: target, Class: 1, Rrtype: TypeCNAME, Ttl: 0x3c}, Target: fmt.Sprintf("fx.168.%d.", i)})
+	}
+	msg.Compress = true
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		msg.Len()
+	}
+}
+
 func BenchmarkUnpackMsg(b *testing.B) {
 	makeMsg := func(question string, ans, ns, e []RR) *Msg {
 		msg := new(Msg)
@@ -223,6 +257,44 @@ func BenchmarkUnpackMsg(b *testing.B) {
 	}
 }
 
+func BenchmarkParser(b *testing.B) {
+	name1 := "12345678901234567890123456789012345.12345678.123."
+	rrMx := testRR(name1 + " 3600 IN MX 10 " + name1)
+	msg := new(Msg)
+	msg.SetQuestion(name1, TypeANY)
+	msg.Answer = []RR{rrMx, rrMx}
+	msg.Compress = true
+	msgBuf, _ := msg.Pack()
+	var p Parser
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Start(msgBuf)
+		p.SkipQuestions()
+		for {
+			if _, err := p.AnswerHeader(); err != nil {
+				break
+			}
+		}
+	}
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	name1 := "12345678901234567890123456789012345.12345678.123."
+	rrMx := testRR(name1 + " 3600 IN MX 10 " + name1)
+	q := Question{name1, TypeANY, ClassINET}
+	buf := make([]byte, 0, 512)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bd := NewBuilder(buf, MsgHdr{})
+		bd.Question(q)
+		bd.Answer(rrMx)
+		bd.Answer(rrMx)
+		bd.Finish()
+	}
+}
+
 func BenchmarkIdGeneration(b *testing.B) {
 	for i := 0; i < b.N; i++ {
 		_ = id()
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/hex"
 	"net"
+	"reflect"
 	"testing"
 )
 
@@ -213,6 +214,100 @@ func TestCopy(t *testing.T) {
 	if rr.String() != rr1.String() {
 		t.Fatalf("Copy() failed %s != %s", rr.String(), rr1.String())
 	}
+
+	// Types embedding another type keep their own type.
+	for _, s := range []string{
+		"miek.nl. 3600 IN CDS 12345 8 2 0123456789ABCDEF",
+		"miek.nl. 3600 IN DLV 12345 8 2 0123456789ABCDEF",
+		"miek.nl. 3600 IN CDNSKEY 257 3 8 AwEAAQ==",
+		"miek.nl. 3600 IN KEY 256 3 8 AwEAAQ==",
+	} {
+		rr := testRR(s)
+		if rr1 := Copy(rr); reflect.TypeOf(rr1) != reflect.TypeOf(rr) || rr1.String() != rr.String() {
+			t.Errorf("Copy() failed %T %s != %T %s", rr, rr, rr1, rr1)
+		}
+	}
+}
+
+func TestPackUnpackNewTypes(t *testing.T) {
+	for _, tc := range []struct {
+		in, rdata string
+	}{
+		{"foo.example. IN APL 1:192.168.32.0/21 !1:192.168.38.0/28", "00011503c0a820" + "00011c83c0a826"},
+		{"foo.example. IN APL 1:0.0.0.0/0 2:2001:db8::/32", "00010000" + "0002200420010db8"},
+		{"example. IN IPSECKEY 10 0 2 . AQID", "0a0002" + "010203"},
+		{"example. IN IPSECKEY 10 1 2 192.0.2.38 AQID", "0a0102" + "c0000226" + "010203"},
+		{"example. IN IPSECKEY 10 3 2 GW.Example. AQID", "0a0302" + "024757074578616d706c6500" + "010203"},
+		{"example. IN AMTRELAY 10 1 2 2001:db8::15", "0a82" + "20010db8000000000000000000000015"},
+		{"example. IN AMTRELAY 10 0 3 relay.example.", "0a03" + "0572656c6179076578616d706c6500"},
+		{"example. IN DSYNC CSYNC 1 53 Ep.example.", "003e" + "01" + "0035" + "024570076578616d706c6500"},
+		{"example. IN WALLET BTC bc1q", "03425443" + "0462633171"},
+	} {
+		rr := testRR(tc.in)
+		if rr == nil {
+			t.Errorf("failed to parse %q", tc.in)
+			continue
+		}
+		buf := make([]byte, rr.len())
+		off, err := PackRR(rr, buf, 0, nil, true)
+		if err != nil {
+			t.Errorf("failed to pack %q: %v", tc.in, err)
+			continue
+		}
+		if rdata := hex.EncodeToString(buf[off-int(rr.Header().Rdlength) : off]); rdata != tc.rdata {
+			t.Errorf("%q: got rdata %s, want %s", tc.in, rdata, tc.rdata)
+		}
+		rr1, _, err := UnpackRR(buf[:off], 0)
+		if err != nil {
+			t.Errorf("failed to unpack %q: %v", tc.in, err)
+			continue
+		}
+		if rr1.String() != rr.String() {
+			t.Errorf("unpacked %q, want %q", rr1, rr)
+		}
+	}
+
+	// Bad APL prefixes on the wire: an address that is too long, bits beyond
+	// the prefix and a trailing zero octet.
+	rr := testRR("foo.example. IN APL 1:192.168.32.0/21")
+	buf := make([]byte, rr.len())
+	off, err := PackRR(rr, buf, 0, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, rdata := range []string{"00011505c0a8200000", "00011503c0a821", "00011504c0a82000"} {
+		wire, _ := hex.DecodeString(rdata)
+		bad := append(append([]byte(nil), buf[:off-int(rr.Header().Rdlength)]...), wire...)
+		bad[len(bad)-len(wire)-1] = byte(len(wire))
+		if rr1, _, err := UnpackRR(bad, 0); err == nil {
+			t.Errorf("unpacked APL rdata %s as %s", rdata, rr1)
+		}
+	}
+
+	// An IPv4 prefix with the 16 octet address net.ParseIP returns.
+	apl := &APL{Hdr: RR_Header{Name: "foo.example.", Rrtype: TypeAPL, Class: ClassINET},
+		Prefixes: []APLPrefix{{Network: net.IPNet{IP: net.ParseIP("192.0.2.0"), Mask: net.CIDRMask(24, 32)}}}}
+	buf = make([]byte, apl.len())
+	off, err = PackRR(apl, buf, 0, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rdata := hex.EncodeToString(buf[off-int(apl.Hdr.Rdlength) : off]); rdata != "00011803c00002" {
+		t.Errorf("got APL rdata %s, want 00011803c00002", rdata)
+	}
+	if s := apl.String(); s != "foo.example.\t0\tIN\tAPL\t1:192.0.2.0/24" {
+		t.Errorf("got APL %q", s)
+	}
+}
+
+func TestCopyAPL(t *testing.T) {
+	rr := testRR("foo.example. IN APL 1:192.168.32.0/21 !2:2001:db8::/32").(*APL)
+	c := Copy(rr).(*APL)
+	c.Prefixes[0].Network.IP[0] = 10
+	c.Prefixes[1].Network.Mask[5] = 0xff
+	if rr.String() != "foo.example.\t3600\tIN\tAPL\t1:192.168.32.0/21 !2:2001:db8::/32" {
+		t.Errorf("changing the copy changed %s", rr)
+	}
 }
 
 func TestMsgCopy(t *testing.T) {
@@ -252,6 +252,13 @@ func (d *DS) ToCDS() *CDS {
 	return c
 }
 
+// SigningKey is a DNSKEY together with its private key. A key with the SEP
+// flag set is a key signing key, otherwise it is a zone signing key.
+type SigningKey struct {
+	DNSKEY *DNSKEY
+	Signer crypto.Signer
+}
+
 // Sign signs an RRSet. The signature needs to be filled in with the values:
 // Inception, Expiration, KeyTag, SignerName and Algorithm.  The rest is copied
 // from the RRset. Sign returns a non-nill error when the signing went OK.
@@ -648,81 +655,100 @@ func rawSignatureData(rrset []RR, s *RRSIG) (buf []byte, err error) {
 			// Wildcard
 			r1.Header().Name = "*." + strings.Join(labels[len(labels)-int(s.Labels):], ".") + "."
 		}
-		// RFC 4034: 6.2.  Canonical RR Form. (2) - domain name to lowercase
-		r1.Header().Name = strings.ToLower(r1.Header().Name)
-		// 6.2. Canonical RR Form. (3) - domain rdata to lowercase.
-		//   NS, MD, MF, CNAME, SOA, MB, MG, MR, PTR,
-		//   HINFO, MINFO, MX, RP, AFSDB, RT, SIG, PX, NXT, NAPTR, KX,
-		//   SRV, DNAME, A6
-		//
-		// RFC 6840 - Clarifications and Implementation Notes for DNS Security (DNSSEC):
-		//	Section 6.2 of [RFC4034] also erroneously lists HINFO as a record
-		//	that needs conversion to lowercase, and twice at that.  Since HINFO
-		//	records contain no domain names, they are not subject to case
-		//	conversion.
-		switch x := r1.(type) {
-		case *NS:
-			x.Ns = strings.ToLower(x.Ns)
-		case *MD:
-			x.Md = strings.ToLower(x.Md)
-		case *MF:
-			x.Mf = strings.ToLower(x.Mf)
-		case *CNAME:
-			x.Target = strings.ToLower(x.Target)
-		case *SOA:
-			x.Ns = strings.ToLower(x.Ns)
-			x.Mbox = strings.ToLower(x.Mbox)
-		case *MB:
-			x.Mb = strings.ToLower(x.Mb)
-		case *MG:
-			x.Mg = strings.ToLower(x.Mg)
-		case *MR:
-			x.Mr = strings.ToLower(x.Mr)
-		case *PTR:
-			x.Ptr = strings.ToLower(x.Ptr)
-		case *MINFO:
-			x.Rmail = strings.ToLower(x.Rmail)
-			x.Email = strings.ToLower(x.Email)
-		case *MX:
-			x.Mx = strings.ToLower(x.Mx)
-		case *RP:
-			x.Mbox = strings.ToLower(x.Mbox)
-			x.Txt = strings.ToLower(x.Txt)
-		case *AFSDB:
-			x.Hostname = strings.ToLower(x.Hostname)
-		case *RT:
-			x.Host = strings.ToLower(x.Host)
-		case *SIG:
-			x.SignerName = strings.ToLower(x.SignerName)
-		case *PX:
-			x.Map822 = strings.ToLower(x.Map822)
-			x.Mapx400 = strings.ToLower(x.Mapx400)
-		case *NAPTR:
-			x.Replacement = strings.ToLower(x.Replacement)
-		case *KX:
-			x.Exchanger = strings.ToLower(x.Exchanger)
-		case *SRV:
-			x.Target = strings.ToLower(x.Target)
-		case *DNAME:
-			x.Target = strings.ToLower(x.Target)
-		}
 		// 6.2. Canonical RR Form. (5) - origTTL
-		wire := make([]byte, r1.len()+1) // +1 to be safe(r)
-		off, err1 := PackRR(r1, wire, 0, nil, false)
+		wire, err1 := canonicalWire(r1)
 		if err1 != nil {
 			return nil, err1
 		}
-		wire = wire[:off]
 		wires[i] = wire
 	}
-	sort.Sort(wires)
-	for i, wire := range wires {
-		if i > 0 && bytes.Equal(wire, wires[i-1]) {
+	return wires.appendCanonical(nil), nil
+}
+
+// appendCanonical sorts the records of an RRset in p in the canonical order of
+// RFC 4034 section 6.3 and appends them to buf, without duplicates.
+func (p wireSlice) appendCanonical(buf []byte) []byte {
+	sort.Sort(p)
+	for i, wire := range p {
+		if i > 0 && bytes.Equal(wire, p[i-1]) {
 			continue
 		}
 		buf = append(buf, wire...)
 	}
-	return buf, nil
+	return buf
+}
+
+// canonicalWire returns r in the canonical RR form of RFC 4034 section 6.2, in
+// wire format. The owner name and the domain names in the rdata of r are
+// lower cased in place, so r must be a copy.
+func canonicalWire(r RR) ([]byte, error) {
+	// RFC 4034: 6.2.  Canonical RR Form. (2) - domain name to lowercase
+	r.Header().Name = strings.ToLower(r.Header().Name)
+	// 6.2. Canonical RR Form. (3) - domain rdata to lowercase.
+	//   NS, MD, MF, CNAME, SOA, MB, MG, MR, PTR,
+	//   HINFO, MINFO, MX, RP, AFSDB, RT, SIG, PX, NXT, NAPTR, KX,
+	//   SRV, DNAME, A6
+	//
+	// RFC 6840 - Clarifications and Implementation Notes for DNS Security (DNSSEC):
+	//	Section 6.2 of [RFC4034] also erroneously lists HINFO as a record
+	//	that needs conversion to lowercase, and twice at that.  Since HINFO
+	//	records contain no domain names, they are not subject to case
+	//	conversion.
+	switch x := r.(type) {
+	case *NS:
+		x.Ns = strings.ToLower(x.Ns)
+	case *MD:
+		x.Md = strings.ToLower(x.Md)
+	case *MF:
+		x.Mf = strings.ToLower(x.Mf)
+	case *CNAME:
+		x.Target = strings.ToLower(x.Target)
+	case *SOA:
+		x.Ns = strings.ToLower(x.Ns)
+		x.Mbox = strings.ToLower(x.Mbox)
+	case *MB:
+		x.Mb = strings.ToLower(x.Mb)
+	case *MG:
+		x.Mg = strings.ToLower(x.Mg)
+	case *MR:
+		x.Mr = strings.ToLower(x.Mr)
+	case *PTR:
+		x.Ptr = strings.ToLower(x.Ptr)
+	case *MINFO:
+		x.Rmail = strings.ToLower(x.Rmail)
+		x.Email = strings.ToLower(x.Email)
+	case *MX:
+		x.Mx = strings.ToLower(x.Mx)
+	case *RP:
+		x.Mbox = strings.ToLower(x.Mbox)
+		x.Txt = strings.ToLower(x.Txt)
+	case *AFSDB:
+		x.Hostname = strings.ToLower(x.Hostname)
+	case *RT:
+		x.Host = strings.ToLower(x.Host)
+	case *SIG:
+		x.SignerName = strings.ToLower(x.SignerName)
+	case *PX:
+		x.Map822 = strings.ToLower(x.Map822)
+		x.Mapx400 = strings.ToLower(x.Mapx400)
+	case *NAPTR:
+		x.Replacement = strings.ToLower(x.Replacement)
+	case *KX:
+		x.Exchanger = strings.ToLower(x.Exchanger)
+	case *SRV:
+		x.Target = strings.ToLower(x.Target)
+	case *DNAME:
+		x.Target = strings.ToLower(x.Target)
+	}
+	// The names in the rdata of the types defined after RFC 3597, like
+	// IPSECKEY, AMTRELAY, SVCB and DSYNC, keep their case and are never
+	// compressed, RFC 3597 section 7.
+	wire := make([]byte, r.len()+1) // +1 to be safe(r)
+	off, err := PackRR(r, wire, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return wire[:off], nil
 }
 
 func packSigWire(sw *rrsigWireFmt, msg []byte) (int, error) {
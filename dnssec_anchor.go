@@ -0,0 +1,378 @@
+package dns
+
+// Trust anchors: IANA's root-anchors.xml, RFC 7958, and the automated
+// updates of RFC 5011.
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// anchorHoldDown is the default add and remove hold-down time, RFC 5011
+// section 2.4.
+const anchorHoldDown = 30 * 24 * time.Hour
+
+// rootAnchors is the XML document of RFC 7958 section 2.
+type rootAnchors struct {
+	Zone      string `xml:"Zone"`
+	KeyDigest []struct {
+		ValidFrom  string `xml:"validFrom,attr"`
+		ValidUntil string `xml:"validUntil,attr"`
+		KeyTag     uint16 `xml:"KeyTag"`
+		Algorithm  uint8  `xml:"Algorithm"`
+		DigestType uint8  `xml:"DigestType"`
+		Digest     string `xml:"Digest"`
+	} `xml:"KeyDigest"`
+}
+
+// ParseRootAnchors parses the trust anchors published by IANA as
+// root-anchors.xml, RFC 7958. It returns the DS records of the key digests
+// that are valid at time t. If t is the zero time, the current time is used.
+func ParseRootAnchors(r io.Reader, t time.Time) ([]RR, error) {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	var doc rootAnchors
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	zone := Fqdn(strings.TrimSpace(doc.Zone))
+	if _, ok := IsDomainName(zone); !ok {
+		return nil, &Error{err: "bad trust anchor zone: " + doc.Zone}
+	}
+
+	var ds []RR
+	for _, k := range doc.KeyDigest {
+		from, err := time.Parse(time.RFC3339, k.ValidFrom)
+		if err != nil {
+			return nil, err
+		}
+		if t.Before(from) {
+			continue
+		}
+		if k.ValidUntil != "" {
+			until, err := time.Parse(time.RFC3339, k.ValidUntil)
+			if err != nil {
+				return nil, err
+			}
+			if !t.Before(until) {
+				continue
+			}
+		}
+		ds = append(ds, &DS{
+			Hdr:        RR_Header{Name: zone, Rrtype: TypeDS, Class: ClassINET},
+			KeyTag:     k.KeyTag,
+			Algorithm:  k.Algorithm,
+			DigestType: k.DigestType,
+			Digest:     strings.ToUpper(strings.TrimSpace(k.Digest)),
+		})
+	}
+	return ds, nil
+}
+
+// AnchorState is the state of a trust anchor, RFC 5011 section 4. A key
+// that is not in the store is in the Start state, one that is removed from it
+// is Removed.
+type AnchorState int
+
+// The states of a trust anchor.
+const (
+	AnchorAddPend AnchorState = iota + 1 // a new key, waiting for the add hold-down time
+	AnchorValid                          // a trusted key
+	AnchorMissing                        // a trusted key that is no longer in the DNSKEY RRset
+	AnchorRevoked                        // a key revoked by its owner, waiting for the remove hold-down time
+)
+
+var anchorStateString = map[AnchorState]string{
+	AnchorAddPend: "ADDPEND",
+	AnchorValid:   "VALID",
+	AnchorMissing: "MISSING",
+	AnchorRevoked: "REVOKED",
+}
+
+func (s AnchorState) String() string { return anchorStateString[s] }
+
+// TrustAnchor is a key in an AnchorStore.
+type TrustAnchor struct {
+	RR      RR          // the DNSKEY, or the DS of a configured key that has not been seen yet
+	State   AnchorState // the state of the key
+	Changed time.Time   // when the state last changed, the start of the hold-down timers
+}
+
+// AnchorStore holds the trust anchors of a zone and keeps them up to date
+// following the DNSKEY RRsets of the zone, RFC 5011. The trust anchors are
+// its keys in the Valid and Missing states.
+type AnchorStore struct {
+	Zone           string           // the zone of the trust anchors
+	AddHoldDown    time.Duration    // the time before a new key is trusted, 30 days if zero
+	RemoveHoldDown time.Duration    // the time a revoked key is kept, 30 days if zero
+	Now            func() time.Time // the clock, time.Now if nil
+
+	m       sync.Mutex
+	anchors []*TrustAnchor
+}
+
+// NewAnchorStore returns a store for the trust anchors of zone, initially
+// the DS and DNSKEY records in anchors.
+func NewAnchorStore(zone string, anchors []RR) *AnchorStore {
+	s := &AnchorStore{Zone: Fqdn(zone)}
+	for _, rr := range anchors {
+		s.add(rr, AnchorValid, time.Time{})
+	}
+	return s
+}
+
+// ReadAnchorStore returns a store with the DS and DNSKEY records for zone
+// read from r, see ParseZone for the arguments. The state of a record is
+// read from its comment, as written by WriteTo. Records without one are
+// trusted, so a file with DS or DNSKEY records can be read too. Other
+// records are ignored.
+func ReadAnchorStore(r io.Reader, zone, file string) (*AnchorStore, error) {
+	s := NewAnchorStore(zone, nil)
+	tokens := ParseZone(r, s.Zone, file)
+	defer func() {
+		// Drain the channel, so the parser does not leak.
+		for range tokens {
+		}
+	}()
+	for t := range tokens {
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		h := t.RR.Header()
+		if h.Rrtype != TypeDS && h.Rrtype != TypeDNSKEY || !equal(h.Name, s.Zone) {
+			continue
+		}
+		state, changed, err := parseAnchorComment(t.Comment)
+		if err != nil {
+			return nil, &ParseError{file: file, err: err.Error(), lex: lex{token: t.Comment}}
+		}
+		s.add(t.RR, state, changed)
+	}
+	return s, nil
+}
+
+// parseAnchorComment parses the state and time of change from a comment
+// such as "; state=VALID changed=2017-08-16T15:16:05Z".
+func parseAnchorComment(c string) (AnchorState, time.Time, error) {
+	state, changed := AnchorValid, time.Time{}
+	for _, f := range strings.Fields(strings.TrimLeft(c, "; ")) {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "state":
+			state = 0
+			for s, str := range anchorStateString {
+				if str == kv[1] {
+					state = s
+				}
+			}
+			if state == 0 {
+				return 0, changed, fmt.Errorf("bad trust anchor state %q", kv[1])
+			}
+		case "changed":
+			t, err := time.Parse(time.RFC3339, kv[1])
+			if err != nil {
+				return 0, changed, err
+			}
+			changed = t
+		}
+	}
+	return state, changed, nil
+}
+
+// WriteTo writes the keys in the store with their state to w, in a format
+// read by ReadAnchorStore.
+func (s *AnchorStore) WriteTo(w io.Writer) (int64, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	var n int64
+	for _, a := range s.anchors {
+		i, err := fmt.Fprintf(w, "%s ; state=%s changed=%s\n", a.RR, a.State, a.Changed.UTC().Format(time.RFC3339))
+		n += int64(i)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// Anchors returns the trust anchors, the keys in the Valid and Missing
+// states, for use in a Validator.
+func (s *AnchorStore) Anchors() []RR {
+	s.m.Lock()
+	defer s.m.Unlock()
+	var rrs []RR
+	for _, a := range s.anchors {
+		if a.State == AnchorValid || a.State == AnchorMissing {
+			rrs = append(rrs, a.RR)
+		}
+	}
+	return rrs
+}
+
+// Keys returns a copy of all keys in the store.
+func (s *AnchorStore) Keys() []TrustAnchor {
+	s.m.Lock()
+	defer s.m.Unlock()
+	keys := make([]TrustAnchor, len(s.anchors))
+	for i, a := range s.anchors {
+		keys[i] = *a
+	}
+	return keys
+}
+
+// Update moves the keys through the states of RFC 5011 section 4 after
+// seeing the DNSKEY RRset of the zone, with its RRSIGs, in rrset.
+//
+// A key with the REVOKE flag that signs the RRset itself is revoked. The
+// other changes are only made if the RRset is signed by a trust anchor:
+// new SEP keys are added in the AddPend state, and become Valid once they
+// are seen after the add hold-down time. Trusted keys that are no longer in
+// the RRset are Missing. Revoked keys are removed after the remove hold-down
+// time, keys in AddPend when they disappear. If the RRset is not signed by a
+// trust anchor, ErrNoDNSKEY or the verification error is returned.
+func (s *AnchorStore) Update(rrset []RR) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	now := time.Now()
+	if s.Now != nil {
+		now = s.Now()
+	}
+
+	var keys []RR
+	var sigs []*RRSIG
+	for _, rr := range rrset {
+		if !equal(rr.Header().Name, s.Zone) {
+			continue
+		}
+		switch rr := rr.(type) {
+		case *DNSKEY:
+			keys = append(keys, rr)
+		case *RRSIG:
+			if rr.TypeCovered == TypeDNSKEY {
+				sigs = append(sigs, rr)
+			}
+		}
+	}
+	signs := func(k *DNSKEY) error {
+		err := ErrNoSig
+		for _, sig := range sigs {
+			if sig.KeyTag != k.KeyTag() || sig.Algorithm != k.Algorithm {
+				continue
+			}
+			if !sig.ValidityPeriod(now) {
+				err = ErrSigExpired
+				continue
+			}
+			if err = sig.Verify(k, keys); err == nil {
+				return nil
+			}
+		}
+		return err
+	}
+
+	// Self signed revocations, RFC 5011 section 2.1.
+	for _, rr := range keys {
+		k := rr.(*DNSKEY)
+		if k.Flags&REVOKE == 0 || signs(k) != nil {
+			continue
+		}
+		if a := s.find(k); a != nil && a.State != AnchorRevoked {
+			a.RR, a.State, a.Changed = k, AnchorRevoked, now
+		}
+	}
+
+	err := ErrNoDNSKEY
+	for _, a := range s.anchors {
+		if a.State != AnchorValid && a.State != AnchorMissing {
+			continue
+		}
+		for _, rr := range keys {
+			k := rr.(*DNSKEY)
+			if k.Flags&REVOKE != 0 || !matchAnchor(a.RR, k) {
+				continue
+			}
+			if err = signs(k); err == nil {
+				break
+			}
+		}
+		if err == nil {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	seen := make(map[*TrustAnchor]bool)
+	for _, rr := range keys {
+		k := rr.(*DNSKEY)
+		if k.Flags&REVOKE != 0 {
+			continue
+		}
+		a := s.find(k)
+		switch {
+		case a == nil && k.Flags&SEP == 0:
+			continue
+		case a == nil:
+			a = s.add(k, AnchorAddPend, now)
+		}
+		seen[a] = true
+		a.RR = k
+		switch {
+		case a.State == AnchorAddPend && now.Sub(a.Changed) >= s.holdDown(s.AddHoldDown):
+			a.State, a.Changed = AnchorValid, now
+		case a.State == AnchorMissing:
+			a.State, a.Changed = AnchorValid, now
+		}
+	}
+
+	anchors := s.anchors[:0]
+	for _, a := range s.anchors {
+		switch {
+		case a.State == AnchorRevoked && now.Sub(a.Changed) >= s.holdDown(s.RemoveHoldDown):
+			continue
+		case seen[a] || a.State == AnchorRevoked:
+		case a.State == AnchorAddPend:
+			continue
+		case a.State == AnchorValid:
+			a.State, a.Changed = AnchorMissing, now
+		}
+		anchors = append(anchors, a)
+	}
+	s.anchors = anchors
+	return nil
+}
+
+// add adds rr to the store.
+func (s *AnchorStore) add(rr RR, state AnchorState, changed time.Time) *TrustAnchor {
+	a := &TrustAnchor{RR: rr, State: state, Changed: changed}
+	s.anchors = append(s.anchors, a)
+	return a
+}
+
+// find returns the trust anchor for k, ignoring the REVOKE flag.
+func (s *AnchorStore) find(k *DNSKEY) *TrustAnchor {
+	plain := *k
+	plain.Flags &^= REVOKE
+	for _, a := range s.anchors {
+		if matchAnchor(a.RR, &plain) {
+			return a
+		}
+	}
+	return nil
+}
+
+func (s *AnchorStore) holdDown(d time.Duration) time.Duration {
+	if d == 0 {
+		return anchorHoldDown
+	}
+	return d
+}
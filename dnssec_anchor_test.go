@@ -0,0 +1,189 @@
+package dns
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRootAnchors = `<?xml version="1.0" encoding="UTF-8"?>
+<TrustAnchor id="380DC50D-484E-40D0-A3AE-68F2B18F61C7" source="http://data.iana.org/root-anchors/root-anchors.xml">
+<Zone>.</Zone>
+<KeyDigest id="Kjqmt7v" validFrom="2010-07-15T00:00:00+00:00" validUntil="2019-01-11T00:00:00+00:00">
+<KeyTag>19036</KeyTag>
+<Algorithm>8</Algorithm>
+<DigestType>2</DigestType>
+<Digest>49AAC11D7B6F6446702E54A1607371607A1A41855200FD2CE1CDDE32F24E8FB5</Digest>
+</KeyDigest>
+<KeyDigest id="Klajeyz" validFrom="2017-02-02T00:00:00+00:00">
+<KeyTag>20326</KeyTag>
+<Algorithm>8</Algorithm>
+<DigestType>2</DigestType>
+<Digest>E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D</Digest>
+</KeyDigest>
+</TrustAnchor>
+`
+
+func TestParseRootAnchors(t *testing.T) {
+	for _, tc := range []struct {
+		t    time.Time
+		tags []uint16
+	}{
+		{time.Date(2009, 1, 1, 0, 0, 0, 0, time.UTC), nil},
+		{time.Date(2018, 1, 1, 0, 0, 0, 0, time.UTC), []uint16{19036, 20326}},
+		{time.Date(2019, 1, 11, 0, 0, 0, 0, time.UTC), []uint16{20326}},
+	} {
+		ds, err := ParseRootAnchors(strings.NewReader(testRootAnchors), tc.t)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ds) != len(tc.tags) {
+			t.Errorf("%s: got %v, want key tags %v", tc.t, ds, tc.tags)
+			continue
+		}
+		for i, rr := range ds {
+			if rr.(*DS).KeyTag != tc.tags[i] || rr.Header().Name != "." {
+				t.Errorf("%s: got %s, want key tag %d", tc.t, rr, tc.tags[i])
+			}
+		}
+	}
+	want := ".\t0\tIN\tDS\t20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"
+	ds, _ := ParseRootAnchors(strings.NewReader(testRootAnchors), time.Time{})
+	if len(ds) != 1 || ds[0].String() != want {
+		t.Errorf("got %v, want %s", ds, want)
+	}
+}
+
+func TestAnchorStore(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	old := newSigningKey(t, ".", ZONE|SEP)
+	next := newSigningKey(t, ".", ZONE|SEP)
+	zsk := newSigningKey(t, ".", ZONE)
+	s := NewAnchorStore(".", []RR{old.DNSKEY.ToDS(SHA256)})
+	s.Now = func() time.Time { return now }
+
+	// keySet returns the DNSKEY RRset of keys, signed by signers.
+	keySet := func(keys []SigningKey, signers ...SigningKey) []RR {
+		var rrset []RR
+		for _, k := range keys {
+			rrset = append(rrset, k.DNSKEY)
+		}
+		sigs, err := signRRset(signers, rrset, ".", uint32(now.Add(-time.Hour).Unix()), uint32(now.Add(10*24*time.Hour).Unix()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return append(rrset, sigs...)
+	}
+	states := func(step string, want ...AnchorState) {
+		keys := s.Keys()
+		if len(keys) != len(want) {
+			t.Fatalf("%s: got %d keys, want %d", step, len(keys), len(want))
+		}
+		for i, k := range keys {
+			if k.State != want[i] {
+				t.Errorf("%s: got key %d in state %s, want %s", step, i, k.State, want[i])
+			}
+		}
+	}
+	update := func(step string, rrset []RR) {
+		if err := s.Update(rrset); err != nil {
+			t.Fatalf("%s: %v", step, err)
+		}
+	}
+
+	update("start", keySet([]SigningKey{old, zsk}, old))
+	states("start", AnchorValid)
+	if _, ok := s.Keys()[0].RR.(*DNSKEY); !ok {
+		t.Errorf("the DS anchor is not replaced by its DNSKEY")
+	}
+
+	// A new key is added, and trusted after the hold-down time.
+	update("new key", keySet([]SigningKey{old, next, zsk}, old))
+	states("new key", AnchorValid, AnchorAddPend)
+	now = now.Add(10 * 24 * time.Hour)
+	update("in hold-down", keySet([]SigningKey{old, next, zsk}, old))
+	states("in hold-down", AnchorValid, AnchorAddPend)
+	now = now.Add(21 * 24 * time.Hour)
+	update("after hold-down", keySet([]SigningKey{old, next, zsk}, old))
+	states("after hold-down", AnchorValid, AnchorValid)
+	if len(s.Anchors()) != 2 {
+		t.Errorf("got %d trust anchors, want 2", len(s.Anchors()))
+	}
+
+	// A key that disappears is missing, but still trusted.
+	update("missing", keySet([]SigningKey{next, zsk}, next))
+	states("missing", AnchorMissing, AnchorValid)
+	update("back", keySet([]SigningKey{old, next, zsk}, next))
+	states("back", AnchorValid, AnchorValid)
+
+	// A new key that disappears before it is trusted is forgotten.
+	third := newSigningKey(t, ".", ZONE|SEP)
+	update("third", keySet([]SigningKey{old, next, third, zsk}, old))
+	states("third", AnchorValid, AnchorValid, AnchorAddPend)
+	update("no third", keySet([]SigningKey{old, next, zsk}, old))
+	states("no third", AnchorValid, AnchorValid)
+
+	// The old key is revoked.
+	rk := *old.DNSKEY
+	rk.Flags |= REVOKE
+	revoked := SigningKey{DNSKEY: &rk, Signer: old.Signer}
+	update("revoked", keySet([]SigningKey{revoked, next, zsk}, revoked, next))
+	states("revoked", AnchorRevoked, AnchorValid)
+	if a := s.Anchors(); len(a) != 1 || a[0].(*DNSKEY).PublicKey != next.DNSKEY.PublicKey {
+		t.Errorf("got trust anchors %v, want the new key", a)
+	}
+
+	// The state survives a restart.
+	buf := new(bytes.Buffer)
+	if _, err := s.WriteTo(buf); err != nil {
+		t.Fatal(err)
+	}
+	r, err := ReadAnchorStore(buf, ".", "anchors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, k := range r.Keys() {
+		want := s.Keys()[i]
+		if k.State != want.State || !k.Changed.Equal(want.Changed) || k.RR.String() != want.RR.String() {
+			t.Errorf("read %s %s %s, want %s %s %s", k.RR, k.State, k.Changed, want.RR, want.State, want.Changed)
+		}
+	}
+
+	// The revoked key is removed after the hold-down time.
+	now = now.Add(31 * 24 * time.Hour)
+	update("removed", keySet([]SigningKey{next, zsk}, next))
+	states("removed", AnchorValid)
+
+	// An RRset not signed by a trust anchor changes nothing.
+	if err := s.Update(keySet([]SigningKey{third, zsk}, third)); err != ErrNoDNSKEY {
+		t.Errorf("got %v for an untrusted RRset, want %v", err, ErrNoDNSKEY)
+	}
+	states("untrusted", AnchorValid)
+	rrset := keySet([]SigningKey{next, third, zsk}, next)
+	now = now.Add(11 * 24 * time.Hour)
+	if err := s.Update(rrset); err != ErrSigExpired {
+		t.Errorf("got %v for an expired RRset, want %v", err, ErrSigExpired)
+	}
+	states("expired", AnchorValid)
+}
+
+func TestReadAnchorStore(t *testing.T) {
+	const anchors = `; trust anchors
+. 86400 IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D
+example.org. 86400 IN DS 12345 8 2 0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF
+. 86400 IN NS a.root-servers.net.
+`
+	s, err := ReadAnchorStore(strings.NewReader(anchors), ".", "anchors")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a := s.Anchors(); len(a) != 1 || a[0].(*DS).KeyTag != 20326 {
+		t.Errorf("got trust anchors %v", a)
+	}
+
+	bad := ". 86400 IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D ; state=GONE\n"
+	if _, err := ReadAnchorStore(strings.NewReader(bad), ".", "anchors"); err == nil {
+		t.Errorf("expected an error for a bad state")
+	}
+}
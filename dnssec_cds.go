@@ -0,0 +1,231 @@
+package dns
+
+// Parent side processing of CDS and CDNSKEY records, RFC 7344 section 4 and
+// RFC 8078.
+
+import (
+	"strings"
+	"time"
+)
+
+// DSUpdate is a change of the DS RRset of a child zone.
+type DSUpdate struct {
+	DS     []RR // the DS RRset to publish, empty if the child asked to delete it
+	Add    []RR // the records in DS that are not in the current RRset
+	Remove []RR // the records in the current RRset that are not in DS
+}
+
+// Changed returns true if u changes the DS RRset.
+func (u *DSUpdate) Changed() bool { return len(u.Add) > 0 || len(u.Remove) > 0 }
+
+// ParentalAgent maintains the DS RRsets of child zones from their CDS and
+// CDNSKEY records, the parental agent of RFC 7344.
+type ParentalAgent struct {
+	// Fetcher fetches the DNSKEY, CDS and CDNSKEY RRsets of the child, with
+	// their signatures. It should only return RRsets that are the same on
+	// all servers of the child.
+	Fetcher KeyFetcher
+	// DigestType is the digest of the DS records made from CDNSKEY
+	// records, SHA256 if zero.
+	DigestType uint8
+	// Bootstrap allows a child without DS records to get them, if its
+	// DNSKEY RRset is signed with a key the CDS or CDNSKEY records refer
+	// to. This is the "accept from inception" of RFC 8078 section 3.3;
+	// the parent should make sure otherwise that the records are genuine.
+	Bootstrap bool
+}
+
+// Update returns the DS RRset for child, currently with the DS records
+// current, as requested by its CDS and CDNSKEY records, at time t. If t is
+// the zero time, the current time is used.
+//
+// The DNSKEY RRset of the child must validate with current, and the CDS and
+// CDNSKEY RRsets must be signed with a key in both, RFC 7344 section 4.1. If
+// both RRsets exist they must ask for the same DS records. A CDS or CDNSKEY
+// record with algorithm 0 asks to delete the DS RRset, RFC 8078 section 4.
+// Otherwise the new DS RRset must validate the DNSKEY RRset too. Without CDS
+// and CDNSKEY records the DS RRset does not change.
+func (a *ParentalAgent) Update(child string, current []RR, t time.Time) (*DSUpdate, error) {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	child = Fqdn(child)
+	var requests []*signedRRset
+	for _, qtype := range []uint16{TypeCDS, TypeCDNSKEY} {
+		m, err := a.Fetcher.Fetch(child, qtype)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range sectionRRsets(m.Answer) {
+			if h := s.rrset[0].Header(); h.Rrtype == qtype && equal(h.Name, child) {
+				requests = append(requests, s)
+			}
+		}
+	}
+	if len(requests) == 0 {
+		return diffDS(current, current), nil
+	}
+	ds, err := a.requested(child, current, requests)
+	if err != nil {
+		return nil, err
+	}
+
+	trusted := current
+	if len(current) == 0 {
+		if len(ds) == 0 {
+			// Nothing to delete.
+			return diffDS(nil, nil), nil
+		}
+		if !a.Bootstrap {
+			return nil, &Error{err: "no DS records to validate with: " + child}
+		}
+		trusted = ds
+	}
+	c := &validation{v: &Validator{Fetcher: a.Fetcher}, t: t}
+	trust := matchDS(trusted)
+	keys, err := c.dnskeys(child, trust)
+	if err != nil {
+		return nil, err
+	}
+	var signers []*DNSKEY
+	for _, k := range keys {
+		if trust(k) {
+			signers = append(signers, k)
+		}
+	}
+	for _, s := range requests {
+		err := error(ErrNoSig)
+		for _, sig := range s.sigs {
+			if err = c.verify(sig, s.rrset, signers); err == nil {
+				break
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(ds) > 0 && len(current) > 0 {
+		// The new DS RRset must not break the chain of trust.
+		if _, err := c.dnskeys(child, matchDS(ds)); err != nil {
+			return nil, &Error{err: "DS records do not validate the DNSKEY RRset: " + child}
+		}
+	}
+	return diffDS(current, ds), nil
+}
+
+// requested returns the DS records asked for with the CDS and CDNSKEY
+// RRsets in requests, nil if they ask to delete the DS RRset.
+func (a *ParentalAgent) requested(child string, current []RR, requests []*signedRRset) ([]RR, error) {
+	digest := a.DigestType
+	if digest == 0 {
+		digest = SHA256
+	}
+	var ttl uint32
+	if len(current) > 0 {
+		ttl = current[0].Header().Ttl
+	} else {
+		ttl = requests[0].rrset[0].Header().Ttl
+	}
+
+	var sets [][]RR
+	del := 0
+	for _, s := range requests {
+		var ds []RR
+		for _, rr := range s.rrset {
+			var d *DS
+			switch rr := rr.(type) {
+			case *CDS:
+				if rr.Algorithm == 0 {
+					del++
+					continue
+				}
+				d = &DS{KeyTag: rr.KeyTag, Algorithm: rr.Algorithm, DigestType: rr.DigestType, Digest: rr.Digest}
+			case *CDNSKEY:
+				if rr.Algorithm == 0 {
+					del++
+					continue
+				}
+				if d = rr.DNSKEY.ToDS(digest); d == nil {
+					return nil, ErrDigest
+				}
+			}
+			d.Hdr = RR_Header{Name: child, Rrtype: TypeDS, Class: ClassINET, Ttl: ttl}
+			d.Digest = strings.ToUpper(d.Digest)
+			ds = append(ds, d)
+		}
+		if del > 0 && len(ds) > 0 {
+			return nil, &Error{err: "delete request with other records: " + child}
+		}
+		sets = append(sets, ds)
+	}
+	if del > 0 {
+		if len(sets) > 1 && (len(sets[0]) > 0 || len(sets[1]) > 0) {
+			return nil, &Error{err: "CDS and CDNSKEY records do not match: " + child}
+		}
+		return nil, nil
+	}
+	if len(sets) > 1 && !sameDS(sets[0], sets[1]) {
+		return nil, &Error{err: "CDS and CDNSKEY records do not match: " + child}
+	}
+	return sets[0], nil
+}
+
+// sameDS returns true if the CDS records in cds and the ones made from
+// CDNSKEY records in cdnskey are for the same keys. A CDS record matches
+// if its key tag and algorithm are the same, as it may use another digest.
+func sameDS(cds, cdnskey []RR) bool {
+	for _, set := range [][2][]RR{{cds, cdnskey}, {cdnskey, cds}} {
+		for _, rr := range set[0] {
+			found := false
+			for _, other := range set[1] {
+				a, b := rr.(*DS), other.(*DS)
+				if a.KeyTag == b.KeyTag && a.Algorithm == b.Algorithm {
+					found = true
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// matchDS returns a function that reports whether a key matches one of the
+// DS records in ds.
+func matchDS(ds []RR) func(*DNSKEY) bool {
+	return func(k *DNSKEY) bool {
+		for _, d := range ds {
+			if matchAnchor(d, k) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// diffDS returns the update from the DS RRset current to ds.
+func diffDS(current, ds []RR) *DSUpdate {
+	u := &DSUpdate{DS: ds}
+	in := func(d *DS, set []RR) bool {
+		for _, rr := range set {
+			o := rr.(*DS)
+			if d.KeyTag == o.KeyTag && d.Algorithm == o.Algorithm && d.DigestType == o.DigestType && strings.EqualFold(d.Digest, o.Digest) {
+				return true
+			}
+		}
+		return false
+	}
+	for _, rr := range ds {
+		if !in(rr.(*DS), current) {
+			u.Add = append(u.Add, rr)
+		}
+	}
+	for _, rr := range current {
+		if !in(rr.(*DS), ds) {
+			u.Remove = append(u.Remove, rr)
+		}
+	}
+	return u
+}
@@ -0,0 +1,101 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParentalAgent(t *testing.T) {
+	ksk := newSigningKey(t, "example.org.", ZONE|SEP)
+	ksk2 := newSigningKey(t, "example.org.", ZONE|SEP)
+	zsk := newSigningKey(t, "example.org.", ZONE)
+	other := newSigningKey(t, "example.org.", ZONE|SEP)
+	current := []RR{ksk.DNSKEY.ToDS(SHA256)}
+
+	cds := func(k SigningKey) string { return k.DNSKEY.ToDS(SHA256).ToCDS().String() }
+	cdnskey := func(k SigningKey) string { return k.DNSKEY.ToCDNSKEY().String() }
+
+	// agent returns a parental agent for the zone with records, signed with
+	// keys.
+	agent := func(keys []SigningKey, records ...string) *ParentalAgent {
+		z, err := ReadZone(strings.NewReader(testZone+strings.Join(records, "\n")+"\n"), "example.org.", "testZone")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := z.Sign(keys, &SignPolicy{}, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+		return &ParentalAgent{Fetcher: KeyFetcherFunc(func(name string, qtype uint16) (*Msg, error) {
+			req := new(Msg)
+			req.SetQuestion(name, qtype)
+			req.SetEdns0(4096, true)
+			return z.Answer(req), nil
+		})}
+	}
+	both := []SigningKey{ksk, ksk2, zsk}
+
+	tests := []struct {
+		name    string
+		agent   *ParentalAgent
+		current []RR
+		ds      []SigningKey // nil if an error is expected
+		deleted bool
+	}{
+		{"no change", agent([]SigningKey{ksk, zsk}), current, []SigningKey{ksk}, false},
+		{"rollover", agent(both, cds(ksk2), cdnskey(ksk2)), current, []SigningKey{ksk2}, false},
+		{"both keys", agent(both, cds(ksk), cds(ksk2)), current, []SigningKey{ksk, ksk2}, false},
+		{"cdnskey", agent(both, cdnskey(ksk2)), current, []SigningKey{ksk2}, false},
+		{"mismatch", agent(both, cds(ksk2), cdnskey(ksk)), current, nil, false},
+		{"untrusted", agent(both, cds(ksk2)), []RR{other.DNSKEY.ToDS(SHA256)}, nil, false},
+		{"breaks the chain", agent(both, cds(other)), current, nil, false},
+		{"DNSKEY not signed by the DS key", agent([]SigningKey{ksk2, zsk}, "@ IN DNSKEY "+ksk.DNSKEY.String()[len("example.org.\t3600\tIN\tDNSKEY\t"):], cds(ksk2)), current, nil, false},
+		{"delete", agent([]SigningKey{ksk, zsk}, "@ IN CDS 0 0 0 00", "@ IN CDNSKEY 0 3 0 AA=="), current, []SigningKey{}, true},
+		{"delete with others", agent([]SigningKey{ksk, zsk}, "@ IN CDS 0 0 0 00", cds(ksk)), current, nil, false},
+		{"no bootstrap", agent(both, cds(ksk2)), nil, nil, false},
+	}
+	for _, tc := range tests {
+		u, err := tc.agent.Update("example.org.", tc.current, time.Time{})
+		if tc.ds == nil {
+			if err == nil {
+				t.Errorf("%s: expected an error, got %v", tc.name, u.DS)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if len(u.DS) != len(tc.ds) {
+			t.Errorf("%s: got DS %v, want %d records", tc.name, u.DS, len(tc.ds))
+			continue
+		}
+		for i, k := range tc.ds {
+			if !matchAnchor(u.DS[i], k.DNSKEY) {
+				t.Errorf("%s: got DS %s, want one for key %d", tc.name, u.DS[i], k.DNSKEY.KeyTag())
+			}
+		}
+		if tc.deleted && len(u.Remove) != len(tc.current) {
+			t.Errorf("%s: got %d removed DS records, want %d", tc.name, len(u.Remove), len(tc.current))
+		}
+	}
+
+	u, err := agent(both, cds(ksk), cds(ksk2)).Update("example.org.", current, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !u.Changed() || len(u.Add) != 1 || len(u.Remove) != 0 || !matchAnchor(u.Add[0], ksk2.DNSKEY) {
+		t.Errorf("got added %v and removed %v, want the DS of the new key added", u.Add, u.Remove)
+	}
+
+	// Bootstrapping an unsigned delegation.
+	a := agent(both, cds(ksk2))
+	a.Bootstrap = true
+	u, err = a.Update("example.org.", nil, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(u.Add) != 1 || !matchAnchor(u.Add[0], ksk2.DNSKEY) || u.Add[0].Header().Rrtype != TypeDS {
+		t.Errorf("got DS %v after bootstrapping", u.Add)
+	}
+}
@@ -0,0 +1,349 @@
+package dns
+
+// Verification of authenticated denial of existence, RFC 4035 section 5.4
+// for NSEC and RFC 5155 section 8 for NSEC3, with the clarifications of RFC
+// 6840 and the iteration limits of RFC 9276.
+
+// NSEC3MaxIterations is the default limit on the additional iterations of
+// NSEC3 records. A denial that relies on records with more iterations is
+// insecure, see RFC 9276 section 3.2.
+const NSEC3MaxIterations = 100
+
+// Denial verifies the proofs of non-existence in a negative response. The
+// records must be validated first, Denial only checks that they prove what
+// they should.
+//
+// Every method returns Secure if the proof holds and Bogus with ErrDenial if
+// it does not. They return Insecure if the proof relies on an NSEC3 record
+// with the opt-out flag (ErrInsecure), or if the NSEC3 records can not be
+// used because of their hash algorithm (ErrAlg) or iterations
+// (ErrIterations).
+type Denial struct {
+	Records       []RR   // the NSEC or NSEC3 records of the response, other records are ignored
+	MaxIterations uint16 // NSEC3 records with more iterations are ignored, NSEC3MaxIterations if zero
+}
+
+// NameError verifies the proof for a name error, RCODE NXDOMAIN: qname and
+// the wildcard at its closest encloser do not exist.
+func (d *Denial) NameError(qname string) (SecurityStatus, error) {
+	nsec, nsec3, err := d.split()
+	if err != nil {
+		return Insecure, err
+	}
+	if len(nsec3) > 0 {
+		if nsec3Match(nsec3, qname) != nil {
+			return Bogus, ErrDenial
+		}
+		ce, optOut, ok := closestEncloser(nsec3, qname)
+		if !ok || nsec3Cover(nsec3, wildcardName(ce)) == nil {
+			return Bogus, ErrDenial
+		}
+		if optOut {
+			// An unsigned delegation may exist, RFC 5155 section 9.2.
+			return Insecure, ErrInsecure
+		}
+		return Secure, nil
+	}
+
+	rr := nsecDeny(nsec, qname)
+	if rr == nil {
+		return Bogus, ErrDenial
+	}
+	if nsecDeny(nsec, wildcardName(nsecEncloser(rr, qname))) == nil {
+		return Bogus, ErrDenial
+	}
+	return Secure, nil
+}
+
+// NoData verifies the proof that qname has no data of type qtype. This
+// covers empty non-terminals and wildcards without qtype, and for DS the
+// unsigned delegations in an opt-out span.
+func (d *Denial) NoData(qname string, qtype uint16) (SecurityStatus, error) {
+	nsec, nsec3, err := d.split()
+	if err != nil {
+		return Insecure, err
+	}
+	if len(nsec3) > 0 {
+		if rr := nsec3Match(nsec3, qname); rr != nil {
+			return noData(rr.TypeBitMap, qname, qtype)
+		}
+		ce, optOut, ok := closestEncloser(nsec3, qname)
+		if !ok {
+			return Bogus, ErrDenial
+		}
+		if rr := nsec3Match(nsec3, wildcardName(ce)); rr != nil {
+			return noData(rr.TypeBitMap, qname, qtype)
+		}
+		if optOut {
+			// An unsigned delegation, or an empty non-terminal above
+			// those, without an NSEC3 record. RFC 5155 section 8.6.
+			return Insecure, ErrInsecure
+		}
+		return Bogus, ErrDenial
+	}
+
+	if rr := nsecMatch(nsec, qname); rr != nil {
+		return noData(rr.TypeBitMap, qname, qtype)
+	}
+	for _, rr := range nsec {
+		if nsecCovers(rr, qname) && IsSubDomain(qname, rr.NextDomain) {
+			// An empty non-terminal.
+			return Secure, nil
+		}
+	}
+	if rr := nsecDeny(nsec, qname); rr != nil {
+		if w := nsecMatch(nsec, wildcardName(nsecEncloser(rr, qname))); w != nil {
+			return noData(w.TypeBitMap, qname, qtype)
+		}
+	}
+	return Bogus, ErrDenial
+}
+
+// Wildcard verifies the proof that the answer for qname could be synthesized
+// from a wildcard: the name below the closest encloser does not exist. The
+// closest encloser has labels labels, the Labels of the RRSIG of the answer.
+func (d *Denial) Wildcard(qname string, labels uint8) (SecurityStatus, error) {
+	if int(labels) >= CountLabel(qname) {
+		// Not from a wildcard, there is nothing to prove.
+		return Secure, nil
+	}
+	nsec, nsec3, err := d.split()
+	if err != nil {
+		return Insecure, err
+	}
+	next := nextCloser(qname, ancestor(qname, int(labels)))
+	if len(nsec3) > 0 {
+		rr := nsec3Cover(nsec3, next)
+		switch {
+		case rr == nil:
+			return Bogus, ErrDenial
+		case rr.Flags&1 == 1:
+			return Insecure, ErrInsecure
+		}
+		return Secure, nil
+	}
+	if nsecDeny(nsec, next) == nil {
+		return Bogus, ErrDenial
+	}
+	return Secure, nil
+}
+
+// NoDS verifies the proof that there are no DS records for name, from a
+// response of the parent zone. It returns Insecure and ErrInsecure if name
+// is a delegation, or may be one in an opt-out span, so the zone below it is
+// unsigned. It returns Secure if name is not a delegation.
+func (d *Denial) NoDS(name string) (SecurityStatus, error) {
+	nsec, nsec3, err := d.split()
+	if err != nil {
+		return Insecure, err
+	}
+	var bitmap []uint16
+	switch {
+	case len(nsec3) > 0:
+		if rr := nsec3Match(nsec3, name); rr != nil {
+			bitmap = rr.TypeBitMap
+			break
+		}
+		_, optOut, ok := closestEncloser(nsec3, name)
+		switch {
+		case !ok:
+			return Bogus, ErrDenial
+		case optOut:
+			return Insecure, ErrInsecure
+		}
+		return Secure, nil
+	default:
+		if rr := nsecMatch(nsec, name); rr != nil {
+			bitmap = rr.TypeBitMap
+			break
+		}
+		for _, rr := range nsec {
+			if nsecCovers(rr, name) && !nsecCut(rr, name) {
+				// name does not exist, or is an empty non-terminal.
+				return Secure, nil
+			}
+		}
+		return Bogus, ErrDenial
+	}
+
+	// The SOA bit means the record is from the child zone, which can not
+	// prove the absence of a DS.
+	if hasType(bitmap, TypeDS) || hasType(bitmap, TypeSOA) {
+		return Bogus, ErrDenial
+	}
+	if hasType(bitmap, TypeNS) {
+		return Insecure, ErrInsecure
+	}
+	return Secure, nil
+}
+
+// split returns the NSEC and the usable NSEC3 records. It returns an error if
+// there are only NSEC3 records that can not be used.
+func (d *Denial) split() (nsec []*NSEC, nsec3 []*NSEC3, err error) {
+	max := d.MaxIterations
+	if max == 0 {
+		max = NSEC3MaxIterations
+	}
+	for _, rr := range d.Records {
+		switch rr := rr.(type) {
+		case *NSEC:
+			nsec = append(nsec, rr)
+		case *NSEC3:
+			switch {
+			case rr.Hash != SHA1:
+				err = ErrAlg
+			case rr.Iterations > max:
+				err = ErrIterations
+			default:
+				nsec3 = append(nsec3, rr)
+			}
+		}
+	}
+	if len(nsec3) > 0 || len(nsec) > 0 {
+		err = nil
+	}
+	return nsec, nsec3, err
+}
+
+// noData checks the type bitmap of the record matching qname for a NODATA
+// response for qtype.
+func noData(bitmap []uint16, qname string, qtype uint16) (SecurityStatus, error) {
+	if hasType(bitmap, qtype) || qtype != TypeCNAME && hasType(bitmap, TypeCNAME) {
+		return Bogus, ErrDenial
+	}
+	if qtype == TypeDS {
+		// From the child zone, except for the root which has no parent.
+		if hasType(bitmap, TypeSOA) && qname != "." {
+			return Bogus, ErrDenial
+		}
+		return Secure, nil
+	}
+	// From the parent side of a delegation, RFC 6840 section 4.4.
+	if hasType(bitmap, TypeNS) && !hasType(bitmap, TypeSOA) {
+		return Bogus, ErrDenial
+	}
+	return Secure, nil
+}
+
+// hasType returns true if t is in bitmap.
+func hasType(bitmap []uint16, t uint16) bool {
+	for _, b := range bitmap {
+		if b == t {
+			return true
+		}
+	}
+	return false
+}
+
+// nsecMatch returns the NSEC record owned by name.
+func nsecMatch(nsec []*NSEC, name string) *NSEC {
+	for _, rr := range nsec {
+		if equal(rr.Hdr.Name, name) {
+			return rr
+		}
+	}
+	return nil
+}
+
+// nsecDeny returns the NSEC record that proves that name does not exist: it
+// covers name, is not from above a zone cut or DNAME, and name is not an empty
+// non-terminal.
+func nsecDeny(nsec []*NSEC, name string) *NSEC {
+	for _, rr := range nsec {
+		if nsecCovers(rr, name) && !nsecCut(rr, name) && !IsSubDomain(name, rr.NextDomain) {
+			return rr
+		}
+	}
+	return nil
+}
+
+// nsecCut returns true if rr is owned by an ancestor of name that is a
+// delegation or DNAME, so it can not prove anything about name, RFC 6840
+// section 4.1.
+func nsecCut(rr *NSEC, name string) bool {
+	if !IsSubDomain(rr.Hdr.Name, name) {
+		return false
+	}
+	return hasType(rr.TypeBitMap, TypeDNAME) || hasType(rr.TypeBitMap, TypeNS) && !hasType(rr.TypeBitMap, TypeSOA)
+}
+
+// nsecCovers returns true if name sorts between the owner and next name of
+// rr, the last NSEC in a zone covers the names after its owner.
+func nsecCovers(rr *NSEC, name string) bool {
+	owner, next := rr.Hdr.Name, rr.NextDomain
+	if canonicalNameCompare(owner, next) >= 0 {
+		return canonicalNameCompare(owner, name) < 0 && IsSubDomain(next, name)
+	}
+	return canonicalNameCompare(owner, name) < 0 && canonicalNameCompare(name, next) < 0
+}
+
+// nsecEncloser returns the closest encloser of name, proven by rr which
+// covers it: the longest ancestor shared with the owner or next name.
+func nsecEncloser(rr *NSEC, name string) string {
+	n := CompareDomainName(rr.Hdr.Name, name)
+	if m := CompareDomainName(rr.NextDomain, name); m > n {
+		n = m
+	}
+	return ancestor(name, n)
+}
+
+// nsec3Match returns the NSEC3 record that matches name.
+func nsec3Match(nsec3 []*NSEC3, name string) *NSEC3 {
+	for _, rr := range nsec3 {
+		if rr.Match(name) {
+			return rr
+		}
+	}
+	return nil
+}
+
+// nsec3Cover returns the NSEC3 record that covers name.
+func nsec3Cover(nsec3 []*NSEC3, name string) *NSEC3 {
+	for _, rr := range nsec3 {
+		if rr.Cover(name) {
+			return rr
+		}
+	}
+	return nil
+}
+
+// closestEncloser finds the closest provable encloser of name, RFC 5155
+// section 8.3: the longest ancestor with a matching NSEC3 record while the
+// next closer name is covered. It also returns the opt-out flag of the record
+// covering the next closer name.
+func closestEncloser(nsec3 []*NSEC3, name string) (ce string, optOut, ok bool) {
+	idx := Split(name)
+	for i := 1; i <= len(idx); i++ {
+		ce = "."
+		if i < len(idx) {
+			ce = name[idx[i]:]
+		}
+		rr := nsec3Match(nsec3, ce)
+		if rr == nil {
+			continue
+		}
+		// An ancestor that is a delegation or DNAME can not be the closest
+		// encloser, the name is not in this zone.
+		if hasType(rr.TypeBitMap, TypeDNAME) || hasType(rr.TypeBitMap, TypeNS) && !hasType(rr.TypeBitMap, TypeSOA) {
+			return "", false, false
+		}
+		cover := nsec3Cover(nsec3, name[idx[i-1]:])
+		if cover == nil {
+			return "", false, false
+		}
+		return ce, cover.Flags&1 == 1, true
+	}
+	return "", false, false
+}
+
+// ancestor returns the ancestor of name with n labels.
+func ancestor(name string, n int) string {
+	idx := Split(name)
+	switch {
+	case n <= 0:
+		return "."
+	case n >= len(idx):
+		return name
+	}
+	return name[idx[len(idx)-n]:]
+}
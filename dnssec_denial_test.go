@@ -0,0 +1,109 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDenial(t *testing.T) {
+	policies := []*SignPolicy{{}, {NSEC3: true, Salt: "AABB", Iterations: 1}, {NSEC3: true, OptOut: true}}
+	tests := []struct {
+		check string
+		name  string
+		qtype uint16
+		proof string // the query whose answer has the records
+		ptype uint16
+		want  [3]SecurityStatus // with NSEC, NSEC3 and NSEC3 with opt-out
+	}{
+		{"NameError", "nope.example.org.", 0, "nope.example.org.", TypeA, [3]SecurityStatus{Secure, Secure, Insecure}},
+		{"NameError", "web.example.org.", 0, "nope.example.org.", TypeA, [3]SecurityStatus{Bogus, Bogus, Bogus}},
+		{"NameError", "ent.example.org.", 0, "ent.example.org.", TypeA, [3]SecurityStatus{Bogus, Bogus, Bogus}},
+		{"NoData", "web.example.org.", TypeMX, "web.example.org.", TypeMX, [3]SecurityStatus{Secure, Secure, Secure}},
+		{"NoData", "web.example.org.", TypeA, "web.example.org.", TypeMX, [3]SecurityStatus{Bogus, Bogus, Bogus}},
+		{"NoData", "ent.example.org.", TypeA, "ent.example.org.", TypeA, [3]SecurityStatus{Secure, Secure, Secure}},
+		{"NoData", "x.wild.example.org.", TypeAAAA, "x.wild.example.org.", TypeAAAA, [3]SecurityStatus{Secure, Secure, Secure}},
+		{"NoData", "x.wild.example.org.", TypeTXT, "x.wild.example.org.", TypeAAAA, [3]SecurityStatus{Bogus, Bogus, Bogus}},
+		{"NoData", "insecure.example.org.", TypeA, "insecure.example.org.", TypeDS, [3]SecurityStatus{Bogus, Bogus, Insecure}},
+		{"Wildcard", "x.wild.example.org.", 3, "x.wild.example.org.", TypeTXT, [3]SecurityStatus{Secure, Secure, Insecure}},
+		{"Wildcard", "x.wild.example.org.", 3, "example.org.", TypeSOA, [3]SecurityStatus{Bogus, Bogus, Bogus}},
+		{"NoDS", "insecure.example.org.", 0, "insecure.example.org.", TypeDS, [3]SecurityStatus{Insecure, Insecure, Insecure}},
+		{"NoDS", "web.example.org.", 0, "web.example.org.", TypeDS, [3]SecurityStatus{Secure, Secure, Secure}},
+		{"NoDS", "insecure.example.org.", 0, "web.example.org.", TypeDS, [3]SecurityStatus{Bogus, Bogus, Bogus}},
+	}
+
+	for i, p := range policies {
+		z, _ := signedTestZone(t, p, time.Time{})
+		for _, tc := range tests {
+			req := new(Msg)
+			req.SetQuestion(tc.proof, tc.ptype)
+			req.SetEdns0(4096, true)
+			d := &Denial{Records: z.Answer(req).Ns}
+
+			var status SecurityStatus
+			var err error
+			switch tc.check {
+			case "NameError":
+				status, err = d.NameError(tc.name)
+			case "NoData":
+				status, err = d.NoData(tc.name, tc.qtype)
+			case "Wildcard":
+				status, err = d.Wildcard(tc.name, uint8(tc.qtype))
+			case "NoDS":
+				status, err = d.NoDS(tc.name)
+			}
+			if status != tc.want[i] {
+				t.Errorf("policy %d: %s(%s) with the proof for %s/%s: got %s, want %s", i, tc.check, tc.name, tc.proof, Type(tc.ptype), status, tc.want[i])
+			}
+			if (status == Secure) != (err == nil) {
+				t.Errorf("policy %d: %s(%s): got error %v for %s", i, tc.check, tc.name, err, status)
+			}
+		}
+	}
+}
+
+func TestDenialIterations(t *testing.T) {
+	z, _ := signedTestZone(t, &SignPolicy{NSEC3: true, Iterations: 150}, time.Time{})
+	req := new(Msg)
+	req.SetQuestion("nope.example.org.", TypeA)
+	req.SetEdns0(4096, true)
+	m := z.Answer(req)
+
+	d := &Denial{Records: m.Ns}
+	if status, err := d.NameError("nope.example.org."); status != Insecure || err != ErrIterations {
+		t.Errorf("got %s, %v with 150 iterations", status, err)
+	}
+	d.MaxIterations = 150
+	if status, err := d.NameError("nope.example.org."); status != Secure {
+		t.Errorf("got %s, %v with 150 iterations allowed", status, err)
+	}
+
+	for _, rr := range m.Ns {
+		if rr, ok := rr.(*NSEC3); ok {
+			rr.Hash = 2
+		}
+	}
+	if status, err := d.NameError("nope.example.org."); status != Insecure || err != ErrAlg {
+		t.Errorf("got %s, %v with an unknown hash algorithm", status, err)
+	}
+	if status, err := (&Denial{}).NameError("nope.example.org."); status != Bogus || err != ErrDenial {
+		t.Errorf("got %s, %v without records", status, err)
+	}
+}
+
+func TestAncestor(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		n    int
+		want string
+	}{
+		{"a.b.example.org.", 2, "example.org."},
+		{"a.b.example.org.", 0, "."},
+		{"a.b.example.org.", 4, "a.b.example.org."},
+		{"a.b.example.org.", 5, "a.b.example.org."},
+		{".", 1, "."},
+	} {
+		if got := ancestor(tc.name, tc.n); got != tc.want {
+			t.Errorf("ancestor(%s, %d) = %s, want %s", tc.name, tc.n, got, tc.want)
+		}
+	}
+}
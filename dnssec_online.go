@@ -0,0 +1,423 @@
+package dns
+
+// Online signing of responses, with minimally covering NSEC records ("white
+// lies", RFC 4470) or compact denial of existence (RFC 9824) for negative
+// answers.
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// onlineValidity is the validity of the signatures if none is given.
+	onlineValidity = 24 * time.Hour
+	// signatureSkew is how far the inception of signatures lies in the past,
+	// for validators with a clock running behind.
+	signatureSkew = time.Hour
+	// onlineCacheSize is the maximum number of signed RRsets that are cached.
+	onlineCacheSize = 10000
+)
+
+// nodataTypes are listed in the NSEC record of a NODATA response. The signer
+// does not know which types exist at the name, listing the common ones,
+// except the queried type, prevents validators from using the NSEC to deny
+// their existence (RFC 8198).
+var nodataTypes = []uint16{TypeA, TypeHINFO, TypeMX, TypeTXT, TypeAAAA, TypeLOC, TypeSRV, TypeNAPTR,
+	TypeCERT, TypeSSHFP, TypeTLSA, TypeSMIMEA, TypeHIP, TypeOPENPGPKEY, TypeSPF, TypeURI, TypeCAA}
+
+// OnlineSigner is a Handler that signs the responses of another Handler on
+// the fly, so a zone can be served with DNSSEC from a dynamic backend without
+// a signed zone file. Only responses to queries with the DO bit set are
+// signed.
+//
+// Negative answers get synthesized NSEC records that cover only the queried
+// name, as described in RFC 4470. With Compact set, a non-existent name is
+// instead denied with a single NSEC at the name, listing the NXNAME type, and
+// the RCODE is NOERROR unless the query has the CO bit set, see RFC 9824.
+//
+// DNSKEY queries for the apex are answered with the DNSKEYs of Keys. The
+// signatures are cached and reused for half of their validity.
+type OnlineSigner struct {
+	Handler  Handler       // the handler whose responses are signed
+	Zone     string        // the fully qualified apex of the zone, the signer name of the signatures
+	Keys     []SigningKey  // key signing keys sign the DNSKEY, CDS and CDNSKEY RRsets, zone signing keys all others
+	Validity time.Duration // the validity of the signatures, a day if zero
+	Compact  bool          // deny names with compact denial of existence instead of white lies
+
+	now func() time.Time // returns the current time, time.Now if nil
+
+	m     sync.Mutex
+	cache map[string]*onlineSigs // the signatures of RRsets, by their canonical text
+}
+
+type onlineSigs struct {
+	sigs    []RR
+	refresh time.Time // the signatures are reused until then
+}
+
+// ServeDNS implements the Handler interface.
+func (s *OnlineSigner) ServeDNS(w ResponseWriter, r *Msg) {
+	opt := r.IsEdns0()
+	if opt == nil || !opt.Do() || len(r.Question) != 1 {
+		s.Handler.ServeDNS(w, r)
+		return
+	}
+	sw := &signingWriter{ResponseWriter: w, s: s, co: opt.Co()}
+	if q := r.Question[0]; q.Qtype == TypeDNSKEY && strings.EqualFold(q.Name, s.Zone) {
+		m := new(Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		for _, k := range s.Keys {
+			m.Answer = append(m.Answer, Copy(k.DNSKEY))
+		}
+		m.SetEdns0(opt.UDPSize(), true)
+		sw.WriteMsg(m)
+		return
+	}
+	s.Handler.ServeDNS(sw, r)
+}
+
+// signingWriter signs the messages written to the ResponseWriter.
+type signingWriter struct {
+	ResponseWriter
+	s  *OnlineSigner
+	co bool // the query has the CO bit set
+}
+
+// WriteMsg implements the ResponseWriter interface. If the message cannot be
+// signed, a SERVFAIL is written instead.
+func (w *signingWriter) WriteMsg(m *Msg) error {
+	if err := w.s.sign(m, w.co); err != nil {
+		r := new(Msg)
+		r.SetRcode(m, RcodeServerFailure)
+		m = r
+	}
+	return w.ResponseWriter.WriteMsg(m)
+}
+
+// sign adds the denial of existence records and signatures to the response m.
+func (s *OnlineSigner) sign(m *Msg, co bool) error {
+	if len(m.Question) != 1 || m.Rcode != RcodeSuccess && m.Rcode != RcodeNameError {
+		return nil
+	}
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	s.deny(m, co)
+
+	var err error
+	if m.Answer, err = s.signSection(m.Answer, now, nil); err != nil {
+		return err
+	}
+	// The NS records of a delegation, and the glue below it, are not signed.
+	// The DS and NSEC records at the delegation are.
+	var cuts []string
+	for _, rr := range m.Ns {
+		if rr.Header().Rrtype == TypeNS && !strings.EqualFold(rr.Header().Name, s.Zone) {
+			cuts = append(cuts, rr.Header().Name)
+		}
+	}
+	below := func(rr RR) bool {
+		for _, c := range cuts {
+			if !IsSubDomain(c, rr.Header().Name) {
+				continue
+			}
+			if t := rr.Header().Rrtype; t != TypeDS && t != TypeNSEC || !strings.EqualFold(c, rr.Header().Name) {
+				return true
+			}
+		}
+		return false
+	}
+	if m.Ns, err = s.signSection(m.Ns, now, below); err != nil {
+		return err
+	}
+	m.Extra, err = s.signSection(m.Extra, now, below)
+	return err
+}
+
+// deny adds the NSEC records proving the non-existence of the name, type or
+// DS record to the authority section of m.
+func (s *OnlineSigner) deny(m *Msg, co bool) {
+	var (
+		soa  *SOA
+		cut  string
+		isDS bool
+	)
+	for _, rr := range m.Ns {
+		switch rr := rr.(type) {
+		case *SOA:
+			soa = rr
+		case *NS:
+			if !strings.EqualFold(rr.Hdr.Name, s.Zone) {
+				cut = rr.Hdr.Name
+			}
+		case *DS:
+			isDS = true
+		}
+	}
+	if cut != "" && len(m.Answer) == 0 {
+		// A referral to an unsigned delegation. The NSEC proving the DS
+		// does not exist is at the delegation, as is the NS RRset, and gets
+		// its TTL.
+		if !isDS && IsSubDomain(s.Zone, cut) {
+			var ttl uint32
+			for _, rr := range m.Ns {
+				if rr.Header().Rrtype == TypeNS && strings.EqualFold(rr.Header().Name, cut) {
+					ttl = rr.Header().Ttl
+				}
+			}
+			m.Ns = append(m.Ns, onlineNSEC(cut, "\\000."+cut, ttl, TypeNS, TypeRRSIG, TypeNSEC))
+		}
+		return
+	}
+	if soa == nil {
+		return
+	}
+	ttl := soa.Hdr.Ttl
+	if soa.Minttl < ttl {
+		ttl = soa.Minttl
+	}
+
+	// The denied name is the last one in the CNAME chain.
+	q := m.Question[0]
+	name := q.Name
+	for _, rr := range m.Answer {
+		if c, ok := rr.(*CNAME); ok && strings.EqualFold(c.Hdr.Name, name) {
+			name = c.Target
+		}
+	}
+	if !IsSubDomain(s.Zone, name) {
+		return
+	}
+	for _, rr := range m.Answer {
+		if strings.EqualFold(rr.Header().Name, name) {
+			// Not a negative answer.
+			return
+		}
+	}
+
+	next := "\\000." + name
+	if name == "." {
+		next = "\\000."
+	}
+	switch {
+	case m.Rcode == RcodeNameError && s.Compact:
+		if !co {
+			m.Rcode = RcodeSuccess
+		}
+		m.Ns = append(m.Ns, onlineNSEC(name, next, ttl, TypeRRSIG, TypeNSEC, TypeNXNAME))
+	case m.Rcode == RcodeNameError:
+		// Cover the name and the wildcard at its parent, the closest encloser
+		// a validator derives from the first NSEC.
+		m.Ns = append(m.Ns, onlineNSEC(prevName(name), next, ttl, TypeRRSIG, TypeNSEC))
+		off, _ := NextLabel(name, 0)
+		wild := wildcardName(name[off:])
+		m.Ns = append(m.Ns, onlineNSEC(prevName(wild), "\\000."+wild, ttl, TypeRRSIG, TypeNSEC))
+	default:
+		types, candidates := []uint16{TypeRRSIG, TypeNSEC}, nodataTypes
+		if strings.EqualFold(name, s.Zone) {
+			candidates = append([]uint16{TypeNS, TypeSOA, TypeDNSKEY}, nodataTypes...)
+		}
+		for _, t := range candidates {
+			if t != q.Qtype {
+				types = append(types, t)
+			}
+		}
+		m.Ns = append(m.Ns, onlineNSEC(name, next, ttl, types...))
+	}
+}
+
+// onlineNSEC returns an NSEC record for owner with the types in its bitmap.
+func onlineNSEC(owner, next string, ttl uint32, types ...uint16) *NSEC {
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return &NSEC{
+		Hdr:        RR_Header{Name: owner, Rrtype: TypeNSEC, Class: ClassINET, Ttl: ttl},
+		NextDomain: next,
+		TypeBitMap: types,
+	}
+}
+
+// signSection returns the records of section with the signatures over their
+// RRsets added. RRsets outside of the zone, or for which skip returns true,
+// are not signed, nor are CNAMEs synthesized from a DNAME.
+func (s *OnlineSigner) signSection(section []RR, now time.Time, skip func(RR) bool) ([]RR, error) {
+	type key struct {
+		name  string
+		rtype uint16
+		class uint16
+	}
+	var (
+		order  []key
+		rrsets = make(map[key][]RR)
+		dnames []string
+	)
+	for _, rr := range section {
+		h := rr.Header()
+		switch h.Rrtype {
+		case TypeRRSIG, TypeOPT, TypeTSIG, TypeSIG:
+			continue
+		case TypeDNAME:
+			dnames = append(dnames, h.Name)
+		}
+		if !IsSubDomain(s.Zone, h.Name) || skip != nil && skip(rr) {
+			continue
+		}
+		k := key{strings.ToLower(h.Name), h.Rrtype, h.Class}
+		if _, ok := rrsets[k]; !ok {
+			order = append(order, k)
+		}
+		rrsets[k] = append(rrsets[k], rr)
+	}
+
+Sets:
+	for _, k := range order {
+		if k.rtype == TypeCNAME {
+			for _, d := range dnames {
+				if IsSubDomain(d, k.name) && !strings.EqualFold(d, k.name) {
+					continue Sets
+				}
+			}
+		}
+		sigs, err := s.signatures(rrsets[k], now)
+		if err != nil {
+			return nil, err
+		}
+		section = append(section, sigs...)
+	}
+	return section, nil
+}
+
+// signatures returns the RRSIGs over rrset, from the cache if they are not
+// due to be refreshed.
+func (s *OnlineSigner) signatures(rrset []RR, now time.Time) ([]RR, error) {
+	text := make([]string, len(rrset))
+	for i, rr := range rrset {
+		text[i] = strings.ToLower(rr.String())
+	}
+	sort.Strings(text)
+	k := strings.Join(text, "\n")
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	if e, ok := s.cache[k]; ok && now.Before(e.refresh) {
+		return onlineCopy(e.sigs), nil
+	}
+
+	validity := s.Validity
+	if validity == 0 {
+		validity = onlineValidity
+	}
+	sigs, err := signRRset(s.Keys, rrset, s.Zone, uint32(now.Add(-signatureSkew).Unix()), uint32(now.Add(validity).Unix()))
+	if err != nil {
+		return nil, err
+	}
+	if s.cache == nil {
+		s.cache = make(map[string]*onlineSigs)
+	}
+	if len(s.cache) >= onlineCacheSize {
+		for k, e := range s.cache {
+			if !now.Before(e.refresh) {
+				delete(s.cache, k)
+			}
+		}
+		if len(s.cache) >= onlineCacheSize {
+			s.cache = make(map[string]*onlineSigs)
+		}
+	}
+	s.cache[k] = &onlineSigs{sigs: sigs, refresh: now.Add(validity / 2)}
+	return onlineCopy(sigs), nil
+}
+
+func onlineCopy(rrs []RR) []RR {
+	c := make([]RR, len(rrs))
+	for i, rr := range rrs {
+		c[i] = Copy(rr)
+	}
+	return c
+}
+
+// signingKeys returns the keys that sign RRsets of type t: the key signing
+// keys for the DNSKEY, CDS and CDNSKEY RRsets, RFC 7344 section 4.1, and the
+// zone signing keys for all others. If there are no keys of the wanted kind,
+// the keys of the other kind are returned.
+func signingKeys(keys []SigningKey, t uint16) []SigningKey {
+	ksk := t == TypeDNSKEY || t == TypeCDS || t == TypeCDNSKEY
+	var use []SigningKey
+	for _, sep := range []bool{ksk, !ksk} {
+		for _, k := range keys {
+			if k.DNSKEY.Flags&SEP == SEP == sep {
+				use = append(use, k)
+			}
+		}
+		if len(use) > 0 {
+			break
+		}
+	}
+	return use
+}
+
+// signRRset returns the RRSIGs over rrset made with the keys that sign its
+// type, valid from inception until expiration.
+func signRRset(keys []SigningKey, rrset []RR, signer string, inception, expiration uint32) ([]RR, error) {
+	var sigs []RR
+	for _, k := range signingKeys(keys, rrset[0].Header().Rrtype) {
+		sig := &RRSIG{
+			Hdr:        RR_Header{Ttl: rrset[0].Header().Ttl},
+			KeyTag:     k.DNSKEY.KeyTag(),
+			SignerName: signer,
+			Algorithm:  k.DNSKEY.Algorithm,
+			Inception:  inception,
+			Expiration: expiration,
+		}
+		if err := sig.Sign(k.Signer, rrset); err != nil {
+			return nil, err
+		}
+		sigs = append(sigs, sig)
+	}
+	if len(sigs) == 0 {
+		return nil, ErrKey
+	}
+	return sigs, nil
+}
+
+// prevName returns a name that sorts just before name in the canonical
+// order, so an NSEC record from it up to the first name below name covers
+// only name and the names below it, which must not exist.
+func prevName(name string) string {
+	off, end := NextLabel(name, 0)
+	if end {
+		return name
+	}
+	b := []byte(canonicalLabel(name[:off-1]))
+	parent := name[off:]
+	if b[len(b)-1] == 0 {
+		if len(b) == 1 {
+			// The first name below parent.
+			return parent
+		}
+		b = b[:len(b)-1]
+	} else {
+		b[len(b)-1]--
+		if c := b[len(b)-1]; c >= 'A' && c <= 'Z' {
+			// Sorted as lower case, skip past the upper case letters.
+			b[len(b)-1] = 'A' - 1
+		}
+	}
+	// Names below the label sort after it, prefix a label that sorts after
+	// all of them but the most contrived ones.
+	return "\\255." + labelString(b) + "." + parent
+}
+
+// labelString returns the presentation format of the label with octets b.
+func labelString(b []byte) string {
+	wire := append([]byte{byte(len(b))}, b...)
+	s, _, err := UnpackDomainName(append(wire, 0), 0)
+	if err != nil {
+		return ""
+	}
+	return s[:len(s)-1]
+}
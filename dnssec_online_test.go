@@ -0,0 +1,237 @@
+package dns
+
+import (
+	"crypto"
+	"strings"
+	"testing"
+	"time"
+)
+
+// recorder is a ResponseWriter that keeps the message written.
+type recorder struct {
+	ResponseWriter
+	m *Msg
+}
+
+func (r *recorder) WriteMsg(m *Msg) error {
+	r.m = m
+	return nil
+}
+
+func newSigningKey(t *testing.T, zone string, flags uint16) SigningKey {
+	k := &DNSKEY{Hdr: RR_Header{Name: zone, Rrtype: TypeDNSKEY, Class: ClassINET, Ttl: 3600}, Flags: flags, Protocol: 3, Algorithm: ECDSAP256SHA256}
+	priv, err := k.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return SigningKey{DNSKEY: k, Signer: priv.(crypto.Signer)}
+}
+
+// verifySigs verifies the RRSIGs in rrs over the RRsets they cover with keys
+// and returns the number of RRSIGs.
+func verifySigs(t *testing.T, name string, rrs []RR, keys []SigningKey) int {
+	n := 0
+	for _, rr := range rrs {
+		sig, ok := rr.(*RRSIG)
+		if !ok {
+			continue
+		}
+		n++
+		var rrset []RR
+		for _, r := range rrs {
+			if r.Header().Rrtype == sig.TypeCovered && strings.EqualFold(r.Header().Name, sig.Hdr.Name) {
+				rrset = append(rrset, r)
+			}
+		}
+		var key *DNSKEY
+		for _, k := range keys {
+			if k.DNSKEY.KeyTag() == sig.KeyTag {
+				key = k.DNSKEY
+			}
+		}
+		if key == nil {
+			t.Errorf("%s: no key for %s", name, sig)
+			continue
+		}
+		if err := sig.Verify(key, rrset); err != nil {
+			t.Errorf("%s: failed to verify %s: %v", name, sig, err)
+		}
+	}
+	return n
+}
+
+func TestOnlineSigner(t *testing.T) {
+	z, err := ReadZone(strings.NewReader(testZone), "example.org.", "testZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ksk, zsk := newSigningKey(t, "example.org.", ZONE|SEP), newSigningKey(t, "example.org.", ZONE)
+	s := &OnlineSigner{Handler: z, Zone: "example.org.", Keys: []SigningKey{ksk, zsk}}
+	query := func(qname string, qtype uint16, do bool) *Msg {
+		req := new(Msg)
+		req.SetQuestion(qname, qtype)
+		req.SetEdns0(4096, do)
+		w := new(recorder)
+		s.ServeDNS(w, req)
+		return w.m
+	}
+
+	m := query("www.example.org.", TypeA, true)
+	if n := verifySigs(t, "www", m.Answer, s.Keys); n != 2 {
+		t.Errorf("got %d RRSIGs in the answer, want 2", n)
+	}
+	if m := query("www.example.org.", TypeA, false); len(m.Answer) != 2 {
+		t.Errorf("expected an unsigned answer without the DO bit, got %v", m.Answer)
+	}
+
+	m = query("example.org.", TypeDNSKEY, true)
+	if n := verifySigs(t, "DNSKEY", m.Answer, []SigningKey{ksk}); n != 1 || len(m.Answer) != 3 {
+		t.Errorf("expected the DNSKEYs signed by the KSK, got %v", m.Answer)
+	}
+
+	// NXDOMAIN, the NSEC records must cover the name and the wildcard, but
+	// none of the names in the zone.
+	m = query("nope.example.org.", TypeA, true)
+	if m.Rcode != RcodeNameError {
+		t.Errorf("got rcode %s, want NXDOMAIN", RcodeToString[m.Rcode])
+	}
+	var nsec []*NSEC
+	for _, rr := range m.Ns {
+		if rr, ok := rr.(*NSEC); ok {
+			nsec = append(nsec, rr)
+		}
+	}
+	if len(nsec) != 2 || verifySigs(t, "nope", m.Ns, s.Keys) != 3 {
+		t.Fatalf("expected two signed NSEC records and a signed SOA, got %v", m.Ns)
+	}
+	covers := func(n *NSEC, name string) bool {
+		return canonicalNameCompare(n.Hdr.Name, name) < 0 && canonicalNameCompare(name, n.NextDomain) < 0
+	}
+	if !covers(nsec[0], "nope.example.org.") || !covers(nsec[1], "*.example.org.") {
+		t.Errorf("NSEC records do not cover the name and wildcard: %v", nsec)
+	}
+	for name := range z.nodes {
+		if covers(nsec[0], name) || covers(nsec[1], name) {
+			t.Errorf("NSEC records cover %s", name)
+		}
+	}
+
+	// NODATA, the NSEC is at the name and lists other types.
+	m = query("web.example.org.", TypeAAAA, true)
+	if len(m.Ns) != 4 || verifySigs(t, "nodata", m.Ns, s.Keys) != 2 {
+		t.Fatalf("expected a signed NSEC and a signed SOA, got %v", m.Ns)
+	}
+	n := m.Ns[1].(*NSEC)
+	for _, typ := range n.TypeBitMap {
+		if typ == TypeAAAA {
+			t.Errorf("NSEC lists the queried type: %s", n)
+		}
+	}
+	if n.Hdr.Name != "web.example.org." || n.Hdr.Ttl != 300 {
+		t.Errorf("got NSEC %s", n)
+	}
+
+	// A referral, with a signed DS but unsigned NS records.
+	m = query("www.sub.example.org.", TypeA, true)
+	if verifySigs(t, "referral", m.Ns, s.Keys) != 1 {
+		t.Errorf("expected only the DS to be signed, got %v", m.Ns)
+	}
+	if verifySigs(t, "glue", m.Extra, s.Keys) != 0 {
+		t.Errorf("expected unsigned glue, got %v", m.Extra)
+	}
+}
+
+func TestOnlineSignerUnsignedReferral(t *testing.T) {
+	h := HandlerFunc(func(w ResponseWriter, r *Msg) {
+		m := new(Msg)
+		m.SetReply(r)
+		m.Ns = []RR{testRR("sub.example.org. 86400 IN NS ns.sub.example.org.")}
+		m.Extra = []RR{testRR("ns.sub.example.org. 3600 IN A 192.0.2.5")}
+		w.WriteMsg(m)
+	})
+	s := &OnlineSigner{Handler: h, Zone: "example.org.", Keys: []SigningKey{newSigningKey(t, "example.org.", ZONE|SEP)}}
+	req := new(Msg)
+	req.SetQuestion("www.sub.example.org.", TypeA)
+	req.SetEdns0(4096, true)
+	w := new(recorder)
+	s.ServeDNS(w, req)
+
+	if len(w.m.Ns) != 3 || verifySigs(t, "unsigned referral", w.m.Ns, s.Keys) != 1 {
+		t.Fatalf("expected the NS and a signed NSEC, got %v", w.m.Ns)
+	}
+	if n := w.m.Ns[1].(*NSEC); n.String() != "sub.example.org.\t86400\tIN\tNSEC\t\\000.sub.example.org. NS RRSIG NSEC" {
+		t.Errorf("got NSEC %s", n)
+	}
+}
+
+func TestOnlineSignerCompact(t *testing.T) {
+	z, err := ReadZone(strings.NewReader(testZone), "example.org.", "testZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &OnlineSigner{Handler: z, Zone: "example.org.", Keys: []SigningKey{newSigningKey(t, "example.org.", ZONE|SEP)}, Compact: true}
+	for _, co := range []bool{false, true} {
+		req := new(Msg)
+		req.SetQuestion("nope.example.org.", TypeA)
+		req.SetEdns0(4096, true)
+		req.IsEdns0().SetCo(co)
+		w := new(recorder)
+		s.ServeDNS(w, req)
+
+		want := RcodeSuccess
+		if co {
+			want = RcodeNameError
+		}
+		if w.m.Rcode != want {
+			t.Errorf("co %t: got rcode %s, want %s", co, RcodeToString[w.m.Rcode], RcodeToString[want])
+		}
+		if len(w.m.Ns) != 4 || verifySigs(t, "compact", w.m.Ns, s.Keys) != 2 {
+			t.Fatalf("co %t: expected a signed NSEC and a signed SOA, got %v", co, w.m.Ns)
+		}
+		if n := w.m.Ns[1].(*NSEC); n.String() != "nope.example.org.\t300\tIN\tNSEC\t\\000.nope.example.org. RRSIG NSEC NXNAME" {
+			t.Errorf("co %t: got NSEC %s", co, n)
+		}
+	}
+}
+
+func TestOnlineSignerCache(t *testing.T) {
+	z, err := ReadZone(strings.NewReader(testZone), "example.org.", "testZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1500000000, 0)
+	s := &OnlineSigner{Handler: z, Zone: "example.org.", Keys: []SigningKey{newSigningKey(t, "example.org.", ZONE)}, Validity: time.Hour, now: func() time.Time { return now }}
+	sig := func() *RRSIG {
+		req := new(Msg)
+		req.SetQuestion("web.example.org.", TypeA)
+		req.SetEdns0(4096, true)
+		w := new(recorder)
+		s.ServeDNS(w, req)
+		return w.m.Answer[1].(*RRSIG)
+	}
+
+	first := sig()
+	if first.Expiration != uint32(now.Add(time.Hour).Unix()) {
+		t.Errorf("got expiration %d", first.Expiration)
+	}
+	now = now.Add(29 * time.Minute)
+	if second := sig(); second.Signature != first.Signature {
+		t.Error("expected the cached signature")
+	}
+	now = now.Add(2 * time.Minute)
+	if third := sig(); third.Signature == first.Signature || third.Expiration != uint32(now.Add(time.Hour).Unix()) {
+		t.Error("expected a new signature after half of the validity")
+	}
+}
+
+func TestPrevName(t *testing.T) {
+	for _, name := range []string{"b.example.org.", "B.example.org.", "\\[.example.org.", "\\000.example.org.", "a\\000.example.org.", "*.example.org."} {
+		prev := prevName(name)
+		if canonicalNameCompare(prev, name) >= 0 {
+			t.Errorf("%s: got %s, which does not sort before it", name, prev)
+		}
+		if !IsSubDomain("example.org.", prev) {
+			t.Errorf("%s: got %s, which is not in the zone", name, prev)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package dns
+
+// Key rollovers with the timing of RFC 7583: pre-publication for zone signing
+// keys, section 3.2.1, and double-DS for key signing keys, section 3.3.2.
+
+import (
+	"sync"
+	"time"
+)
+
+// KeyState is the state of a key in a rollover, RFC 7583 section 3.1.
+type KeyState int
+
+// The states of a key.
+const (
+	KeyGenerated KeyState = iota // the key exists, for a KSK its DS may be submitted to the parent
+	KeyPublished                 // the key is in the DNSKEY RRset, not yet in all caches
+	KeyReady                     // the key is in all caches, and can be used
+	KeyActive                    // the key signs
+	KeyRetired                   // the key no longer signs, its signatures or DS may still be cached
+	KeyRemoved                   // the key is gone
+)
+
+var keyStateString = map[KeyState]string{
+	KeyGenerated: "Generated",
+	KeyPublished: "Published",
+	KeyReady:     "Ready",
+	KeyActive:    "Active",
+	KeyRetired:   "Retired",
+	KeyRemoved:   "Removed",
+}
+
+func (s KeyState) String() string { return keyStateString[s] }
+
+// RolloverTiming holds the delays and TTLs of RFC 7583 section 3 that
+// determine when a key can safely move to its next state.
+type RolloverTiming struct {
+	Propagation       time.Duration // DprpC, until a change of the zone is on all its servers
+	ParentPropagation time.Duration // DprpP, until a change of the parent zone is on all its servers
+	Registration      time.Duration // Dreg, until the parent publishes a submitted DS
+	Sign              time.Duration // Dsgn, to sign the whole zone with a new key
+	PublishSafety     time.Duration // added to the publication interval
+	RetireSafety      time.Duration // added to the retire interval
+	DNSKEYTTL         time.Duration // TTLkey, of the DNSKEY RRset
+	MaxTTL            time.Duration // TTLsig, the largest TTL of the signed RRsets
+	DSTTL             time.Duration // TTLds, of the DS RRset in the parent
+}
+
+// ManagedKey is a key of a KeyManager with the times of its transitions.
+// Retired and Removed are zero while they are not scheduled.
+type ManagedKey struct {
+	Key                         SigningKey
+	Generated, Published, Ready time.Time
+	Active, Retired, Removed    time.Time
+}
+
+// KSK returns true if k is a key signing key, see SigningKey.
+func (k *ManagedKey) KSK() bool { return k.Key.DNSKEY.Flags&SEP == SEP }
+
+// State returns the state of k at time t.
+func (k *ManagedKey) State(t time.Time) KeyState {
+	switch {
+	case !k.Removed.IsZero() && !t.Before(k.Removed):
+		return KeyRemoved
+	case !k.Retired.IsZero() && !t.Before(k.Retired):
+		return KeyRetired
+	case !t.Before(k.Active):
+		return KeyActive
+	case !t.Before(k.Ready):
+		return KeyReady
+	case !t.Before(k.Published):
+		return KeyPublished
+	}
+	return KeyGenerated
+}
+
+// KeyManager schedules key rollovers. Zone signing keys are rolled with
+// pre-publication: the new key is published, used once it is in all caches,
+// and the old key is removed once its signatures have expired from the
+// caches. Key signing keys are rolled with a double DS: the DS of the new key
+// is submitted to the parent, the keys are swapped in the DNSKEY RRset once
+// the new DS is in all caches, and the old DS is withdrawn once the new
+// DNSKEY RRset is.
+//
+// The manager does not sign, it reports which keys sign and which records
+// to publish at any time, see Signing and Records.
+type KeyManager struct {
+	Timing     RolloverTiming
+	DigestType uint8            // of the CDS records, SHA256 if zero
+	Now        func() time.Time // the clock, time.Now if nil
+
+	m    sync.Mutex
+	keys []*ManagedKey
+}
+
+func (m *KeyManager) now() time.Time {
+	if m.Now != nil {
+		return m.Now()
+	}
+	return time.Now()
+}
+
+// Keys returns the keys of the manager.
+func (m *KeyManager) Keys() []*ManagedKey {
+	m.m.Lock()
+	defer m.m.Unlock()
+	return append([]*ManagedKey(nil), m.keys...)
+}
+
+// Add adds k as an active key, already published and, for a KSK, with its
+// DS in the parent.
+func (m *KeyManager) Add(k SigningKey) *ManagedKey {
+	m.m.Lock()
+	defer m.m.Unlock()
+	now := m.now()
+	mk := &ManagedKey{Key: k, Generated: now, Published: now, Ready: now, Active: now}
+	m.keys = append(m.keys, mk)
+	return mk
+}
+
+// RollZSK starts a pre-publication rollover from the active zone signing
+// key to next, RFC 7583 section 3.2.1. The new key is published now and
+// becomes active after Ipub = DprpC + TTLkey. The old key is retired then, and
+// removed after Iret = Dsgn + DprpC + TTLsig.
+func (m *KeyManager) RollZSK(next SigningKey) (*ManagedKey, error) {
+	if next.DNSKEY.Flags&SEP == SEP {
+		return nil, &Error{err: "not a zone signing key"}
+	}
+	m.m.Lock()
+	defer m.m.Unlock()
+	now := m.now()
+	old, err := m.rolling(false, now)
+	if err != nil {
+		return nil, err
+	}
+	t := m.Timing
+	ready := now.Add(t.Propagation + t.DNSKEYTTL + t.PublishSafety)
+	mk := &ManagedKey{Key: next, Generated: now, Published: now, Ready: ready, Active: ready}
+	old.Retired = ready
+	old.Removed = ready.Add(t.Sign + t.Propagation + t.MaxTTL + t.RetireSafety)
+	m.keys = append(m.keys, mk)
+	return mk, nil
+}
+
+// RollKSK starts a double-DS rollover from the active key signing key to
+// next, RFC 7583 section 3.3.2. The CDS and CDNSKEY of the new key are
+// published now. After IpubP = Dreg + DprpP + TTLds its DS is in all caches
+// and the keys are swapped in the DNSKEY RRset: the new key is published and
+// active, the old key retired. Its DS is withdrawn after IretC = DprpC +
+// TTLkey, when it is removed.
+func (m *KeyManager) RollKSK(next SigningKey) (*ManagedKey, error) {
+	if next.DNSKEY.Flags&SEP == 0 {
+		return nil, &Error{err: "not a key signing key"}
+	}
+	m.m.Lock()
+	defer m.m.Unlock()
+	now := m.now()
+	old, err := m.rolling(true, now)
+	if err != nil {
+		return nil, err
+	}
+	t := m.Timing
+	swap := now.Add(t.Registration + t.ParentPropagation + t.DSTTL + t.PublishSafety)
+	mk := &ManagedKey{Key: next, Generated: now, Published: swap, Ready: swap, Active: swap}
+	old.Retired = swap
+	old.Removed = swap.Add(t.Propagation + t.DNSKEYTTL + t.RetireSafety)
+	m.keys = append(m.keys, mk)
+	return mk, nil
+}
+
+// rolling returns the active key of the kind to roll, unless a rollover of
+// that kind is in progress. m.m must be held.
+func (m *KeyManager) rolling(ksk bool, now time.Time) (*ManagedKey, error) {
+	var old *ManagedKey
+	for _, k := range m.keys {
+		if k.KSK() != ksk {
+			continue
+		}
+		switch k.State(now) {
+		case KeyGenerated, KeyPublished, KeyReady:
+			return nil, &Error{err: "rollover in progress"}
+		case KeyActive:
+			if !k.Retired.IsZero() {
+				return nil, &Error{err: "rollover in progress"}
+			}
+			old = k
+		}
+	}
+	if old == nil {
+		return nil, &Error{err: "no active key to roll"}
+	}
+	return old, nil
+}
+
+// Signing returns the keys that sign at time t: the active zone signing and
+// key signing keys, for use with Zone.Sign or OnlineSigner.
+func (m *KeyManager) Signing(t time.Time) []SigningKey {
+	m.m.Lock()
+	defer m.m.Unlock()
+	var keys []SigningKey
+	for _, k := range m.keys {
+		if k.State(t) == KeyActive {
+			keys = append(keys, k.Key)
+		}
+	}
+	return keys
+}
+
+// Records returns the DNSKEY, CDS and CDNSKEY records to publish at time t.
+// The DNSKEY RRset has the published zone signing keys until they are
+// removed, so their signatures still validate, and the published key
+// signing keys until they are retired. The CDS and CDNSKEY records, RFC 7344,
+// list the key signing keys whose DS the parent should have, from when they
+// are generated until they are removed.
+func (m *KeyManager) Records(t time.Time) []RR {
+	m.m.Lock()
+	defer m.m.Unlock()
+	digest := m.DigestType
+	if digest == 0 {
+		digest = SHA256
+	}
+	var dnskey, cds, cdnskey []RR
+	for _, k := range m.keys {
+		s := k.State(t)
+		if s == KeyRemoved {
+			continue
+		}
+		if s != KeyGenerated && (!k.KSK() || s != KeyRetired) {
+			dnskey = append(dnskey, k.Key.DNSKEY)
+		}
+		if k.KSK() {
+			if ds := k.Key.DNSKEY.ToDS(digest); ds != nil {
+				cds = append(cds, ds.ToCDS())
+			}
+			cdnskey = append(cdnskey, k.Key.DNSKEY.ToCDNSKEY())
+		}
+	}
+	return append(append(dnskey, cds...), cdnskey...)
+}
+
+// Next returns the time of the first transition of a key after t, or the
+// zero time if none is scheduled.
+func (m *KeyManager) Next(t time.Time) time.Time {
+	m.m.Lock()
+	defer m.m.Unlock()
+	var next time.Time
+	for _, k := range m.keys {
+		for _, u := range []time.Time{k.Published, k.Ready, k.Active, k.Retired, k.Removed} {
+			if u.After(t) && (next.IsZero() || u.Before(next)) {
+				next = u
+			}
+		}
+	}
+	return next
+}
+
+// Purge forgets the keys that are removed at time t.
+func (m *KeyManager) Purge(t time.Time) {
+	m.m.Lock()
+	defer m.m.Unlock()
+	keys := m.keys[:0]
+	for _, k := range m.keys {
+		if k.State(t) != KeyRemoved {
+			keys = append(keys, k)
+		}
+	}
+	m.keys = keys
+}
@@ -0,0 +1,127 @@
+package dns
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKeyManager(t *testing.T) {
+	t0 := time.Unix(1500000000, 0)
+	now := t0
+	m := &KeyManager{
+		Timing: RolloverTiming{
+			Propagation:       time.Hour,
+			ParentPropagation: time.Hour,
+			Registration:      24 * time.Hour,
+			Sign:              2 * time.Hour,
+			DNSKEYTTL:         time.Hour,
+			MaxTTL:            24 * time.Hour,
+			DSTTL:             24 * time.Hour,
+		},
+		Now: func() time.Time { return now },
+	}
+	ksk := newSigningKey(t, "example.org.", ZONE|SEP)
+	zsk := newSigningKey(t, "example.org.", ZONE)
+	m.Add(ksk)
+	m.Add(zsk)
+
+	// check checks the records published and the keys signing at time at.
+	check := func(step string, at time.Time, dnskeys, cds []SigningKey, signing ...SigningKey) {
+		var gotKeys, gotCDS, gotCDNSKEY []string
+		for _, rr := range m.Records(at) {
+			switch rr := rr.(type) {
+			case *DNSKEY:
+				gotKeys = append(gotKeys, rr.PublicKey)
+			case *CDS:
+				gotCDS = append(gotCDS, rr.Digest)
+			case *CDNSKEY:
+				gotCDNSKEY = append(gotCDNSKEY, rr.PublicKey)
+			}
+		}
+		var wantKeys, wantCDS, wantCDNSKEY []string
+		for _, k := range dnskeys {
+			wantKeys = append(wantKeys, k.DNSKEY.PublicKey)
+		}
+		for _, k := range cds {
+			wantCDS = append(wantCDS, k.DNSKEY.ToDS(SHA256).Digest)
+			wantCDNSKEY = append(wantCDNSKEY, k.DNSKEY.PublicKey)
+		}
+		var gotSigning, wantSigning []string
+		for _, k := range m.Signing(at) {
+			gotSigning = append(gotSigning, k.DNSKEY.PublicKey)
+		}
+		for _, k := range signing {
+			wantSigning = append(wantSigning, k.DNSKEY.PublicKey)
+		}
+		for _, c := range []struct {
+			what      string
+			got, want []string
+		}{{"DNSKEY", gotKeys, wantKeys}, {"CDS", gotCDS, wantCDS}, {"CDNSKEY", gotCDNSKEY, wantCDNSKEY}, {"signing", gotSigning, wantSigning}} {
+			if !equalStrings(c.got, c.want) {
+				t.Errorf("%s: got %d %s keys, want %d", step, len(c.got), c.what, len(c.want))
+			}
+		}
+	}
+	check("start", now, []SigningKey{ksk, zsk}, []SigningKey{ksk}, ksk, zsk)
+
+	// A ZSK rollover: Ipub is 2h, Iret 27h.
+	zsk2 := newSigningKey(t, "example.org.", ZONE)
+	mk, err := m.RollZSK(zsk2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.RollZSK(newSigningKey(t, "example.org.", ZONE)); err == nil {
+		t.Errorf("expected an error for a second ZSK rollover")
+	}
+	if _, err := m.RollZSK(newSigningKey(t, "example.org.", ZONE|SEP)); err == nil {
+		t.Errorf("expected an error for a ZSK rollover to a KSK")
+	}
+	check("zsk published", now, []SigningKey{ksk, zsk, zsk2}, []SigningKey{ksk}, ksk, zsk)
+	if s := mk.State(now.Add(time.Hour)); s != KeyPublished {
+		t.Errorf("got new ZSK in state %s, want %s", s, KeyPublished)
+	}
+	check("zsk active", t0.Add(2*time.Hour), []SigningKey{ksk, zsk, zsk2}, []SigningKey{ksk}, ksk, zsk2)
+	if next := m.Next(t0.Add(2 * time.Hour)); !next.Equal(t0.Add(29 * time.Hour)) {
+		t.Errorf("got next transition at %s, want %s", next, t0.Add(29*time.Hour))
+	}
+	check("zsk removed", t0.Add(29*time.Hour), []SigningKey{ksk, zsk2}, []SigningKey{ksk}, ksk, zsk2)
+
+	// A KSK rollover: IpubP is 49h, IretC 2h.
+	now = t0.Add(30 * time.Hour)
+	m.Purge(now)
+	if len(m.Keys()) != 2 {
+		t.Errorf("got %d keys after purging, want 2", len(m.Keys()))
+	}
+	ksk2 := newSigningKey(t, "example.org.", ZONE|SEP)
+	if _, err := m.RollKSK(ksk2); err != nil {
+		t.Fatal(err)
+	}
+	check("ds submitted", now, []SigningKey{ksk, zsk2}, []SigningKey{ksk, ksk2}, ksk, zsk2)
+	swap := now.Add(49 * time.Hour)
+	check("before swap", swap.Add(-time.Second), []SigningKey{ksk, zsk2}, []SigningKey{ksk, ksk2}, ksk, zsk2)
+	check("swapped", swap, []SigningKey{zsk2, ksk2}, []SigningKey{ksk, ksk2}, zsk2, ksk2)
+	check("ds withdrawn", swap.Add(2*time.Hour), []SigningKey{zsk2, ksk2}, []SigningKey{ksk2}, zsk2, ksk2)
+	if next := m.Next(swap.Add(2 * time.Hour)); !next.IsZero() {
+		t.Errorf("got next transition at %s, want none", next)
+	}
+}
+
+// equalStrings returns true if a and b hold the same strings, in any order.
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	count := make(map[string]int)
+	for _, s := range a {
+		count[s]++
+	}
+	for _, s := range b {
+		count[s]--
+	}
+	for _, n := range count {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -1,6 +1,7 @@
 package dns
 
 import (
+	"bytes"
 	"crypto"
 	"crypto/ecdsa"
 	"crypto/rsa"
@@ -839,3 +840,26 @@ func TestInvalidRRSet(t *testing.T) {
 		t.Fatal("Verification did not return ErrRRset with inconsistent records")
 	}
 }
+
+func TestCanonicalWireRdataCase(t *testing.T) {
+	for _, tc := range []struct {
+		in, name string
+	}{
+		{"Example. IN MX 10 MX.Example.", "\x02mx\x07example\x00"},
+		{"Example. IN IPSECKEY 10 3 2 GW.Example. AQID", "\x02GW\x07Example\x00"},
+		{"Example. IN AMTRELAY 10 0 3 Relay.Example.", "\x05Relay\x07Example\x00"},
+		{"Example. IN DSYNC CDS 1 53 EP.Example.", "\x02EP\x07Example\x00"},
+		{"Example. IN SVCB 1 SVC.Example.", "\x03SVC\x07Example\x00"},
+	} {
+		wire, err := canonicalWire(testRR(tc.in).copy())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(wire, []byte("\x07example\x00")) {
+			t.Errorf("%s: owner name not lower cased", tc.in)
+		}
+		if !bytes.Contains(wire, []byte(tc.name)) {
+			t.Errorf("%s: got canonical wire %q, want rdata name %q", tc.in, wire, tc.name)
+		}
+	}
+}
@@ -0,0 +1,568 @@
+package dns
+
+// Validation of responses along the chain of trust from a trust anchor, RFC
+// 4035 section 5, with the negative trust anchors of RFC 7646.
+
+import (
+	"strings"
+	"time"
+)
+
+// SecurityStatus is the outcome of validating a response, see RFC 4033
+// section 5.
+type SecurityStatus int
+
+// The security status of a response.
+const (
+	Indeterminate SecurityStatus = iota // there is no trust anchor for the data
+	Secure                              // the data is signed along an unbroken chain of trust
+	Insecure                            // the data is in or below a zone that is provably unsigned
+	Bogus                               // the data should be signed, but the signatures do not validate
+)
+
+var securityStatusString = map[SecurityStatus]string{
+	Indeterminate: "Indeterminate",
+	Secure:        "Secure",
+	Insecure:      "Insecure",
+	Bogus:         "Bogus",
+}
+
+func (s SecurityStatus) String() string { return securityStatusString[s] }
+
+// worse returns true if s is a worse outcome than t.
+func (s SecurityStatus) worse(t SecurityStatus) bool {
+	rank := [...]int{Secure: 0, Insecure: 1, Indeterminate: 2, Bogus: 3}
+	return rank[s] > rank[t]
+}
+
+// A KeyFetcher fetches the DNSKEY and DS records, with their signatures, of
+// the zones in the chain of trust.
+type KeyFetcher interface {
+	// Fetch returns the response to the query for name and qtype, which is
+	// TypeDNSKEY or TypeDS. For DS queries with a negative answer, the
+	// NSEC or NSEC3 records proving it must be in the response.
+	Fetch(name string, qtype uint16) (*Msg, error)
+}
+
+// The KeyFetcherFunc type is an adapter to allow the use of ordinary
+// functions as key fetchers.
+type KeyFetcherFunc func(name string, qtype uint16) (*Msg, error)
+
+// Fetch calls f(name, qtype).
+func (f KeyFetcherFunc) Fetch(name string, qtype uint16) (*Msg, error) {
+	return f(name, qtype)
+}
+
+// ValidationError describes why a response is not secure.
+type ValidationError struct {
+	Name string // the owner name of the RRset, or the zone, where validation stopped
+	Type uint16 // the type of that RRset
+	Err  error  // the reason, such as ErrSigExpired or ErrInsecure
+}
+
+func (e *ValidationError) Error() string {
+	return "dns: " + e.Name + " " + Type(e.Type).String() + ": " + strings.TrimPrefix(e.Err.Error(), "dns: ")
+}
+
+// Validator validates responses along the chain of trust from its trust
+// anchors, fetching the DNSKEY and DS records of the zones in between.
+type Validator struct {
+	Anchors         []RR     // the trust anchors, DS or DNSKEY records
+	NegativeAnchors []string // responses for names at or below these are not validated, see RFC 7646
+	MaxIterations   uint16   // denials with NSEC3 records with more iterations are insecure, NSEC3MaxIterations if zero
+	Fetcher         KeyFetcher
+}
+
+// Validate validates the RRsets in the answer and authority sections of m at
+// time t. If t is the zero time, the current time is used. The NS RRsets of
+// delegations, which are not signed, and the additional section are not
+// validated.
+//
+// For answers synthesized from a wildcard and for negative answers from a
+// secure zone, the proof of non-existence in the authority section is
+// verified, see Denial.
+//
+// It returns the status of the least secure RRset or proof. If that is not Secure, the
+// error is a *ValidationError with the reason.
+func (v *Validator) Validate(m *Msg, t time.Time) (SecurityStatus, error) {
+	if t.IsZero() {
+		t = time.Now()
+	}
+	c := &validation{v: v, t: t, zones: make(map[string]*zoneTrust)}
+
+	status, err := Secure, error(nil)
+	check := func(s SecurityStatus, e error) {
+		if s.worse(status) {
+			status, err = s, e
+		}
+	}
+	if len(m.Question) > 0 {
+		if name := m.Question[0].Name; v.negative(name) {
+			return Insecure, &ValidationError{Name: name, Type: m.Question[0].Qtype, Err: ErrNegAnchor}
+		}
+	}
+	for _, s := range sectionRRsets(m.Answer) {
+		if s.rrset[0].Header().Rrtype == TypeCNAME && len(s.sigs) == 0 && synthesized(s.rrset[0].(*CNAME), m.Answer) {
+			continue
+		}
+		st, e := c.rrset(s.rrset, s.sigs)
+		check(st, e)
+		if st == Secure {
+			check(c.wildcard(m, s))
+		}
+	}
+	for _, s := range sectionRRsets(m.Ns) {
+		if s.rrset[0].Header().Rrtype == TypeNS && len(s.sigs) == 0 {
+			continue
+		}
+		check(c.rrset(s.rrset, s.sigs))
+	}
+	if status == Secure && len(m.Question) > 0 {
+		check(c.proof(m))
+	}
+	return status, err
+}
+
+// wildcard verifies the proof for the RRset s if it is synthesized from a
+// wildcard.
+func (c *validation) wildcard(m *Msg, s *signedRRset) (SecurityStatus, error) {
+	h := s.rrset[0].Header()
+	labels := CountLabel(h.Name)
+	for _, sig := range s.sigs {
+		if int(sig.Labels) < labels {
+			labels = int(sig.Labels)
+		}
+	}
+	if labels == CountLabel(h.Name) {
+		return Secure, nil
+	}
+	d, err := c.denial(m)
+	if err != nil {
+		return Bogus, err
+	}
+	status, err := d.Wildcard(h.Name, uint8(labels))
+	if err != nil {
+		err = &ValidationError{Name: h.Name, Type: h.Rrtype, Err: err}
+	}
+	return status, err
+}
+
+// proof verifies the proof of a name error or NODATA response, for the
+// name at the end of the CNAME chain in the answer section. If that name is
+// not in a secure zone the response is as secure as the zone. For a referral
+// without DS records it verifies that the delegation is unsigned.
+func (c *validation) proof(m *Msg) (SecurityStatus, error) {
+	q := m.Question[0]
+	name := q.Name
+	answered := false
+	for i := 0; i <= len(m.Answer); i++ {
+		next := ""
+		for _, rr := range m.Answer {
+			h := rr.Header()
+			if !equal(h.Name, name) {
+				continue
+			}
+			if h.Rrtype == q.Qtype || q.Qtype == TypeANY {
+				answered = true
+			}
+			if cname, ok := rr.(*CNAME); ok && q.Qtype != TypeCNAME {
+				next = cname.Target
+			}
+		}
+		if next == "" {
+			break
+		}
+		name = next
+	}
+
+	var soa, ns, ds bool
+	cut := ""
+	for _, rr := range m.Ns {
+		switch rr.Header().Rrtype {
+		case TypeSOA:
+			soa = true
+		case TypeNS:
+			ns, cut = true, rr.Header().Name
+		case TypeDS:
+			ds = true
+		}
+	}
+
+	zone := name
+	var verify func(*Denial) (SecurityStatus, error)
+	switch {
+	case m.Rcode == RcodeNameError:
+		verify = func(d *Denial) (SecurityStatus, error) { return d.NameError(name) }
+	case m.Rcode != RcodeSuccess || answered:
+		return Secure, nil
+	case ns && !soa:
+		if ds {
+			return Secure, nil
+		}
+		// A referral to an unsigned zone, the proof comes from the parent.
+		off, end := NextLabel(cut, 0)
+		if end {
+			return Secure, nil
+		}
+		name, zone = cut, cut[off:]
+		verify = func(d *Denial) (SecurityStatus, error) {
+			status, err := d.NoDS(cut)
+			if status == Secure {
+				// Not a delegation, so not a referral.
+				return Bogus, ErrDenial
+			}
+			return status, err
+		}
+		q.Qtype = TypeDS
+	case !soa && !equal(name, q.Name):
+		// The CNAME chain is not followed to its end, that is not a
+		// negative answer for the name it ends at.
+		return Secure, nil
+	default:
+		verify = func(d *Denial) (SecurityStatus, error) { return d.NoData(name, q.Qtype) }
+	}
+
+	if zt := c.trust(zone); zt.status != Secure {
+		return zt.status, zt.err
+	}
+	d, err := c.denial(m)
+	if err != nil {
+		return Bogus, err
+	}
+	status, err := verify(d)
+	if err != nil {
+		err = &ValidationError{Name: name, Type: q.Qtype, Err: err}
+	}
+	return status, err
+}
+
+// negative returns true if name is at or below a negative trust anchor.
+func (v *Validator) negative(name string) bool {
+	for _, n := range v.NegativeAnchors {
+		if IsSubDomain(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// signedRRset is an RRset with its signatures.
+type signedRRset struct {
+	rrset []RR
+	sigs  []*RRSIG
+}
+
+// sectionRRsets returns the RRsets in section, with the RRSIGs covering them.
+func sectionRRsets(section []RR) []*signedRRset {
+	type key struct {
+		name  string
+		rtype uint16
+		class uint16
+	}
+	var sets []*signedRRset
+	index := make(map[key]*signedRRset)
+	get := func(k key) *signedRRset {
+		s, ok := index[k]
+		if !ok {
+			s = new(signedRRset)
+			index[k] = s
+			sets = append(sets, s)
+		}
+		return s
+	}
+	for _, rr := range section {
+		h := rr.Header()
+		switch h.Rrtype {
+		case TypeOPT, TypeTSIG, TypeSIG:
+		case TypeRRSIG:
+			sig := rr.(*RRSIG)
+			s := get(key{strings.ToLower(h.Name), sig.TypeCovered, h.Class})
+			s.sigs = append(s.sigs, sig)
+		default:
+			s := get(key{strings.ToLower(h.Name), h.Rrtype, h.Class})
+			s.rrset = append(s.rrset, rr)
+		}
+	}
+	// Drop signatures without an RRset.
+	j := 0
+	for _, s := range sets {
+		if len(s.rrset) > 0 {
+			sets[j] = s
+			j++
+		}
+	}
+	return sets[:j]
+}
+
+// synthesized returns true if c is the CNAME synthesized from a DNAME in
+// section, RFC 6672 section 5.3.1.
+func synthesized(c *CNAME, section []RR) bool {
+	for _, rr := range section {
+		d, ok := rr.(*DNAME)
+		if !ok || !IsSubDomain(d.Hdr.Name, c.Hdr.Name) || equal(d.Hdr.Name, c.Hdr.Name) {
+			continue
+		}
+		prefix := c.Hdr.Name[:len(c.Hdr.Name)-len(d.Hdr.Name)]
+		if strings.EqualFold(c.Target, prefix+d.Target) || d.Target == "." && strings.EqualFold(c.Target, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// validation holds the state of validating a single response.
+type validation struct {
+	v     *Validator
+	t     time.Time
+	zones map[string]*zoneTrust // by lower cased name
+}
+
+// zoneTrust is the result of following the chain of trust to a name: the
+// zone the name is in and its validated keys.
+type zoneTrust struct {
+	zone   string
+	keys   []*DNSKEY
+	status SecurityStatus
+	err    error
+}
+
+// rrset validates rrset with its signatures.
+func (c *validation) rrset(rrset []RR, sigs []*RRSIG) (SecurityStatus, error) {
+	h := rrset[0].Header()
+	name := strings.ToLower(h.Name)
+	if c.v.negative(name) {
+		return Insecure, &ValidationError{Name: h.Name, Type: h.Rrtype, Err: ErrNegAnchor}
+	}
+	if len(sigs) == 0 {
+		// Unsigned data is fine in an unsigned zone.
+		zt := c.trust(name)
+		if zt.status != Secure {
+			return zt.status, zt.err
+		}
+		return Bogus, &ValidationError{Name: h.Name, Type: h.Rrtype, Err: ErrNoSig}
+	}
+
+	var err error = ErrSig
+	for _, sig := range sigs {
+		signer := strings.ToLower(sig.SignerName)
+		if !IsSubDomain(signer, name) || int(sig.Labels) > CountLabel(name) {
+			continue
+		}
+		zt := c.trust(signer)
+		if zt.status != Secure {
+			return zt.status, zt.err
+		}
+		if !equal(zt.zone, signer) {
+			// The signer is not the apex of a zone.
+			err = ErrNoDNSKEY
+			continue
+		}
+		if e := c.verify(sig, rrset, zt.keys); e != nil {
+			err = e
+			continue
+		}
+		return Secure, nil
+	}
+	return Bogus, &ValidationError{Name: h.Name, Type: h.Rrtype, Err: err}
+}
+
+// verify verifies rrset with sig, made by one of keys.
+func (c *validation) verify(sig *RRSIG, rrset []RR, keys []*DNSKEY) error {
+	if !sig.ValidityPeriod(c.t) {
+		if int32(sig.Inception-uint32(c.t.Unix())) > 0 {
+			return ErrSigNotYet
+		}
+		return ErrSigExpired
+	}
+	err := ErrNoDNSKEY
+	for _, k := range keys {
+		if k.KeyTag() != sig.KeyTag || k.Algorithm != sig.Algorithm {
+			continue
+		}
+		if err = sig.Verify(k, rrset); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// trust follows the chain of trust from the closest trust anchor down to
+// name, and returns the zone name is in with its keys.
+func (c *validation) trust(name string) *zoneTrust {
+	name = strings.ToLower(name)
+	if zt, ok := c.zones[name]; ok {
+		return zt
+	}
+	// Guard against a chain that loops, such as a DS signed by its own zone.
+	c.zones[name] = &zoneTrust{status: Bogus, err: &ValidationError{Name: name, Type: TypeDNSKEY, Err: ErrNoDNSKEY}}
+	zt := c.follow(name)
+	c.zones[name] = zt
+	return zt
+}
+
+func (c *validation) follow(name string) *zoneTrust {
+	fail := func(status SecurityStatus, zone string, t uint16, err error) *zoneTrust {
+		if _, ok := err.(*ValidationError); !ok {
+			err = &ValidationError{Name: zone, Type: t, Err: err}
+		}
+		return &zoneTrust{status: status, err: err}
+	}
+
+	var anchors []RR
+	for _, a := range c.v.Anchors {
+		if equal(a.Header().Name, name) {
+			anchors = append(anchors, a)
+		}
+	}
+	if len(anchors) > 0 {
+		keys, err := c.dnskeys(name, func(k *DNSKEY) bool {
+			for _, a := range anchors {
+				if matchAnchor(a, k) {
+					return true
+				}
+			}
+			return false
+		})
+		if err != nil {
+			return fail(Bogus, name, TypeDNSKEY, err)
+		}
+		return &zoneTrust{zone: name, keys: keys, status: Secure}
+	}
+
+	off, end := NextLabel(name, 0)
+	if end {
+		return fail(Indeterminate, name, TypeDNSKEY, ErrNoTrustAnchor)
+	}
+	parent := c.trust(name[off:])
+	if parent.status != Secure {
+		return parent
+	}
+
+	// Is name a secure delegation from the parent zone?
+	m, err := c.v.Fetcher.Fetch(name, TypeDS)
+	if err != nil {
+		return fail(Bogus, name, TypeDS, err)
+	}
+	var ds *signedRRset
+	for _, s := range sectionRRsets(m.Answer) {
+		if s.rrset[0].Header().Rrtype == TypeDS && equal(s.rrset[0].Header().Name, name) {
+			ds = s
+		}
+	}
+	if ds == nil {
+		if status, err := c.noDS(m, name); status != Secure {
+			return fail(status, name, TypeDS, err)
+		}
+		// Not a zone cut, name is in the parent zone.
+		return parent
+	}
+	if status, err := c.rrset(ds.rrset, ds.sigs); status != Secure {
+		return fail(status, name, TypeDS, err)
+	}
+
+	// Follow the DS records with a supported algorithm and digest, if there
+	// are none the zone is treated as unsigned, RFC 4035 section 5.2.
+	var supported []*DS
+	for _, rr := range ds.rrset {
+		d := rr.(*DS)
+		if _, ok := AlgorithmToHash[d.Algorithm]; ok && d.Algorithm != RSAMD5 && (&DNSKEY{}).ToDS(d.DigestType) != nil {
+			supported = append(supported, d)
+		}
+	}
+	if len(supported) == 0 {
+		return fail(Insecure, name, TypeDS, ErrDigest)
+	}
+	keys, err := c.dnskeys(name, func(k *DNSKEY) bool {
+		for _, d := range supported {
+			if kd := k.ToDS(d.DigestType); kd != nil && kd.KeyTag == d.KeyTag && kd.Algorithm == d.Algorithm && strings.EqualFold(kd.Digest, d.Digest) {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		return fail(Bogus, name, TypeDNSKEY, err)
+	}
+	return &zoneTrust{zone: name, keys: keys, status: Secure}
+}
+
+// noDS checks the proof in the negative answer m that there are no DS
+// records for name, see Denial.NoDS.
+func (c *validation) noDS(m *Msg, name string) (SecurityStatus, error) {
+	nsec, err := c.denial(m)
+	if err != nil {
+		return Bogus, err
+	}
+	return nsec.NoDS(name)
+}
+
+// denial validates the NSEC and NSEC3 records in the authority section of m
+// and returns them for checking the proof.
+func (c *validation) denial(m *Msg) (*Denial, error) {
+	d := &Denial{MaxIterations: c.v.MaxIterations}
+	for _, s := range sectionRRsets(m.Ns) {
+		h := s.rrset[0].Header()
+		if h.Rrtype != TypeNSEC && h.Rrtype != TypeNSEC3 {
+			continue
+		}
+		if status, err := c.rrset(s.rrset, s.sigs); status != Secure {
+			return nil, err
+		}
+		d.Records = append(d.Records, s.rrset...)
+	}
+	return d, nil
+}
+
+// dnskeys fetches the DNSKEY RRset of zone and validates it with the keys
+// for which trusted returns true. It returns the zone keys of the RRset,
+// except the revoked ones.
+func (c *validation) dnskeys(zone string, trusted func(*DNSKEY) bool) ([]*DNSKEY, error) {
+	m, err := c.v.Fetcher.Fetch(zone, TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	var set *signedRRset
+	for _, s := range sectionRRsets(m.Answer) {
+		if s.rrset[0].Header().Rrtype == TypeDNSKEY && equal(s.rrset[0].Header().Name, zone) {
+			set = s
+		}
+	}
+	if set == nil {
+		return nil, ErrNoDNSKEY
+	}
+
+	var keys, anchors []*DNSKEY
+	for _, rr := range set.rrset {
+		k := rr.(*DNSKEY)
+		if k.Flags&ZONE == 0 || k.Flags&REVOKE != 0 || k.Protocol != 3 {
+			continue
+		}
+		keys = append(keys, k)
+		if trusted(k) {
+			anchors = append(anchors, k)
+		}
+	}
+	if len(anchors) == 0 {
+		return nil, ErrNoDNSKEY
+	}
+	err = ErrNoSig
+	for _, sig := range set.sigs {
+		if err = c.verify(sig, set.rrset, anchors); err == nil {
+			return keys, nil
+		}
+	}
+	return nil, err
+}
+
+// matchAnchor returns true if k is the key of the trust anchor a, a DS or
+// DNSKEY record.
+func matchAnchor(a RR, k *DNSKEY) bool {
+	switch a := a.(type) {
+	case *DS:
+		kd := k.ToDS(a.DigestType)
+		return kd != nil && kd.KeyTag == a.KeyTag && kd.Algorithm == a.Algorithm && strings.EqualFold(kd.Digest, a.Digest)
+	case *DNSKEY:
+		return a.Algorithm == k.Algorithm && a.Flags&^REVOKE == k.Flags&^REVOKE && a.PublicKey == k.PublicKey
+	}
+	return false
+}
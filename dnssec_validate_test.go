@@ -0,0 +1,214 @@
+package dns
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testParentZone = `$ORIGIN org.
+$TTL 3600
+@		IN	SOA	ns.example.net. hostmaster.example.net. 1 7200 3600 1209600 300
+		IN	NS	ns.example.net.
+example		IN	NS	ns1.example
+ns1.example	IN	A	192.0.2.1
+`
+
+// testChain is a signed org. zone, with example.org. as a signed child. The
+// child has an unsigned delegation to insecure.example.org.
+type testChain struct {
+	zones  []*Zone
+	anchor *DS
+	keys   map[string][]SigningKey
+}
+
+func newTestChain(t *testing.T, p *SignPolicy, now time.Time) *testChain {
+	org, err := ReadZone(strings.NewReader(testParentZone), "org.", "testParentZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	example, err := ReadZone(strings.NewReader(testZone+"insecure\tIN\tNS\tns.example.net.\n"), "example.org.", "testZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &testChain{zones: []*Zone{org, example}, keys: make(map[string][]SigningKey)}
+	for _, z := range []*Zone{example, org} {
+		keys := []SigningKey{newSigningKey(t, z.Origin, ZONE|SEP), newSigningKey(t, z.Origin, ZONE)}
+		c.keys[z.Origin] = keys
+		if z == org {
+			org.Insert(c.keys["example.org."][0].DNSKEY.ToDS(SHA256))
+			c.anchor = keys[0].DNSKEY.ToDS(SHA256)
+		}
+		if err := z.Sign(keys, p, now); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return c
+}
+
+// zone returns the zone that answers for name, for DS queries the parent.
+func (c *testChain) zone(name string, qtype uint16) *Zone {
+	var z *Zone
+	for _, zz := range c.zones {
+		if IsSubDomain(zz.Origin, name) && (qtype != TypeDS || !equal(zz.Origin, name)) && (z == nil || len(zz.Origin) > len(z.Origin)) {
+			z = zz
+		}
+	}
+	return z
+}
+
+func (c *testChain) query(name string, qtype uint16) *Msg {
+	req := new(Msg)
+	req.SetQuestion(name, qtype)
+	req.SetEdns0(4096, true)
+	return c.zone(name, qtype).Answer(req)
+}
+
+func (c *testChain) Fetch(name string, qtype uint16) (*Msg, error) {
+	if c.zone(name, qtype) == nil {
+		return nil, errors.New("no zone for " + name)
+	}
+	return c.query(name, qtype), nil
+}
+
+func TestValidate(t *testing.T) {
+	c := newTestChain(t, &SignPolicy{}, time.Time{})
+	v := &Validator{Anchors: []RR{c.anchor}, Fetcher: c}
+
+	unsigned := new(Msg)
+	unsigned.SetQuestion("host.insecure.example.org.", TypeA)
+	a, _ := NewRR("host.insecure.example.org. 3600 IN A 192.0.2.9")
+	unsigned.Answer = []RR{a}
+
+	tampered := c.query("web.example.org.", TypeA)
+	tampered.Answer[0].(*A).A[3]++
+
+	stripped := c.query("web.example.org.", TypeA)
+	stripped.Answer = stripped.Answer[:1]
+
+	// A name error without the NSEC records, and one for a name that exists.
+	noProof := c.query("nope.example.org.", TypeA)
+	noProof.Ns = noProof.Ns[:2]
+	wrongProof := c.query("nope.example.org.", TypeA)
+	wrongProof.Question[0].Name = "web.example.org."
+
+	// Negative answers without records, the zone must not be secure for
+	// them to pass.
+	empty := func(name string, qtype uint16, rcode int) *Msg {
+		m := new(Msg)
+		m.SetQuestion(name, qtype)
+		m.Rcode = rcode
+		return m
+	}
+
+	tests := []struct {
+		name   string
+		m      *Msg
+		t      time.Time
+		status SecurityStatus
+		err    error
+	}{
+		{"answer", c.query("web.example.org.", TypeA), time.Time{}, Secure, nil},
+		{"cname", c.query("www.example.org.", TypeA), time.Time{}, Secure, nil},
+		{"wildcard", c.query("x.wild.example.org.", TypeTXT), time.Time{}, Secure, nil},
+		{"dname", c.query("a.alias.example.org.", TypeA), time.Time{}, Secure, nil},
+		{"nxdomain", c.query("nope.example.org.", TypeA), time.Time{}, Secure, nil},
+		{"parent", c.query("ns1.example.org.", TypeA), time.Time{}, Secure, nil},
+		{"nodata", c.query("web.example.org.", TypeMX), time.Time{}, Secure, nil},
+		{"wildcard nodata", c.query("x.wild.example.org.", TypeAAAA), time.Time{}, Secure, nil},
+		{"referral", c.query("host.insecure.example.org.", TypeA), time.Time{}, Insecure, ErrInsecure},
+		{"no proof", noProof, time.Time{}, Bogus, ErrDenial},
+		{"wrong proof", wrongProof, time.Time{}, Bogus, ErrDenial},
+		{"tampered", tampered, time.Time{}, Bogus, ErrSig},
+		{"stripped", stripped, time.Time{}, Bogus, ErrNoSig},
+		{"expired", c.query("web.example.org.", TypeA), time.Now().Add(60 * 24 * time.Hour), Bogus, ErrSigExpired},
+		{"not yet valid", c.query("web.example.org.", TypeA), time.Now().Add(-24 * time.Hour), Bogus, ErrSigNotYet},
+		{"insecure", unsigned, time.Time{}, Insecure, ErrInsecure},
+		{"apex", c.query("example.org.", TypeSOA), time.Time{}, Secure, nil},
+		{"empty nxdomain", empty("nope.example.org.", TypeA, RcodeNameError), time.Time{}, Bogus, ErrDenial},
+		{"empty nodata", empty("web.example.org.", TypeMX, RcodeSuccess), time.Time{}, Bogus, ErrDenial},
+		{"insecure nxdomain", empty("nope.insecure.example.org.", TypeA, RcodeNameError), time.Time{}, Insecure, ErrInsecure},
+		{"insecure nodata", empty("host.insecure.example.org.", TypeMX, RcodeSuccess), time.Time{}, Insecure, ErrInsecure},
+		{"indeterminate nxdomain", empty("nope.example.net.", TypeA, RcodeNameError), time.Time{}, Indeterminate, ErrNoTrustAnchor},
+		{"indeterminate nodata", empty("www.example.net.", TypeMX, RcodeSuccess), time.Time{}, Indeterminate, ErrNoTrustAnchor},
+	}
+	for _, tc := range tests {
+		status, err := v.Validate(tc.m, tc.t)
+		if status != tc.status {
+			t.Errorf("%s: got %s, want %s: %v", tc.name, status, tc.status, err)
+		}
+		var reason error
+		if err != nil {
+			reason = err.(*ValidationError).Err
+		}
+		if reason != tc.err {
+			t.Errorf("%s: got error %v, want %v", tc.name, err, tc.err)
+		}
+	}
+
+	net := new(Msg)
+	net.SetQuestion("www.example.net.", TypeA)
+	a, _ = NewRR("www.example.net. 3600 IN A 192.0.2.10")
+	net.Answer = []RR{a}
+	if status, err := v.Validate(net, time.Time{}); status != Indeterminate || err.(*ValidationError).Err != ErrNoTrustAnchor {
+		t.Errorf("got %s, %v for a name without a trust anchor", status, err)
+	}
+
+	// Without the DNSKEYs an empty name error can not be proven.
+	failing := &Validator{Anchors: []RR{c.anchor}, Fetcher: KeyFetcherFunc(func(string, uint16) (*Msg, error) {
+		return nil, errors.New("fetch failed")
+	})}
+	if status, err := failing.Validate(empty("nope.example.org.", TypeA, RcodeNameError), time.Time{}); status == Secure {
+		t.Errorf("got %s, %v for a name error without DNSKEYs", status, err)
+	}
+
+	// With a negative trust anchor the bogus answer is insecure.
+	v.NegativeAnchors = []string{"example.org."}
+	if status, err := v.Validate(tampered, time.Time{}); status != Insecure || err.(*ValidationError).Err != ErrNegAnchor {
+		t.Errorf("got %s, %v below a negative trust anchor", status, err)
+	}
+}
+
+func TestValidateAnchors(t *testing.T) {
+	c := newTestChain(t, &SignPolicy{NSEC3: true, OptOut: true}, time.Time{})
+	m := c.query("web.example.org.", TypeA)
+
+	// A DNSKEY as trust anchor.
+	v := &Validator{Anchors: []RR{c.keys["org."][0].DNSKEY}, Fetcher: c}
+	if status, err := v.Validate(m, time.Time{}); status != Secure {
+		t.Errorf("got %s, %v with a DNSKEY trust anchor", status, err)
+	}
+	// A trust anchor directly for the zone.
+	v = &Validator{Anchors: []RR{c.keys["example.org."][0].DNSKEY.ToDS(SHA1)}, Fetcher: c}
+	if status, err := v.Validate(m, time.Time{}); status != Secure {
+		t.Errorf("got %s, %v with a trust anchor for the zone", status, err)
+	}
+	// The wrong key.
+	other := newSigningKey(t, "org.", ZONE|SEP)
+	v = &Validator{Anchors: []RR{other.DNSKEY.ToDS(SHA256)}, Fetcher: c}
+	if status, err := v.Validate(m, time.Time{}); status != Bogus || err.(*ValidationError).Err != ErrNoDNSKEY {
+		t.Errorf("got %s, %v with the wrong trust anchor", status, err)
+	}
+
+	// An unsigned delegation in an opt-out span.
+	unsigned := new(Msg)
+	unsigned.SetQuestion("host.insecure.example.org.", TypeA)
+	a, _ := NewRR("host.insecure.example.org. 3600 IN A 192.0.2.9")
+	unsigned.Answer = []RR{a}
+	v = &Validator{Anchors: []RR{c.anchor}, Fetcher: c}
+	if status, err := v.Validate(unsigned, time.Time{}); status != Insecure {
+		t.Errorf("got %s, %v for an opt-out delegation", status, err)
+	}
+
+	// A DS with an unknown digest type makes the child insecure.
+	c.zones[0].m.Lock()
+	for _, rr := range c.zones[0].nodes["example.org."].rrsets[TypeDS] {
+		rr.(*DS).DigestType = 99
+	}
+	c.zones[0].m.Unlock()
+	c.zones[0].Sign(c.keys["org."], &SignPolicy{}, time.Time{})
+	if status, err := v.Validate(m, time.Time{}); status != Insecure || err.(*ValidationError).Err != ErrDigest {
+		t.Errorf("got %s, %v for an unsupported digest", status, err)
+	}
+}
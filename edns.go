@@ -7,24 +7,33 @@ import (
 	"fmt"
 	"net"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // EDNS0 Option codes.
 const (
-	EDNS0LLQ          = 0x1     // long lived queries: http://tools.ietf.org/html/draft-sekar-dns-llq-01
-	EDNS0UL           = 0x2     // update lease draft: http://files.dns-sd.org/draft-sekar-dns-ul.txt
-	EDNS0NSID         = 0x3     // nsid (See RFC 5001)
-	EDNS0DAU          = 0x5     // DNSSEC Algorithm Understood
-	EDNS0DHU          = 0x6     // DS Hash Understood
-	EDNS0N3U          = 0x7     // NSEC3 Hash Understood
-	EDNS0SUBNET       = 0x8     // client-subnet (See RFC 7871)
-	EDNS0EXPIRE       = 0x9     // EDNS0 expire
-	EDNS0COOKIE       = 0xa     // EDNS0 Cookie
-	EDNS0TCPKEEPALIVE = 0xb     // EDNS0 tcp keep alive (See RFC 7828)
-	EDNS0PADDING      = 0xc     // EDNS0 padding (See RFC 7830)
-	EDNS0LOCALSTART   = 0xFDE9  // Beginning of range reserved for local/experimental use (See RFC 6891)
-	EDNS0LOCALEND     = 0xFFFE  // End of range reserved for local/experimental use (See RFC 6891)
-	_DO               = 1 << 15 // DNSSEC OK
+	EDNS0LLQ           = 0x1     // long lived queries: http://tools.ietf.org/html/draft-sekar-dns-llq-01
+	EDNS0UL            = 0x2     // update lease draft: http://files.dns-sd.org/draft-sekar-dns-ul.txt
+	EDNS0NSID          = 0x3     // nsid (See RFC 5001)
+	EDNS0DAU           = 0x5     // DNSSEC Algorithm Understood
+	EDNS0DHU           = 0x6     // DS Hash Understood
+	EDNS0N3U           = 0x7     // NSEC3 Hash Understood
+	EDNS0SUBNET        = 0x8     // client-subnet (See RFC 7871)
+	EDNS0EXPIRE        = 0x9     // EDNS0 expire
+	EDNS0COOKIE        = 0xa     // EDNS0 Cookie
+	EDNS0TCPKEEPALIVE  = 0xb     // EDNS0 tcp keep alive (See RFC 7828)
+	EDNS0PADDING       = 0xc     // EDNS0 padding (See RFC 7830)
+	EDNS0CHAIN         = 0xd     // EDNS0 chain query (See RFC 7901)
+	EDNS0KEYTAG        = 0xe     // EDNS0 key tag (See RFC 8145)
+	EDNS0EDE           = 0xf     // EDNS0 extended DNS errors (See RFC 8914)
+	EDNS0CLIENTTAG     = 0x10    // EDNS0 client tag (See draft-bellis-dnsop-edns-tags)
+	EDNS0REPORTCHANNEL = 0x12    // EDNS0 report channel (See RFC 9567)
+	EDNS0ZONEVERSION   = 0x13    // EDNS0 zone version (See RFC 9660)
+	EDNS0LOCALSTART    = 0xFDE9  // Beginning of range reserved for local/experimental use (See RFC 6891)
+	EDNS0LOCALEND      = 0xFFFE  // End of range reserved for local/experimental use (See RFC 6891)
+	_DO                = 1 << 15 // DNSSEC OK
+	_CO                = 1 << 14 // Compact Answers OK
 )
 
 // OPT is the EDNS0 RR appended to messages to convey extra (meta) information.
@@ -36,9 +45,14 @@ type OPT struct {
 
 func (rr *OPT) String() string {
 	s := "\n;; OPT PSEUDOSECTION:\n; EDNS: version " + strconv.Itoa(int(rr.Version())) + "; "
-	if rr.Do() {
+	switch {
+	case rr.Do() && rr.Co():
+		s += "flags: do co; "
+	case rr.Do():
 		s += "flags: do; "
-	} else {
+	case rr.Co():
+		s += "flags: co; "
+	default:
 		s += "flags: ; "
 	}
 	s += "udp: " + strconv.Itoa(int(rr.UDPSize()))
@@ -73,6 +87,24 @@ func (rr *OPT) String() string {
 			s += "\n; LOCAL OPT: " + o.String()
 		case *EDNS0_PADDING:
 			s += "\n; PADDING: " + o.String()
+		case *EDNS0_EDE:
+			s += "\n; EDE: " + o.String()
+		case *EDNS0_CHAIN:
+			s += "\n; CHAIN: " + o.String()
+		case *EDNS0_KEYTAG:
+			s += "\n; KEY TAG: " + o.String()
+		case *EDNS0_CLIENTTAG:
+			s += "\n; CLIENT TAG: " + o.String()
+		case *EDNS0_REPORTCHANNEL:
+			s += "\n; REPORT CHANNEL: " + o.String()
+		case *EDNS0_ZONEVERSION:
+			s += "\n; ZONE VERSION: " + o.String()
+		case *EDNS0_PRIVATE:
+			name := "PRIVATE OPT"
+			if p, ok := privateOptions[o.Option()]; ok {
+				name = p.name
+			}
+			s += "\n; " + name + ": " + o.String()
 		}
 	}
 	return s
@@ -140,6 +172,20 @@ func (rr *OPT) SetDo(do ...bool) {
 	}
 }
 
+// Co returns the value of the CO (Compact Answers OK) bit, see RFC 9824.
+func (rr *OPT) Co() bool {
+	return rr.Hdr.Ttl&_CO == _CO
+}
+
+// SetCo sets the CO (Compact Answers OK) bit.
+func (rr *OPT) SetCo(co bool) {
+	if co {
+		rr.Hdr.Ttl |= _CO
+	} else {
+		rr.Hdr.Ttl &^= _CO
+	}
+}
+
 // EDNS0 defines an EDNS0 Option. An OPT RR can have multiple options appended to it.
 type EDNS0 interface {
 	// Option returns the option code for the option.
@@ -427,9 +473,19 @@ type EDNS0_DAU struct {
 }
 
 // Option implements the EDNS0 interface.
-func (e *EDNS0_DAU) Option() uint16        { return EDNS0DAU }
-func (e *EDNS0_DAU) pack() ([]byte, error) { return e.AlgCode, nil }
-func (e *EDNS0_DAU) unpack(b []byte) error { e.AlgCode = b; return nil }
+func (e *EDNS0_DAU) Option() uint16 { return EDNS0DAU }
+
+func (e *EDNS0_DAU) pack() ([]byte, error) {
+	if err := checkAlgCodes(e.AlgCode); err != nil {
+		return nil, err
+	}
+	return e.AlgCode, nil
+}
+
+func (e *EDNS0_DAU) unpack(b []byte) error {
+	e.AlgCode = append([]uint8(nil), b...)
+	return nil
+}
 
 func (e *EDNS0_DAU) String() string {
 	s := ""
@@ -450,9 +506,19 @@ type EDNS0_DHU struct {
 }
 
 // Option implements the EDNS0 interface.
-func (e *EDNS0_DHU) Option() uint16        { return EDNS0DHU }
-func (e *EDNS0_DHU) pack() ([]byte, error) { return e.AlgCode, nil }
-func (e *EDNS0_DHU) unpack(b []byte) error { e.AlgCode = b; return nil }
+func (e *EDNS0_DHU) Option() uint16 { return EDNS0DHU }
+
+func (e *EDNS0_DHU) pack() ([]byte, error) {
+	if err := checkAlgCodes(e.AlgCode); err != nil {
+		return nil, err
+	}
+	return e.AlgCode, nil
+}
+
+func (e *EDNS0_DHU) unpack(b []byte) error {
+	e.AlgCode = append([]uint8(nil), b...)
+	return nil
+}
 
 func (e *EDNS0_DHU) String() string {
 	s := ""
@@ -473,9 +539,19 @@ type EDNS0_N3U struct {
 }
 
 // Option implements the EDNS0 interface.
-func (e *EDNS0_N3U) Option() uint16        { return EDNS0N3U }
-func (e *EDNS0_N3U) pack() ([]byte, error) { return e.AlgCode, nil }
-func (e *EDNS0_N3U) unpack(b []byte) error { e.AlgCode = b; return nil }
+func (e *EDNS0_N3U) Option() uint16 { return EDNS0N3U }
+
+func (e *EDNS0_N3U) pack() ([]byte, error) {
+	if err := checkAlgCodes(e.AlgCode); err != nil {
+		return nil, err
+	}
+	return e.AlgCode, nil
+}
+
+func (e *EDNS0_N3U) unpack(b []byte) error {
+	e.AlgCode = append([]uint8(nil), b...)
+	return nil
+}
 
 func (e *EDNS0_N3U) String() string {
 	// Re-use the hash map
@@ -490,6 +566,27 @@ func (e *EDNS0_N3U) String() string {
 	return s
 }
 
+// checkAlgCodes checks the algorithm list of a DAU, DHU or N3U option: it
+// must not be empty, and have no duplicates and no reserved code zero. Only
+// the lists that are packed are checked, a bad list in a message is kept as
+// it is.
+func checkAlgCodes(codes []uint8) error {
+	if len(codes) == 0 {
+		return errors.New("dns: empty algorithm list")
+	}
+	var seen [256]bool
+	for _, c := range codes {
+		if c == 0 {
+			return errors.New("dns: reserved algorithm 0 in algorithm list")
+		}
+		if seen[c] {
+			return errors.New("dns: duplicate algorithm in algorithm list: " + strconv.Itoa(int(c)))
+		}
+		seen[c] = true
+	}
+	return nil
+}
+
 // EDNS0_EXPIRE implementes the EDNS0 option as described in RFC 7314.
 type EDNS0_EXPIRE struct {
 	Code   uint16 // Always EDNS0EXPIRE
@@ -625,3 +722,338 @@ func (e *EDNS0_PADDING) Option() uint16        { return EDNS0PADDING }
 func (e *EDNS0_PADDING) pack() ([]byte, error) { return e.Padding, nil }
 func (e *EDNS0_PADDING) unpack(b []byte) error { e.Padding = b; return nil }
 func (e *EDNS0_PADDING) String() string        { return fmt.Sprintf("%0X", e.Padding) }
+
+// Extended DNS Error codes, see RFC 8914 section 4.
+const (
+	ExtendedErrorCodeOther uint16 = iota
+	ExtendedErrorCodeUnsupportedDNSKEYAlgorithm
+	ExtendedErrorCodeUnsupportedDSDigestType
+	ExtendedErrorCodeStaleAnswer
+	ExtendedErrorCodeForgedAnswer
+	ExtendedErrorCodeDNSSECIndeterminate
+	ExtendedErrorCodeDNSBogus
+	ExtendedErrorCodeSignatureExpired
+	ExtendedErrorCodeSignatureNotYetValid
+	ExtendedErrorCodeDNSKEYMissing
+	ExtendedErrorCodeRRSIGsMissing
+	ExtendedErrorCodeNoZoneKeyBitSet
+	ExtendedErrorCodeNSECMissing
+	ExtendedErrorCodeCachedError
+	ExtendedErrorCodeNotReady
+	ExtendedErrorCodeBlocked
+	ExtendedErrorCodeCensored
+	ExtendedErrorCodeFiltered
+	ExtendedErrorCodeProhibited
+	ExtendedErrorCodeStaleNXDOMAINAnswer
+	ExtendedErrorCodeNotAuthoritative
+	ExtendedErrorCodeNotSupported
+	ExtendedErrorCodeNoReachableAuthority
+	ExtendedErrorCodeNetworkError
+	ExtendedErrorCodeInvalidData
+	ExtendedErrorCodeSignatureExpiredBeforeValid
+	ExtendedErrorCodeTooEarly
+	ExtendedErrorCodeUnsupportedNSEC3IterValue
+	ExtendedErrorCodeUnableToConformToPolicy
+	ExtendedErrorCodeSynthesized
+	ExtendedErrorCodeInvalidQueryType
+)
+
+// ExtendedErrorCodeToString maps extended error info codes to a human readable
+// description.
+var ExtendedErrorCodeToString = map[uint16]string{
+	ExtendedErrorCodeOther:                       "Other",
+	ExtendedErrorCodeUnsupportedDNSKEYAlgorithm:  "Unsupported DNSKEY Algorithm",
+	ExtendedErrorCodeUnsupportedDSDigestType:     "Unsupported DS Digest Type",
+	ExtendedErrorCodeStaleAnswer:                 "Stale Answer",
+	ExtendedErrorCodeForgedAnswer:                "Forged Answer",
+	ExtendedErrorCodeDNSSECIndeterminate:         "DNSSEC Indeterminate",
+	ExtendedErrorCodeDNSBogus:                    "DNSSEC Bogus",
+	ExtendedErrorCodeSignatureExpired:            "Signature Expired",
+	ExtendedErrorCodeSignatureNotYetValid:        "Signature Not Yet Valid",
+	ExtendedErrorCodeDNSKEYMissing:               "DNSKEY Missing",
+	ExtendedErrorCodeRRSIGsMissing:               "RRSIGs Missing",
+	ExtendedErrorCodeNoZoneKeyBitSet:             "No Zone Key Bit Set",
+	ExtendedErrorCodeNSECMissing:                 "NSEC Missing",
+	ExtendedErrorCodeCachedError:                 "Cached Error",
+	ExtendedErrorCodeNotReady:                    "Not Ready",
+	ExtendedErrorCodeBlocked:                     "Blocked",
+	ExtendedErrorCodeCensored:                    "Censored",
+	ExtendedErrorCodeFiltered:                    "Filtered",
+	ExtendedErrorCodeProhibited:                  "Prohibited",
+	ExtendedErrorCodeStaleNXDOMAINAnswer:         "Stale NXDOMAIN Answer",
+	ExtendedErrorCodeNotAuthoritative:            "Not Authoritative",
+	ExtendedErrorCodeNotSupported:                "Not Supported",
+	ExtendedErrorCodeNoReachableAuthority:        "No Reachable Authority",
+	ExtendedErrorCodeNetworkError:                "Network Error",
+	ExtendedErrorCodeInvalidData:                 "Invalid Data",
+	ExtendedErrorCodeSignatureExpiredBeforeValid: "Signature Expired Before Valid",
+	ExtendedErrorCodeTooEarly:                    "Too Early",
+	ExtendedErrorCodeUnsupportedNSEC3IterValue:   "Unsupported NSEC3 Iterations Value",
+	ExtendedErrorCodeUnableToConformToPolicy:     "Unable To Conform To Policy",
+	ExtendedErrorCodeSynthesized:                 "Synthesized",
+	ExtendedErrorCodeInvalidQueryType:            "Invalid Query Type",
+}
+
+// EDNS0_EDE option is used to return extended error information about a
+// response, like why it is a SERVFAIL. A message can have more than one of
+// them. See RFC 8914.
+// Basic use pattern for adding an extended error to a reply:
+//
+//	m := new(dns.Msg)
+//	m.SetRcode(req, dns.RcodeServerFailure)
+//	m.SetEdns0(4096, false)
+//	m.AddEDE(dns.ExtendedErrorCodeDNSBogus, "signature expired on example.org. DNSKEY")
+type EDNS0_EDE struct {
+	InfoCode  uint16
+	ExtraText string // UTF-8 encoded, may be empty
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_EDE) Option() uint16 { return EDNS0EDE }
+
+func (e *EDNS0_EDE) String() string {
+	info := strconv.Itoa(int(e.InfoCode))
+	if s, ok := ExtendedErrorCodeToString[e.InfoCode]; ok {
+		info += " (" + s + ")"
+	}
+	if e.ExtraText == "" {
+		return info
+	}
+	return info + ": " + strconv.Quote(e.ExtraText)
+}
+
+func (e *EDNS0_EDE) pack() ([]byte, error) {
+	if !utf8.ValidString(e.ExtraText) {
+		return nil, errors.New("dns: extended error text is not UTF-8")
+	}
+	b := make([]byte, 2+len(e.ExtraText))
+	binary.BigEndian.PutUint16(b[0:], e.InfoCode)
+	copy(b[2:], e.ExtraText)
+	return b, nil
+}
+
+func (e *EDNS0_EDE) unpack(b []byte) error {
+	if len(b) < 2 {
+		return ErrBuf
+	}
+	e.InfoCode = binary.BigEndian.Uint16(b[0:])
+	e.ExtraText = string(b[2:])
+	return nil
+}
+
+// EDNS0_CHAIN implements the EDNS0 CHAIN option, with which a client asks for
+// the DNSSEC records from its closest trust point to the answer. In a reply it
+// has the closest trust point the server used. See RFC 7901.
+type EDNS0_CHAIN struct {
+	ClosestTrustPoint string // fully qualified, never compressed
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_CHAIN) Option() uint16 { return EDNS0CHAIN }
+func (e *EDNS0_CHAIN) String() string { return e.ClosestTrustPoint }
+
+func (e *EDNS0_CHAIN) pack() ([]byte, error) {
+	return packOptionName(e.ClosestTrustPoint)
+}
+
+func (e *EDNS0_CHAIN) unpack(b []byte) error {
+	name, err := unpackOptionName(b)
+	if err != nil {
+		return err
+	}
+	e.ClosestTrustPoint = name
+	return nil
+}
+
+// packOptionName returns the uncompressed wire format of the fully qualified
+// domain name s, as used in the CHAIN and Report-Channel options.
+func packOptionName(s string) ([]byte, error) {
+	if !IsFqdn(s) {
+		return nil, errors.New("dns: domain name in option is not fully qualified: " + s)
+	}
+	b := make([]byte, 255)
+	off, err := PackDomainName(s, b, 0, nil, false)
+	if err != nil {
+		return nil, err
+	}
+	return b[:off], nil
+}
+
+// unpackOptionName unpacks the domain name that is all of the option data b.
+func unpackOptionName(b []byte) (string, error) {
+	// The name is not compressed, RFC 7901 and RFC 9567 require that.
+	for off := 0; off < len(b) && b[off] != 0; off += int(b[off]) + 1 {
+		if b[off]&0xC0 != 0 {
+			return "", errors.New("dns: compressed domain name in option")
+		}
+	}
+	name, off, err := UnpackDomainName(b, 0)
+	if err != nil {
+		return "", err
+	}
+	if off != len(b) {
+		return "", errors.New("dns: trailing data after domain name in option")
+	}
+	return name, nil
+}
+
+// EDNS0_KEYTAG implements the EDNS0 edns-key-tag option, with which a
+// validator signals the key tags of the trust anchors it uses for a zone.
+// See RFC 8145.
+type EDNS0_KEYTAG struct {
+	KeyTags []uint16
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_KEYTAG) Option() uint16 { return EDNS0KEYTAG }
+
+func (e *EDNS0_KEYTAG) String() string {
+	s := make([]string, len(e.KeyTags))
+	for i, t := range e.KeyTags {
+		s[i] = strconv.Itoa(int(t))
+	}
+	return strings.Join(s, " ")
+}
+
+func (e *EDNS0_KEYTAG) pack() ([]byte, error) {
+	if len(e.KeyTags) == 0 {
+		return nil, errors.New("dns: no key tags in key tag option")
+	}
+	b := make([]byte, 2*len(e.KeyTags))
+	for i, t := range e.KeyTags {
+		binary.BigEndian.PutUint16(b[2*i:], t)
+	}
+	return b, nil
+}
+
+func (e *EDNS0_KEYTAG) unpack(b []byte) error {
+	if len(b) == 0 || len(b)%2 != 0 {
+		return errors.New("dns: bad key tag option length: " + strconv.Itoa(len(b)))
+	}
+	e.KeyTags = make([]uint16, len(b)/2)
+	for i := range e.KeyTags {
+		e.KeyTags[i] = binary.BigEndian.Uint16(b[2*i:])
+	}
+	return nil
+}
+
+// EDNS0_CLIENTTAG implements the EDNS0 client tag option, a 16 bit value a
+// client uses to tell the server which local policy to apply.
+type EDNS0_CLIENTTAG struct {
+	Tag uint16
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_CLIENTTAG) Option() uint16 { return EDNS0CLIENTTAG }
+func (e *EDNS0_CLIENTTAG) String() string { return strconv.Itoa(int(e.Tag)) }
+
+func (e *EDNS0_CLIENTTAG) pack() ([]byte, error) {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, e.Tag)
+	return b, nil
+}
+
+func (e *EDNS0_CLIENTTAG) unpack(b []byte) error {
+	if len(b) != 2 {
+		return errors.New("dns: bad client tag option length: " + strconv.Itoa(len(b)))
+	}
+	e.Tag = binary.BigEndian.Uint16(b)
+	return nil
+}
+
+// EDNS0_REPORTCHANNEL implements the EDNS0 Report-Channel option, which has
+// the agent domain that resolvers send error reports to. See RFC 9567.
+type EDNS0_REPORTCHANNEL struct {
+	AgentDomain string // fully qualified, never compressed
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_REPORTCHANNEL) Option() uint16 { return EDNS0REPORTCHANNEL }
+func (e *EDNS0_REPORTCHANNEL) String() string { return e.AgentDomain }
+
+func (e *EDNS0_REPORTCHANNEL) pack() ([]byte, error) {
+	if e.AgentDomain == "." {
+		return nil, errors.New("dns: root as report channel agent domain")
+	}
+	return packOptionName(e.AgentDomain)
+}
+
+func (e *EDNS0_REPORTCHANNEL) unpack(b []byte) error {
+	name, err := unpackOptionName(b)
+	if err != nil {
+		return err
+	}
+	if name == "." {
+		return errors.New("dns: root as report channel agent domain")
+	}
+	e.AgentDomain = name
+	return nil
+}
+
+// ZoneVersionTypeSOASerial is the ZONEVERSION type of a version that is the
+// serial of the SOA record, RFC 9660 section 7.2.
+const ZoneVersionTypeSOASerial = 0
+
+// EDNS0_ZONEVERSION implements the EDNS0 ZONEVERSION option. In a query it is
+// empty, in a reply it has the version of the zone the answer is from. See
+// RFC 9660.
+type EDNS0_ZONEVERSION struct {
+	LabelCount uint8 // the number of labels of the zone name, if this is a reply
+	Type       uint8
+	Version    []byte // the version of the zone, if empty this is a query
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_ZONEVERSION) Option() uint16 { return EDNS0ZONEVERSION }
+
+func (e *EDNS0_ZONEVERSION) String() string {
+	if len(e.Version) == 0 {
+		return ""
+	}
+	if serial, ok := e.Serial(); ok {
+		return strconv.Itoa(int(e.LabelCount)) + " SOA-SERIAL " + strconv.FormatUint(uint64(serial), 10)
+	}
+	return strconv.Itoa(int(e.LabelCount)) + " " + strconv.Itoa(int(e.Type)) + " " + hex.EncodeToString(e.Version)
+}
+
+// Serial returns the SOA serial in the option, if its type is
+// ZoneVersionTypeSOASerial.
+func (e *EDNS0_ZONEVERSION) Serial() (uint32, bool) {
+	if e.Type != ZoneVersionTypeSOASerial || len(e.Version) != 4 {
+		return 0, false
+	}
+	return binary.BigEndian.Uint32(e.Version), true
+}
+
+func (e *EDNS0_ZONEVERSION) pack() ([]byte, error) {
+	if len(e.Version) == 0 {
+		if e.LabelCount != 0 || e.Type != 0 {
+			return nil, errors.New("dns: zone version option without version")
+		}
+		return []byte{}, nil
+	}
+	if e.Type == ZoneVersionTypeSOASerial && len(e.Version) != 4 {
+		return nil, errors.New("dns: bad SOA serial length in zone version option: " + strconv.Itoa(len(e.Version)))
+	}
+	b := make([]byte, 2+len(e.Version))
+	b[0] = e.LabelCount
+	b[1] = e.Type
+	copy(b[2:], e.Version)
+	return b, nil
+}
+
+func (e *EDNS0_ZONEVERSION) unpack(b []byte) error {
+	switch {
+	case len(b) == 0:
+		*e = EDNS0_ZONEVERSION{}
+		return nil
+	case len(b) < 3:
+		return errors.New("dns: bad zone version option length: " + strconv.Itoa(len(b)))
+	case b[1] == ZoneVersionTypeSOASerial && len(b) != 6:
+		return errors.New("dns: bad SOA serial length in zone version option: " + strconv.Itoa(len(b)-2))
+	}
+	e.LabelCount = b[0]
+	e.Type = b[1]
+	e.Version = append([]byte(nil), b[2:]...)
+	return nil
+}
@@ -1,6 +1,10 @@
 package dns
 
-import "testing"
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
 
 func TestOPTTtl(t *testing.T) {
 	e := &OPT{}
@@ -66,3 +70,153 @@ func TestOPTTtl(t *testing.T) {
 		t.Errorf("set 42, expected %d, got %d", 42, e.ExtendedRcode())
 	}
 }
+
+func TestEDNS0_EDE(t *testing.T) {
+	req := new(Msg)
+	req.SetQuestion("example.org.", TypeA)
+	req.SetEdns0(1232, true)
+
+	m := new(Msg)
+	m.SetRcode(req, RcodeServerFailure)
+	if len(m.EDE()) != 0 {
+		t.Errorf("got extended errors in a message without OPT RR: %v", m.EDE())
+	}
+	m.AddEDE(ExtendedErrorCodeDNSBogus, "signature expired: example.org. DNSKEY")
+	m.AddEDE(ExtendedErrorCodeStaleAnswer, "")
+	m.AddEDE(ExtendedErrorCodeTooEarly+100, "ünknown")
+	if len(m.Extra) != 1 {
+		t.Fatalf("got %d records in the additional section, want 1", len(m.Extra))
+	}
+
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m1 := new(Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	ede := m1.EDE()
+	if len(ede) != 3 {
+		t.Fatalf("got %d extended errors, want 3", len(ede))
+	}
+	for i, want := range []string{
+		`6 (DNSSEC Bogus): "signature expired: example.org. DNSKEY"`,
+		`3 (Stale Answer)`,
+		`126: "ünknown"`,
+	} {
+		if ede[i].String() != want {
+			t.Errorf("got extended error %q, want %q", ede[i].String(), want)
+		}
+	}
+	if StringToExtendedErrorCode["Blocked"] != ExtendedErrorCodeBlocked {
+		t.Errorf("got code %d for Blocked", StringToExtendedErrorCode["Blocked"])
+	}
+
+	// The extra text must be UTF-8 and the info code is required.
+	m.AddEDE(ExtendedErrorCodeOther, "\xff")
+	if _, err := m.Pack(); err == nil {
+		t.Error("packed an extended error with text that is not UTF-8")
+	}
+	e := new(EDNS0_EDE)
+	if err := e.unpack([]byte{0}); err == nil {
+		t.Error("unpacked an extended error without info code")
+	}
+}
+
+func TestEDNS0Options(t *testing.T) {
+	for _, tc := range []struct {
+		opt  EDNS0
+		want string
+	}{
+		{&EDNS0_CHAIN{ClosestTrustPoint: "example.com."}, "example.com."},
+		{&EDNS0_CHAIN{ClosestTrustPoint: "."}, "."},
+		{&EDNS0_KEYTAG{KeyTags: []uint16{19036, 20326}}, "19036 20326"},
+		{&EDNS0_CLIENTTAG{Tag: 42}, "42"},
+		{&EDNS0_REPORTCHANNEL{AgentDomain: "a01.agent-domain.example."}, "a01.agent-domain.example."},
+		{&EDNS0_ZONEVERSION{}, ""},
+		{&EDNS0_ZONEVERSION{LabelCount: 2, Type: ZoneVersionTypeSOASerial, Version: []byte{0x78, 0x49, 0x6b, 0x79}}, "2 SOA-SERIAL 2018077561"},
+		{&EDNS0_ZONEVERSION{LabelCount: 2, Type: 250, Version: []byte{0xab}}, "2 250 ab"},
+		{&EDNS0_DAU{AlgCode: []uint8{RSASHA256, ED25519}}, " RSASHA256 ED25519"},
+		{&EDNS0_DHU{AlgCode: []uint8{SHA256, SHA384}}, " SHA256 SHA384"},
+		{&EDNS0_N3U{AlgCode: []uint8{SHA1}}, " SHA1"},
+	} {
+		m := new(Msg)
+		m.SetQuestion("example.com.", TypeA)
+		m.SetEdns0(4096, true)
+		m.IsEdns0().Option = []EDNS0{tc.opt}
+		buf, err := m.Pack()
+		if err != nil {
+			t.Errorf("failed to pack %T: %v", tc.opt, err)
+			continue
+		}
+		m1 := new(Msg)
+		if err := m1.Unpack(buf); err != nil {
+			t.Errorf("failed to unpack %T: %v", tc.opt, err)
+			continue
+		}
+		opt := m1.IsEdns0().Option
+		if len(opt) != 1 || reflect.TypeOf(opt[0]) != reflect.TypeOf(tc.opt) {
+			t.Errorf("unpacked %T as %v", tc.opt, opt)
+			continue
+		}
+		if !reflect.DeepEqual(opt[0], tc.opt) || opt[0].String() != tc.want {
+			t.Errorf("unpacked %T as %q, want %q", tc.opt, opt[0].String(), tc.want)
+		}
+	}
+}
+
+func TestEDNS0OptionsBad(t *testing.T) {
+	for _, o := range []EDNS0{
+		&EDNS0_CHAIN{ClosestTrustPoint: "example.com"},
+		&EDNS0_KEYTAG{},
+		&EDNS0_REPORTCHANNEL{AgentDomain: "."},
+		&EDNS0_ZONEVERSION{LabelCount: 2, Type: ZoneVersionTypeSOASerial, Version: []byte{1, 2}},
+		&EDNS0_ZONEVERSION{LabelCount: 2},
+		&EDNS0_DAU{},
+		&EDNS0_DHU{AlgCode: []uint8{SHA256, 0}},
+		&EDNS0_N3U{AlgCode: []uint8{SHA1, SHA1}},
+	} {
+		if _, err := o.pack(); err == nil {
+			t.Errorf("packed %T %v", o, o)
+		}
+	}
+
+	for _, tc := range []struct {
+		opt  EDNS0
+		data []byte
+	}{
+		{new(EDNS0_CHAIN), []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0, 0}},
+		{new(EDNS0_CHAIN), []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e'}},
+		{new(EDNS0_KEYTAG), []byte{}},
+		{new(EDNS0_KEYTAG), []byte{1, 2, 3}},
+		{new(EDNS0_CLIENTTAG), []byte{1}},
+		{new(EDNS0_REPORTCHANNEL), []byte{0}},
+		{new(EDNS0_REPORTCHANNEL), []byte{1, 'a', 0xC0, 4, 1, 'b', 0}},
+		{new(EDNS0_CHAIN), []byte{7, 'e', 'x', 'a', 'm', 'p', 'l', 'e', 0xC0, 0}},
+		{new(EDNS0_ZONEVERSION), []byte{1}},
+		{new(EDNS0_ZONEVERSION), []byte{1, ZoneVersionTypeSOASerial, 1, 2, 3}},
+	} {
+		if err := tc.opt.unpack(tc.data); err == nil {
+			t.Errorf("unpacked %v as %T %v", tc.data, tc.opt, tc.opt)
+		}
+	}
+
+	// Bad algorithm lists are not packed, but do not fail a message.
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.SetEdns0(4096, false)
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// EDNS0_DHU with the algorithm list 2, 2.
+	buf = append(buf, 0, EDNS0DHU, 0, 2, 2, 2)
+	binary.BigEndian.PutUint16(buf[len(buf)-6-2:], 6)
+	if err := m.Unpack(buf); err != nil {
+		t.Fatalf("failed to unpack a message with a bad DHU option: %v", err)
+	}
+	if opt := m.IsEdns0(); len(opt.Option) != 1 || !reflect.DeepEqual(opt.Option[0], &EDNS0_DHU{AlgCode: []uint8{2, 2}}) {
+		t.Errorf("got options %v", opt.Option)
+	}
+}
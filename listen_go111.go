@@ -0,0 +1,60 @@
+// +build go1.11
+
+package dns
+
+import (
+	"context"
+	"net"
+	"strings"
+	"syscall"
+)
+
+// listenTCP opens a TCP listener, setting SO_REUSEPORT and TCP_FASTOPEN as
+// configured.
+func (srv *Server) listenTCP(network, addr string) (net.Listener, error) {
+	if srv.ReusePort <= 1 && srv.TCPFastOpen <= 0 {
+		a, err := net.ResolveTCPAddr(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return net.ListenTCP(network, a)
+	}
+	lc := net.ListenConfig{Control: srv.control}
+	return lc.Listen(context.Background(), network, addr)
+}
+
+// listenUDP opens a UDP socket, setting SO_REUSEPORT as configured.
+func (srv *Server) listenUDP(network, addr string) (*net.UDPConn, error) {
+	if srv.ReusePort <= 1 {
+		a, err := net.ResolveUDPAddr(network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return net.ListenUDP(network, a)
+	}
+	lc := net.ListenConfig{Control: srv.control}
+	l, err := lc.ListenPacket(context.Background(), network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return l.(*net.UDPConn), nil
+}
+
+// control sets the socket options on sockets opened by ListenAndServe.
+func (srv *Server) control(network, address string, c syscall.RawConn) error {
+	var err error
+	cerr := c.Control(func(fd uintptr) {
+		if srv.ReusePort > 1 {
+			if err = setReusePort(fd); err != nil {
+				return
+			}
+		}
+		if srv.TCPFastOpen > 0 && strings.HasPrefix(network, "tcp") {
+			err = setTCPFastOpen(fd, srv.TCPFastOpen)
+		}
+	})
+	if cerr != nil {
+		return cerr
+	}
+	return err
+}
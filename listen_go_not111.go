@@ -0,0 +1,30 @@
+// +build !go1.11
+
+package dns
+
+import "net"
+
+// listenTCP opens a TCP listener. Setting socket options before binding needs
+// net.ListenConfig, so SO_REUSEPORT and TCP_FASTOPEN need Go 1.11.
+func (srv *Server) listenTCP(network, addr string) (net.Listener, error) {
+	if srv.ReusePort > 1 || srv.TCPFastOpen > 0 {
+		return nil, &Error{err: "SO_REUSEPORT and TCP Fast Open need Go 1.11"}
+	}
+	a, err := net.ResolveTCPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenTCP(network, a)
+}
+
+// listenUDP opens a UDP socket, SO_REUSEPORT needs Go 1.11.
+func (srv *Server) listenUDP(network, addr string) (*net.UDPConn, error) {
+	if srv.ReusePort > 1 {
+		return nil, &Error{err: "SO_REUSEPORT needs Go 1.11"}
+	}
+	a, err := net.ResolveUDPAddr(network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return net.ListenUDP(network, a)
+}
@@ -0,0 +1,11 @@
+// +build !linux mips mipsle mips64 mips64le
+
+package dns
+
+func setReusePort(fd uintptr) error {
+	return &Error{err: "SO_REUSEPORT not supported on this platform"}
+}
+
+func setTCPFastOpen(fd uintptr, qlen int) error {
+	return &Error{err: "TCP Fast Open not supported on this platform"}
+}
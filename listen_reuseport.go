@@ -0,0 +1,19 @@
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package dns
+
+import "syscall"
+
+// The syscall package does not define these for all architectures.
+const (
+	soReusePort = 0xf
+	tcpFastOpen = 0x17
+)
+
+func setReusePort(fd uintptr) error {
+	return syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, soReusePort, 1)
+}
+
+func setTCPFastOpen(fd uintptr, qlen int) error {
+	return syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, tcpFastOpen, qlen)
+}
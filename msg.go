@@ -18,6 +18,7 @@ import (
 	"math/big"
 	"math/rand"
 	"strconv"
+	"strings"
 	"sync"
 )
 
@@ -32,24 +33,37 @@ var (
 	ErrAuth          error = &Error{err: "bad authentication"}             // ErrAuth indicates an error in the TSIG authentication.
 	ErrBuf           error = &Error{err: "buffer size too small"}          // ErrBuf indicates that the buffer used is too small for the message.
 	ErrConnEmpty     error = &Error{err: "conn has no connection"}         // ErrConnEmpty indicates a connection is being used before it is initialized.
+	ErrDenial        error = &Error{err: "bad denial of existence"}        // ErrDenial indicates that the NSEC or NSEC3 records do not prove a negative answer.
+	ErrDigest        error = &Error{err: "unsupported digest"}             // ErrDigest indicates that none of the DS or ZONEMD records has a supported digest type.
 	ErrExtendedRcode error = &Error{err: "bad extended rcode"}             // ErrExtendedRcode ...
 	ErrFqdn          error = &Error{err: "domain must be fully qualified"} // ErrFqdn indicates that a domain name does not have a closing dot.
 	ErrId            error = &Error{err: "id mismatch"}                    // ErrId indicates there is a mismatch with the message's ID.
+	ErrInsecure      error = &Error{err: "insecure delegation"}            // ErrInsecure indicates that a zone is delegated without DS records.
+	ErrIterations    error = &Error{err: "too many NSEC3 iterations"}      // ErrIterations indicates that the NSEC3 records use more iterations than allowed.
 	ErrKeyAlg        error = &Error{err: "bad key algorithm"}              // ErrKeyAlg indicates that the algorithm in the key is not valid.
 	ErrKey           error = &Error{err: "bad key"}
 	ErrKeySize       error = &Error{err: "bad key size"}
 	ErrLongDomain    error = &Error{err: fmt.Sprintf("domain name exceeded %d wire-format octets", maxDomainNameWireOctets)}
+	ErrMACSize       error = &Error{err: "bad mac size"}          // ErrMACSize indicates that a TSIG MAC is longer than its hash or truncated below the allowed minimum.
+	ErrNegAnchor     error = &Error{err: "negative trust anchor"} // ErrNegAnchor indicates that a name is at or below a negative trust anchor.
+	ErrNoDNSKEY      error = &Error{err: "no matching DNSKEY"}    // ErrNoDNSKEY indicates that no DNSKEY matches a DS, trust anchor or signature.
 	ErrNoSig         error = &Error{err: "no signature found"}
+	ErrNoTrustAnchor error = &Error{err: "no trust anchor"} // ErrNoTrustAnchor indicates that no trust anchor is configured for a name.
 	ErrPrivKey       error = &Error{err: "bad private key"}
 	ErrRcode         error = &Error{err: "bad rcode"}
 	ErrRdata         error = &Error{err: "bad rdata"}
 	ErrRRset         error = &Error{err: "bad rrset"}
 	ErrSecret        error = &Error{err: "no secrets defined"}
+	ErrSectionDone   error = &Error{err: "section done"} // ErrSectionDone indicates that a section of a message has been parsed or built completely.
 	ErrShortRead     error = &Error{err: "short read"}
 	ErrSig           error = &Error{err: "bad signature"}                      // ErrSig indicates that a signature can not be cryptographically validated.
+	ErrSigExpired    error = &Error{err: "signature expired"}                  // ErrSigExpired indicates that the validity period of a signature has passed.
+	ErrSigNotYet     error = &Error{err: "signature not yet valid"}            // ErrSigNotYet indicates that the validity period of a signature has not started.
 	ErrSoa           error = &Error{err: "no SOA"}                             // ErrSOA indicates that no SOA RR was seen when doing zone transfers.
 	ErrTime          error = &Error{err: "bad time"}                           // ErrTime indicates a timing error in TSIG authentication.
+	ErrTrunc         error = &Error{err: "bad truncation"}                     // ErrTrunc indicates that a TSIG MAC is shorter than its algorithm calls for.
 	ErrTruncated     error = &Error{err: "failed to unpack truncated message"} // ErrTruncated indicates that we failed to unpack a truncated message. We unpacked as much as we had so Msg can still be used, if desired.
+	ErrZoneMD        error = &Error{err: "bad zone digest"}                    // ErrZoneMD indicates that no ZONEMD record matches the digest of a zone.
 )
 
 // Id by default, returns a 16 bits random number to be used as a
@@ -116,6 +130,52 @@ type MsgHdr struct {
 	Rcode              int
 }
 
+// bits returns the flags, opcode and rcode of h as in the second word of the
+// wire format header. Only the lower 4 bits of the rcode are used.
+func (h *MsgHdr) bits() uint16 {
+	bits := uint16(h.Opcode)<<11 | uint16(h.Rcode&0xF)
+	if h.Response {
+		bits |= _QR
+	}
+	if h.Authoritative {
+		bits |= _AA
+	}
+	if h.Truncated {
+		bits |= _TC
+	}
+	if h.RecursionDesired {
+		bits |= _RD
+	}
+	if h.RecursionAvailable {
+		bits |= _RA
+	}
+	if h.Zero {
+		bits |= _Z
+	}
+	if h.AuthenticatedData {
+		bits |= _AD
+	}
+	if h.CheckingDisabled {
+		bits |= _CD
+	}
+	return bits
+}
+
+// setBits sets the flags, opcode and rcode of h from the second word of the
+// wire format header.
+func (h *MsgHdr) setBits(bits uint16) {
+	h.Response = bits&_QR != 0
+	h.Opcode = int(bits>>11) & 0xF
+	h.Authoritative = bits&_AA != 0
+	h.Truncated = bits&_TC != 0
+	h.RecursionDesired = bits&_RD != 0
+	h.RecursionAvailable = bits&_RA != 0
+	h.Zero = bits&_Z != 0
+	h.AuthenticatedData = bits&_AD != 0
+	h.CheckingDisabled = bits&_CD != 0
+	h.Rcode = int(bits & 0xF)
+}
+
 // Msg contains the layout of a DNS message.
 type Msg struct {
 	MsgHdr
@@ -177,11 +237,11 @@ var RcodeToString = map[int]string{
 // map needs to hold a mapping between domain names and offsets
 // pointing into msg.
 func PackDomainName(s string, msg []byte, off int, compression map[string]int, compress bool) (off1 int, err error) {
-	off1, _, err = packDomainName(s, msg, off, compression, compress)
+	off1, _, err = packDomainName(s, msg, off, compressionMap{ext: compression}, compress)
 	return
 }
 
-func packDomainName(s string, msg []byte, off int, compression map[string]int, compress bool) (off1 int, labels int, err error) {
+func packDomainName(s string, msg []byte, off int, compression compressionMap, compress bool) (off1 int, labels int, err error) {
 	// special case if msg == nil
 	lenmsg := 256
 	if msg != nil {
@@ -203,117 +263,168 @@ func packDomainName(s string, msg []byte, off int, compression map[string]int, c
 			return lenmsg, 0, ErrFqdn
 		}
 	}
-	// Each dot ends a segment of the name.
-	// We trade each dot byte for a length byte.
+	// Root label is special
+	if s == "." {
+		if off+1 > lenmsg {
+			return lenmsg, 0, ErrBuf
+		}
+		if msg != nil {
+			msg[off] = 0
+		}
+		return off + 1, 1, nil
+	}
+
+	// Each dot ends a label. We trade each dot byte for a length byte.
 	// Except for escaped dots (\.), which are normal dots.
 	// There is also a trailing zero.
+	var lbsBuf, offsBuf [16]int
+	lbs, offs := lbsBuf[:0], offsBuf[:0]
+	if t := compression.table; t != nil {
+		lbs, offs = t.lbs[:0], t.offs[:0]
+	}
+	lbs = labelOffsets(s, lbs)
+	escaped := strings.IndexByte(s, '\\') >= 0
+	n := 0
+	if len(lbs) > 0 {
+		n = len(lbs) - 1
+	}
 
-	// Compression
-	nameoffset := -1
+	// Compression: the longest suffix of s that is already in the message
+	// is replaced by a pointer to it, the labels before it are packed.
+	var keys []string // of the suffixes in compression.ext
+	switch {
+	case compression.table != nil && escaped:
+		compression.table.mapSuffixes(s, compress)
+	case compression.table != nil:
+		compression.table.suffixes(s, lbs)
+	case compression.ext != nil && escaped:
+		keys = mapKeys(s, compress, nil)
+	case compression.ext != nil:
+		for i := 0; i < n; i++ {
+			keys = append(keys, s[lbs[i]:])
+		}
+	}
 	pointer := -1
-	// Emit sequence of counted strings, chopping at dots.
-	begin := 0
-	bs := []byte(s)
-	roBs, bsFresh, escapedDot := s, true, false
-	for i := 0; i < ls; i++ {
-		if bs[i] == '\\' {
-			for j := i; j < ls-1; j++ {
-				bs[j] = bs[j+1]
-			}
-			ls--
-			if off+1 > lenmsg {
-				return lenmsg, labels, ErrBuf
+	if compress {
+		switch {
+		case compression.table != nil:
+			if i, p := compression.table.search(); i >= 0 {
+				n, pointer = i, p
 			}
-			// check for \DDD
-			if i+2 < ls && isDigit(bs[i]) && isDigit(bs[i+1]) && isDigit(bs[i+2]) {
-				bs[i] = dddToByte(bs[i:])
-				for j := i + 1; j < ls-2; j++ {
-					bs[j] = bs[j+2]
+		case compression.ext != nil:
+			for i, k := range keys {
+				if p, ok := compression.ext[k]; ok {
+					n, pointer = i, p
+					break
 				}
-				ls -= 2
 			}
-			escapedDot = bs[i] == '.'
-			bsFresh = false
-			continue
 		}
+	}
 
-		if bs[i] == '.' {
-			if i > 0 && bs[i-1] == '.' && !escapedDot {
-				// two dots back to back is not legal
-				return lenmsg, labels, ErrRdata
-			}
-			if i-begin >= 1<<6 { // top two bits of length must be clear
-				return lenmsg, labels, ErrRdata
+	for i := 0; i < n; i++ {
+		label := s[lbs[i] : lbs[i+1]-1]
+		if len(label) == 0 && i > 0 {
+			// two dots back to back is not legal
+			return lenmsg, labels, ErrRdata
+		}
+		size := len(label)
+		if escaped {
+			size = 0
+			for j := 0; j < len(label); j++ {
+				if label[j] == '\\' {
+					if j+3 < len(label) && isDigit(label[j+1]) && isDigit(label[j+2]) && isDigit(label[j+3]) {
+						j += 3
+					} else {
+						j++
+					}
+				}
+				size++
 			}
-			// off can already (we're in a loop) be bigger than len(msg)
-			// this happens when a name isn't fully qualified
-			if off+1 > lenmsg {
+		}
+		if size >= 1<<6 { // top two bits of length must be clear
+			return lenmsg, labels, ErrRdata
+		}
+		if off+1 > lenmsg {
+			return lenmsg, labels, ErrBuf
+		}
+		if msg != nil {
+			msg[off] = byte(size)
+		}
+		offs = append(offs, off)
+		off++
+		if !escaped {
+			if off+size > lenmsg {
 				return lenmsg, labels, ErrBuf
 			}
 			if msg != nil {
-				msg[off] = byte(i - begin)
+				copy(msg[off:], label)
 			}
-			offset := off
-			off++
-			for j := begin; j < i; j++ {
-				if off+1 > lenmsg {
-					return lenmsg, labels, ErrBuf
-				}
-				if msg != nil {
-					msg[off] = bs[j]
+			off += size
+			labels++
+			continue
+		}
+		for j := 0; j < len(label); j++ {
+			c := label[j]
+			if c == '\\' {
+				if j+3 < len(label) && isDigit(label[j+1]) && isDigit(label[j+2]) && isDigit(label[j+3]) {
+					c = dddStringToByte(label[j+1:])
+					j += 3
+				} else {
+					j++
+					c = label[j]
 				}
-				off++
 			}
-			if compress && !bsFresh {
-				roBs = string(bs)
-				bsFresh = true
+			if off+1 > lenmsg {
+				return lenmsg, labels, ErrBuf
 			}
-			// Don't try to compress '.'
-			// We should only compress when compress it true, but we should also still pick
-			// up names that can be used for *future* compression(s).
-			if compression != nil && roBs[begin:] != "." {
-				if p, ok := compression[roBs[begin:]]; !ok {
-					// Only offsets smaller than this can be used.
-					if offset < maxCompressionOffset {
-						compression[roBs[begin:]] = offset
-					}
-				} else {
-					// The first hit is the longest matching dname
-					// keep the pointer offset we get back and store
-					// the offset of the current name, because that's
-					// where we need to insert the pointer later
-
-					// If compress is true, we're allowed to compress this dname
-					if pointer == -1 && compress {
-						pointer = p         // Where to point to
-						nameoffset = offset // Where to point from
-						break
-					}
-				}
+			if msg != nil {
+				msg[off] = c
 			}
-			labels++
-			begin = i + 1
+			off++
 		}
-		escapedDot = false
+		labels++
 	}
-	// Root label is special
-	if len(bs) == 1 && bs[0] == '.' {
-		return off, labels, nil
+
+	// We should only compress when compress is true, but we should also still pick
+	// up names that can be used for *future* compression(s).
+	switch {
+	case compression.table != nil:
+		compression.table.insert(offs, n)
+	case compression.ext != nil:
+		for i, o := range offs {
+			// Only offsets smaller than this can be used.
+			if _, ok := compression.ext[keys[i]]; !ok && o < maxCompressionOffset {
+				compression.ext[keys[i]] = o
+			}
+		}
+	}
+
+	// An escape after the last label, as in "a\.", still needs room in msg.
+	if escaped && pointer == -1 && off+1 > lenmsg {
+		tail := s
+		if len(lbs) > 0 {
+			tail = s[lbs[len(lbs)-1]:]
+		}
+		if strings.IndexByte(tail, '\\') >= 0 {
+			return lenmsg, labels, ErrBuf
+		}
 	}
+
 	// If we did compression and we find something add the pointer here
 	if pointer != -1 {
 		// We have two bytes (14 bits) to put the pointer in
-		// if msg == nil, we will never do compression
-		binary.BigEndian.PutUint16(msg[nameoffset:], uint16(pointer^0xC000))
-		off = nameoffset + 1
-		goto End
+		if off+2 > lenmsg {
+			return lenmsg, labels, ErrBuf
+		}
+		if msg != nil {
+			binary.BigEndian.PutUint16(msg[off:], uint16(pointer^0xC000))
+		}
+		return off + 2, labels, nil
 	}
 	if msg != nil && off < len(msg) {
 		msg[off] = 0
 	}
-End:
-	off++
-	return off, labels, nil
+	return off + 1, labels, nil
 }
 
 // Unpack a domain name.
@@ -332,7 +443,17 @@ End:
 
 // UnpackDomainName unpacks a domain name into a string.
 func UnpackDomainName(msg []byte, off int) (string, int, error) {
-	s := make([]byte, 0, 64)
+	s, off1, err := appendDomainName(make([]byte, 0, 64), msg, off)
+	if err != nil && err != ErrLongDomain {
+		return "", off1, err
+	}
+	return string(s), off1, err
+}
+
+// appendDomainName appends the domain name at msg[off:] in presentation
+// format to s. If the name is too long it returns it with ErrLongDomain.
+func appendDomainName(s, msg []byte, off int) ([]byte, int, error) {
+	start := len(s)
 	off1 := 0
 	lenmsg := len(msg)
 	maxLen := maxDomainNameWireOctets
@@ -340,7 +461,7 @@ func UnpackDomainName(msg []byte, off int) (string, int, error) {
 Loop:
 	for {
 		if off >= lenmsg {
-			return "", lenmsg, ErrBuf
+			return s, lenmsg, ErrBuf
 		}
 		c := int(msg[off])
 		off++
@@ -352,7 +473,7 @@ Loop:
 			}
 			// literal string
 			if off+c > lenmsg {
-				return "", lenmsg, ErrBuf
+				return s, lenmsg, ErrBuf
 			}
 			for j := off; j < off+c; j++ {
 				switch b := msg[j]; b {
@@ -389,7 +510,7 @@ Loop:
 			// also, don't follow too many pointers --
 			// maybe there's a loop.
 			if off >= lenmsg {
-				return "", lenmsg, ErrBuf
+				return s, lenmsg, ErrBuf
 			}
 			c1 := msg[off]
 			off++
@@ -397,7 +518,7 @@ Loop:
 				off1 = off
 			}
 			if ptr++; ptr > 10 {
-				return "", lenmsg, &Error{err: "too many compression pointers"}
+				return s, lenmsg, &Error{err: "too many compression pointers"}
 			}
 			// pointer should guarantee that it advances and points forwards at least
 			// but the condition on previous three lines guarantees that it's
@@ -405,19 +526,19 @@ Loop:
 			off = (c^0xC0)<<8 | int(c1)
 		default:
 			// 0x80 and 0x40 are reserved
-			return "", lenmsg, ErrRdata
+			return s, lenmsg, ErrRdata
 		}
 	}
 	if ptr == 0 {
 		off1 = off
 	}
-	if len(s) == 0 {
-		s = []byte(".")
-	} else if len(s) >= maxLen {
+	if len(s) == start {
+		s = append(s, '.')
+	} else if len(s)-start >= maxLen {
 		// error if the name is too long, but don't throw it away
-		return string(s), lenmsg, ErrLongDomain
+		return s, lenmsg, ErrLongDomain
 	}
-	return string(s), off1, nil
+	return s, off1, nil
 }
 
 func packTxt(txt []string, msg []byte, offset int, tmp []byte) (int, error) {
@@ -560,6 +681,10 @@ func dddToByte(s []byte) byte {
 	return byte((s[0]-'0')*100 + (s[1]-'0')*10 + (s[2] - '0'))
 }
 
+func dddStringToByte(s string) byte {
+	return byte((s[0]-'0')*100 + (s[1]-'0')*10 + (s[2] - '0'))
+}
+
 // Helper function for packing and unpacking
 func intToBytes(i *big.Int, length int) []byte {
 	buf := i.Bytes()
@@ -574,6 +699,10 @@ func intToBytes(i *big.Int, length int) []byte {
 // PackRR packs a resource record rr into msg[off:].
 // See PackDomainName for documentation about the compression.
 func PackRR(rr RR, msg []byte, off int, compression map[string]int, compress bool) (off1 int, err error) {
+	return packRR(rr, msg, off, compressionMap{ext: compression}, compress)
+}
+
+func packRR(rr RR, msg []byte, off int, compression compressionMap, compress bool) (off1 int, err error) {
 	if rr == nil {
 		return len(msg), &Error{err: "nil rr"}
 	}
@@ -697,11 +826,12 @@ func (dns *Msg) PackBuffer(buf []byte) (msg []byte, err error) {
 	// We use a similar function in tsig.go's stripTsig.
 	var (
 		dh          Header
-		compression map[string]int
+		compression compressionMap
 	)
 
 	if dns.Compress {
-		compression = make(map[string]int) // Compression pointer mappings
+		compression.table = getCompressionTable() // Compression pointer mappings
+		defer putCompressionTable(compression.table)
 	}
 
 	if dns.Rcode < 0 || dns.Rcode > 0xFFF {
@@ -719,31 +849,7 @@ func (dns *Msg) PackBuffer(buf []byte) (msg []byte, err error) {
 
 	// Convert convenient Msg into wire-like Header.
 	dh.Id = dns.Id
-	dh.Bits = uint16(dns.Opcode)<<11 | uint16(dns.Rcode)
-	if dns.Response {
-		dh.Bits |= _QR
-	}
-	if dns.Authoritative {
-		dh.Bits |= _AA
-	}
-	if dns.Truncated {
-		dh.Bits |= _TC
-	}
-	if dns.RecursionDesired {
-		dh.Bits |= _RD
-	}
-	if dns.RecursionAvailable {
-		dh.Bits |= _RA
-	}
-	if dns.Zero {
-		dh.Bits |= _Z
-	}
-	if dns.AuthenticatedData {
-		dh.Bits |= _AD
-	}
-	if dns.CheckingDisabled {
-		dh.Bits |= _CD
-	}
+	dh.Bits = dns.MsgHdr.bits()
 
 	// Prepare variable sized arrays.
 	question := dns.Question
@@ -776,19 +882,19 @@ func (dns *Msg) PackBuffer(buf []byte) (msg []byte, err error) {
 		}
 	}
 	for i := 0; i < len(answer); i++ {
-		off, err = PackRR(answer[i], msg, off, compression, dns.Compress)
+		off, err = packRR(answer[i], msg, off, compression, dns.Compress)
 		if err != nil {
 			return nil, err
 		}
 	}
 	for i := 0; i < len(ns); i++ {
-		off, err = PackRR(ns[i], msg, off, compression, dns.Compress)
+		off, err = packRR(ns[i], msg, off, compression, dns.Compress)
 		if err != nil {
 			return nil, err
 		}
 	}
 	for i := 0; i < len(extra); i++ {
-		off, err = PackRR(extra[i], msg, off, compression, dns.Compress)
+		off, err = packRR(extra[i], msg, off, compression, dns.Compress)
 		if err != nil {
 			return nil, err
 		}
@@ -807,16 +913,7 @@ func (dns *Msg) Unpack(msg []byte) (err error) {
 	}
 
 	dns.Id = dh.Id
-	dns.Response = (dh.Bits & _QR) != 0
-	dns.Opcode = int(dh.Bits>>11) & 0xF
-	dns.Authoritative = (dh.Bits & _AA) != 0
-	dns.Truncated = (dh.Bits & _TC) != 0
-	dns.RecursionDesired = (dh.Bits & _RD) != 0
-	dns.RecursionAvailable = (dh.Bits & _RA) != 0
-	dns.Zero = (dh.Bits & _Z) != 0
-	dns.AuthenticatedData = (dh.Bits & _AD) != 0
-	dns.CheckingDisabled = (dh.Bits & _CD) != 0
-	dns.Rcode = int(dh.Bits & 0xF)
+	dns.MsgHdr.setBits(dh.Bits)
 
 	// If we are at the end of the message we should return *just* the
 	// header. This can still be useful to the caller. 9.9.9.9 sends these
@@ -928,7 +1025,8 @@ func compressedLen(dns *Msg, compress bool) int {
 	// We always return one more than needed.
 	l := 12 // Message header is always 12 bytes
 	if compress {
-		compression := map[string]int{}
+		compression := getCompressionTable()
+		defer putCompressionTable(compression)
 		for _, r := range dns.Question {
 			l += r.len()
 			compressionLenHelper(compression, r.Name)
@@ -962,7 +1060,7 @@ func compressedLen(dns *Msg, compress bool) int {
 	return l
 }
 
-func compressionLenSlice(len int, c map[string]int, rs []RR) int {
+func compressionLenSlice(len int, c *compressionTable, rs []RR) int {
 	var l int
 	for _, r := range rs {
 		if r == nil {
@@ -996,36 +1094,23 @@ func compressionLenSlice(len int, c map[string]int, rs []RR) int {
 	return l
 }
 
-// Put the parts of the name in the compression map.
-func compressionLenHelper(c map[string]int, s string) {
-	pref := ""
-	lbs := Split(s)
-	for j := len(lbs) - 1; j >= 0; j-- {
-		pref = s[lbs[j]:]
-		if _, ok := c[pref]; !ok {
-			c[pref] = len(pref)
-		}
-	}
+// Put the parts of the name in the compression table.
+func compressionLenHelper(c *compressionTable, s string) {
+	c.lbs = labelOffsets(s, c.lbs[:0])
+	c.suffixes(s, c.lbs)
+	c.insert(nil, len(c.keys))
 }
 
-// Look for each part in the compression map and returns its length,
-// keep on searching so we get the longest match.
-func compressionLenSearch(c map[string]int, s string) (int, bool) {
-	off := 0
-	end := false
-	if s == "" { // don't bork on bogus data
+// Look for each part in the compression table and returns the length of the
+// longest one found.
+func compressionLenSearch(c *compressionTable, s string) (int, bool) {
+	c.lbs = labelOffsets(s, c.lbs[:0])
+	c.suffixes(s, c.lbs)
+	i, _ := c.search()
+	if i < 0 {
 		return 0, false
 	}
-	for {
-		if _, ok := c[s[off:]]; ok {
-			return len(s[off:]), true
-		}
-		if end {
-			break
-		}
-		off, end = NextLabel(s, off)
-	}
-	return 0, false
+	return len(s) - c.lbs[i], true
 }
 
 // Copy returns a new RR which is a deep-copy of r.
@@ -1080,8 +1165,8 @@ func (dns *Msg) CopyTo(r1 *Msg) *Msg {
 	return r1
 }
 
-func (q *Question) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
-	off, err := PackDomainName(q.Name, msg, off, compression, compress)
+func (q *Question) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, _, err := packDomainName(q.Name, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -1122,7 +1207,7 @@ func unpackQuestion(msg []byte, off int) (Question, int, error) {
 	return q, off, err
 }
 
-func (dh *Header) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (dh *Header) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := packUint16(dh.Id, msg, off)
 	if err != nil {
 		return off, err
@@ -101,12 +101,12 @@ func unpackHeader(msg []byte, off int) (rr RR_Header, off1 int, truncmsg []byte,
 
 // pack packs an RR header, returning the offset to the end of the header.
 // See PackDomainName for documentation about the compression.
-func (hdr RR_Header) pack(msg []byte, off int, compression map[string]int, compress bool) (off1 int, err error) {
+func (hdr RR_Header) pack(msg []byte, off int, compression compressionMap, compress bool) (off1 int, err error) {
 	if off == len(msg) {
 		return off, nil
 	}
 
-	off, err = PackDomainName(hdr.Name, msg, off, compression, compress)
+	off, _, err = packDomainName(hdr.Name, msg, off, compression, compress)
 	if err != nil {
 		return len(msg), err
 	}
@@ -462,7 +462,58 @@ Option:
 		}
 		edns = append(edns, e)
 		off += int(optlen)
+	case EDNS0EDE:
+		e := new(EDNS0_EDE)
+		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
+			return nil, len(msg), err
+		}
+		edns = append(edns, e)
+		off += int(optlen)
+	case EDNS0CHAIN:
+		e := new(EDNS0_CHAIN)
+		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
+			return nil, len(msg), err
+		}
+		edns = append(edns, e)
+		off += int(optlen)
+	case EDNS0KEYTAG:
+		e := new(EDNS0_KEYTAG)
+		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
+			return nil, len(msg), err
+		}
+		edns = append(edns, e)
+		off += int(optlen)
+	case EDNS0CLIENTTAG:
+		e := new(EDNS0_CLIENTTAG)
+		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
+			return nil, len(msg), err
+		}
+		edns = append(edns, e)
+		off += int(optlen)
+	case EDNS0REPORTCHANNEL:
+		e := new(EDNS0_REPORTCHANNEL)
+		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
+			return nil, len(msg), err
+		}
+		edns = append(edns, e)
+		off += int(optlen)
+	case EDNS0ZONEVERSION:
+		e := new(EDNS0_ZONEVERSION)
+		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
+			return nil, len(msg), err
+		}
+		edns = append(edns, e)
+		off += int(optlen)
 	default:
+		if p, ok := privateOptions[code]; ok {
+			e := &EDNS0_PRIVATE{Code: code, Data: p.generator()}
+			if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
+				return nil, len(msg), err
+			}
+			edns = append(edns, e)
+			off += int(optlen)
+			break
+		}
 		e := new(EDNS0_LOCAL)
 		e.Code = code
 		if err := e.unpack(msg[off : off+int(optlen)]); err != nil {
@@ -625,13 +676,185 @@ func unpackDataDomainNames(msg []byte, off, end int) ([]string, int, error) {
 	return servers, off, nil
 }
 
-func packDataDomainNames(names []string, msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func packDataDomainNames(names []string, msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	var err error
 	for j := 0; j < len(names); j++ {
-		off, err = PackDomainName(names[j], msg, off, compression, false && compress)
+		off, _, err = packDomainName(names[j], msg, off, compression, false && compress)
+		if err != nil {
+			return len(msg), err
+		}
+	}
+	return off, nil
+}
+
+func packDataApl(data []APLPrefix, msg []byte, off int) (int, error) {
+	var err error
+	for i := range data {
+		off, err = packDataAplPrefix(&data[i], msg, off)
 		if err != nil {
 			return len(msg), err
 		}
 	}
 	return off, nil
 }
+
+func packDataAplPrefix(p *APLPrefix, msg []byte, off int) (int, error) {
+	ip := p.ip()
+	if len(ip) != len(p.Network.Mask) {
+		return len(msg), &Error{err: "address and mask lengths don't match"}
+	}
+
+	var err error
+	prefix, _ := p.Network.Mask.Size()
+	addr := ip.Mask(p.Network.Mask)[:(prefix+7)/8]
+
+	switch len(ip) {
+	case net.IPv4len:
+		off, err = packUint16(1, msg, off)
+	case net.IPv6len:
+		off, err = packUint16(2, msg, off)
+	default:
+		err = &Error{err: "unrecognized address family"}
+	}
+	if err != nil {
+		return len(msg), err
+	}
+
+	off, err = packUint8(uint8(prefix), msg, off)
+	if err != nil {
+		return len(msg), err
+	}
+
+	// The address part is sent without its trailing zero octets, RFC 3123
+	// section 4.
+	for len(addr) > 0 && addr[len(addr)-1] == 0 {
+		addr = addr[:len(addr)-1]
+	}
+	var n uint8
+	if p.Negation {
+		n = 0x80
+	}
+	off, err = packUint8(n|uint8(len(addr)), msg, off)
+	if err != nil {
+		return len(msg), err
+	}
+
+	if off+len(addr) > len(msg) {
+		return len(msg), &Error{err: "overflow packing APL prefix"}
+	}
+	off += copy(msg[off:], addr)
+
+	return off, nil
+}
+
+func unpackDataApl(msg []byte, off, end int) ([]APLPrefix, int, error) {
+	if end > len(msg) {
+		return nil, len(msg), &Error{err: "overflow unpacking APL prefix"}
+	}
+	var result []APLPrefix
+	for off < end {
+		prefix, off1, err := unpackDataAplPrefix(msg[:end], off)
+		if err != nil {
+			return nil, len(msg), err
+		}
+		off = off1
+		result = append(result, prefix)
+	}
+	return result, off, nil
+}
+
+func unpackDataAplPrefix(msg []byte, off int) (APLPrefix, int, error) {
+	family, off, err := unpackUint16(msg, off)
+	if err != nil {
+		return APLPrefix{}, len(msg), &Error{err: "overflow unpacking APL prefix"}
+	}
+	prefix, off, err := unpackUint8(msg, off)
+	if err != nil {
+		return APLPrefix{}, len(msg), &Error{err: "overflow unpacking APL prefix"}
+	}
+	nlen, off, err := unpackUint8(msg, off)
+	if err != nil {
+		return APLPrefix{}, len(msg), &Error{err: "overflow unpacking APL prefix"}
+	}
+
+	var ip []byte
+	switch family {
+	case 1:
+		ip = make([]byte, net.IPv4len)
+	case 2:
+		ip = make([]byte, net.IPv6len)
+	default:
+		return APLPrefix{}, len(msg), &Error{err: "unrecognized APL address family"}
+	}
+	if int(prefix) > 8*len(ip) {
+		return APLPrefix{}, len(msg), &Error{err: "APL prefix too long"}
+	}
+	afdlen := int(nlen & 0x7f)
+	if afdlen > len(ip) {
+		return APLPrefix{}, len(msg), &Error{err: "APL length too long"}
+	}
+	if off+afdlen > len(msg) {
+		return APLPrefix{}, len(msg), &Error{err: "overflow unpacking APL address"}
+	}
+	off += copy(ip, msg[off:off+afdlen])
+	if afdlen > 0 && ip[afdlen-1] == 0 {
+		return APLPrefix{}, len(msg), &Error{err: "trailing zero octets in APL address"}
+	}
+	ipnet := net.IPNet{
+		IP:   ip,
+		Mask: net.CIDRMask(int(prefix), 8*len(ip)),
+	}
+	if !ipnet.IP.Mask(ipnet.Mask).Equal(ipnet.IP) {
+		return APLPrefix{}, len(msg), &Error{err: "extra APL address bits"}
+	}
+
+	return APLPrefix{
+		Negation: (nlen & 0x80) != 0,
+		Network:  ipnet,
+	}, off, nil
+}
+
+// packIPSECGateway packs the gateway of an IPSECKEY or AMTRELAY RR, which is
+// addr or host depending on the gateway type typ.
+func packIPSECGateway(addr net.IP, host string, msg []byte, off int, typ uint8, compression compressionMap, compress bool) (int, error) {
+	switch typ {
+	case IPSECGatewayNone:
+		return off, nil
+	case IPSECGatewayIPv4:
+		if addr.To4() == nil {
+			return len(msg), &Error{err: "bad gateway address: " + addr.String()}
+		}
+		return packDataA(addr, msg, off)
+	case IPSECGatewayIPv6:
+		if len(addr) != net.IPv6len || addr.To4() != nil {
+			return len(msg), &Error{err: "bad gateway address: " + addr.String()}
+		}
+		return packDataAAAA(addr, msg, off)
+	case IPSECGatewayHost:
+		off, _, err := packDomainName(host, msg, off, compression, compress)
+		return off, err
+	}
+	return len(msg), &Error{err: "bad gateway type: " + strconv.Itoa(int(typ))}
+}
+
+// unpackIPSECGateway unpacks the gateway of an IPSECKEY or AMTRELAY RR of
+// gateway type typ.
+func unpackIPSECGateway(msg []byte, off int, typ uint8) (net.IP, string, int, error) {
+	var (
+		addr net.IP
+		host string
+		err  error
+	)
+	switch typ {
+	case IPSECGatewayNone:
+	case IPSECGatewayIPv4:
+		addr, off, err = unpackDataA(msg, off)
+	case IPSECGatewayIPv6:
+		addr, off, err = unpackDataAAAA(msg, off)
+	case IPSECGatewayHost:
+		host, off, err = UnpackDomainName(msg, off)
+	default:
+		return nil, "", len(msg), &Error{err: "bad gateway type: " + strconv.Itoa(int(typ))}
+	}
+	return addr, host, off, err
+}
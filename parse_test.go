@@ -1424,6 +1424,158 @@ func TestParseCSYNC(t *testing.T) {
 	}
 }
 
+func TestParseZONEMD(t *testing.T) {
+	zonemds := map[string]string{
+		`example. 86400 IN ZONEMD 2018031900 1 1 ( c68090d90a7aed71 6bc459f9340e3d7c )`: `example.	86400	IN	ZONEMD	2018031900 1 1 c68090d90a7aed716bc459f9340e3d7c`,
+		`example. 86400 IN ZONEMD 2018031900 1 240 e2d523f654b9422a96c5a8f44607bbee`:    `example.	86400	IN	ZONEMD	2018031900 1 240 e2d523f654b9422a96c5a8f44607bbee`,
+	}
+	for s, o := range zonemds {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Error("failed to parse RR: ", err)
+			continue
+		}
+		if rr.String() != o {
+			t.Errorf("`%s' should be equal to\n`%s', but is     `%s'", s, o, rr.String())
+		}
+	}
+}
+
+func TestParseAPL(t *testing.T) {
+	apls := map[string]string{
+		"foo.example. IN APL 1:192.168.32.0/21 !1:192.168.38.0/28": "foo.example.\t3600\tIN\tAPL\t1:192.168.32.0/21 !1:192.168.38.0/28",
+		"foo.example. IN APL 1:224.0.0.0/4 2:FF00:0:0:0:0:0:0:0/8": "foo.example.\t3600\tIN\tAPL\t1:224.0.0.0/4 2:ff00::/8",
+		"foo.example. IN APL 2:::ffff:192.0.2.0/120":               "foo.example.\t3600\tIN\tAPL\t2:::ffff:192.0.2.0/120",
+		"foo.example. IN APL": "foo.example.\t3600\tIN\tAPL\t",
+	}
+	for s, o := range apls {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Error("failed to parse RR: ", err)
+			continue
+		}
+		if rr.String() != o {
+			t.Errorf("`%s' should be equal to\n`%s', but is     `%s'", s, o, rr.String())
+		}
+		if rr1, err := NewRR(rr.String()); err != nil || rr1.String() != o {
+			t.Errorf("failed to parse `%s' again: %v", o, err)
+		}
+	}
+
+	for _, s := range []string{
+		"foo.example. IN APL 1:192.168.32.1/21",
+		"foo.example. IN APL 1:192.168.32.0/33",
+		"foo.example. IN APL 1:2001:db8::/32",
+		"foo.example. IN APL 2:192.0.2.0/24",
+		"foo.example. IN APL 3:192.0.2.0/24",
+		"foo.example. IN APL 1:192.0.2.0",
+		"foo.example. IN APL !192.0.2.0/24",
+	} {
+		if rr, err := NewRR(s); err == nil {
+			t.Errorf("parsed %q as %q", s, rr)
+		}
+	}
+}
+
+func TestParseIPSECKEY(t *testing.T) {
+	const key = "AQNRU3mG7TVTO2BkR47usntb102uFJtugbo6BSGvgqt4AQ=="
+	keys := map[string]string{
+		"38.2.0.192.in-addr.arpa. 7200 IN IPSECKEY ( 10 0 2 . " + key + " )":                        "38.2.0.192.in-addr.arpa.\t7200\tIN\tIPSECKEY\t10 0 2 . " + key,
+		"38.2.0.192.in-addr.arpa. 7200 IN IPSECKEY ( 10 1 2 192.0.2.38 " + key + " )":               "38.2.0.192.in-addr.arpa.\t7200\tIN\tIPSECKEY\t10 1 2 192.0.2.38 " + key,
+		"38.2.0.192.in-addr.arpa. 7200 IN IPSECKEY ( 10 2 2 2001:0DB8:0:8002::2000:1 " + key + " )": "38.2.0.192.in-addr.arpa.\t7200\tIN\tIPSECKEY\t10 2 2 2001:db8:0:8002::2000:1 " + key,
+		"38.2.0.192.in-addr.arpa. 7200 IN IPSECKEY ( 10 3 2 mygateway.example.com. " + key + " )":   "38.2.0.192.in-addr.arpa.\t7200\tIN\tIPSECKEY\t10 3 2 mygateway.example.com. " + key,
+		"38.2.0.192.in-addr.arpa. 7200 IN IPSECKEY 10 3 2 mygateway " + key:                         "38.2.0.192.in-addr.arpa.\t7200\tIN\tIPSECKEY\t10 3 2 mygateway. " + key,
+	}
+	for s, o := range keys {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Error("failed to parse RR: ", err)
+			continue
+		}
+		if rr.String() != o {
+			t.Errorf("`%s' should be equal to\n`%s', but is     `%s'", s, o, rr.String())
+		}
+	}
+
+	for _, s := range []string{
+		"example. IN IPSECKEY 10 0 2 192.0.2.38 " + key,
+		"example. IN IPSECKEY 10 1 2 2001:db8::1 " + key,
+		"example. IN IPSECKEY 10 2 2 192.0.2.38 " + key,
+		"example. IN IPSECKEY 10 4 2 . " + key,
+	} {
+		if rr, err := NewRR(s); err == nil {
+			t.Errorf("parsed %q as %q", s, rr)
+		}
+	}
+}
+
+func TestParseAMTRELAY(t *testing.T) {
+	relays := map[string]string{
+		"example.net. IN AMTRELAY 10 0 0 .":                  "example.net.\t3600\tIN\tAMTRELAY\t10 0 0 .",
+		"example.net. IN AMTRELAY 10 1 1 203.0.113.15":       "example.net.\t3600\tIN\tAMTRELAY\t10 1 1 203.0.113.15",
+		"example.net. IN AMTRELAY 10 0 2 2001:db8::15":       "example.net.\t3600\tIN\tAMTRELAY\t10 0 2 2001:db8::15",
+		"example.net. IN AMTRELAY 128 1 3 amtrelays.example": "example.net.\t3600\tIN\tAMTRELAY\t128 1 3 amtrelays.example.",
+	}
+	for s, o := range relays {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Error("failed to parse RR: ", err)
+			continue
+		}
+		if rr.String() != o {
+			t.Errorf("`%s' should be equal to\n`%s', but is     `%s'", s, o, rr.String())
+		}
+	}
+
+	for _, s := range []string{
+		"example.net. IN AMTRELAY 10 2 0 .",
+		"example.net. IN AMTRELAY 10 0 1 2001:db8::15",
+		"example.net. IN AMTRELAY 10 0 4 .",
+	} {
+		if rr, err := NewRR(s); err == nil {
+			t.Errorf("parsed %q as %q", s, rr)
+		}
+	}
+}
+
+func TestParseDSYNC(t *testing.T) {
+	dsyncs := map[string]string{
+		"_dsync.example. IN DSYNC CDS 1 5359 rr-endpoint.example.": "_dsync.example.\t3600\tIN\tDSYNC\tCDS 1 5359 rr-endpoint.example.",
+		"_dsync.example. IN DSYNC TYPE59 1 5359 rr-endpoint":       "_dsync.example.\t3600\tIN\tDSYNC\tCDS 1 5359 rr-endpoint.example.",
+		"_dsync.example. IN DSYNC TYPE65000 2 53 .":                "_dsync.example.\t3600\tIN\tDSYNC\tTYPE65000 2 53 .",
+	}
+	for s, o := range dsyncs {
+		rr, err := NewRR("$ORIGIN example.\n" + s)
+		if err != nil {
+			t.Error("failed to parse RR: ", err)
+			continue
+		}
+		if rr.String() != o {
+			t.Errorf("`%s' should be equal to\n`%s', but is     `%s'", s, o, rr.String())
+		}
+	}
+	if rr, err := NewRR("_dsync.example. IN DSYNC FOO 1 5359 rr-endpoint.example."); err == nil {
+		t.Errorf("parsed a DSYNC record with a bad type: %s", rr)
+	}
+}
+
+func TestParseRESINFOWALLET(t *testing.T) {
+	txts := map[string]string{
+		`resolver.example. IN RESINFO qnamemin exterr=15,16,17 infourl=https://resolver.example/guide`: "resolver.example.\t3600\tIN\tRESINFO\t\"qnamemin\" \"exterr=15,16,17\" \"infourl=https://resolver.example/guide\"",
+		`example. IN WALLET "BTC" "bc1qexample"`:                                                       "example.\t3600\tIN\tWALLET\t\"BTC\" \"bc1qexample\"",
+	}
+	for s, o := range txts {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Error("failed to parse RR: ", err)
+			continue
+		}
+		if rr.String() != o {
+			t.Errorf("`%s' should be equal to\n`%s', but is     `%s'", s, o, rr.String())
+		}
+	}
+}
+
 func TestParseBadNAPTR(t *testing.T) {
 	// Should look like: mplus.ims.vodafone.com.	3600	IN	NAPTR	10 100 "S" "SIP+D2U" "" _sip._udp.mplus.ims.vodafone.com.
 	naptr := `mplus.ims.vodafone.com.	3600	IN	NAPTR	10 100 S SIP+D2U  _sip._udp.mplus.ims.vodafone.com.`
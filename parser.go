@@ -0,0 +1,379 @@
+package dns
+
+// A streaming parser of messages in wire format.
+
+// The sections of a message, in the order they are in.
+const (
+	sectionQuestion = iota
+	sectionAnswer
+	sectionAuthority
+	sectionAdditional
+	sectionDone
+)
+
+// Parser parses a message in wire format section by section, without
+// unpacking it into a Msg. The records are returned as a RawHeader, and their
+// rdata is only looked at if asked for, so parsing allocates no memory unless
+// one of this package's RR types is asked for with RR.
+//
+// The sections must be parsed in order. Asking for a record in a later section
+// skips what is left of the earlier ones, asking for one in a section that is
+// done returns ErrSectionDone. Basic use pattern for looking at the A records
+// in the answer section:
+//
+//	var p dns.Parser
+//	if _, err := p.Start(msg); err != nil {
+//		return err
+//	}
+//	for {
+//		h, err := p.AnswerHeader()
+//		if err == dns.ErrSectionDone {
+//			break
+//		}
+//		if err != nil {
+//			return err
+//		}
+//		if h.Rrtype == dns.TypeA && h.Name.Equal("www.example.org.") {
+//			a, err := p.A()
+//			...
+//		}
+//	}
+type Parser struct {
+	msg     []byte
+	off     int
+	section int
+	left    int // the number of records left in the section
+	counts  [4]uint16
+
+	// The record of the last RawHeader, while its rdata has not been read.
+	pending bool
+	rrOff   int
+	rrtype  uint16
+	rdOff   int
+	rdEnd   int
+}
+
+// RawName is a domain name in a message parsed by a Parser, possibly
+// compressed. It refers to the message, so it is only valid as long as the
+// message is not changed.
+type RawName struct {
+	msg []byte
+	off int
+}
+
+// RawQuestion is a question returned by a Parser.
+type RawQuestion struct {
+	Name   RawName
+	Qtype  uint16
+	Qclass uint16
+}
+
+// RawHeader is the header of a record returned by a Parser.
+type RawHeader struct {
+	Name     RawName
+	Rrtype   uint16
+	Class    uint16
+	Ttl      uint32
+	Rdlength uint16
+}
+
+// Start starts parsing msg and returns its header. The section counts of the
+// header are not trusted, a message with fewer records is parsed until its
+// end.
+func (p *Parser) Start(msg []byte) (MsgHdr, error) {
+	*p = Parser{msg: msg}
+	dh, off, err := unpackMsgHdr(msg, 0)
+	if err != nil {
+		return MsgHdr{}, err
+	}
+	p.off = off
+	p.counts = [4]uint16{dh.Qdcount, dh.Ancount, dh.Nscount, dh.Arcount}
+	p.left = int(dh.Qdcount)
+
+	h := MsgHdr{Id: dh.Id}
+	h.setBits(dh.Bits)
+	return h, nil
+}
+
+// Question returns the next question of the message.
+func (p *Parser) Question() (RawQuestion, error) {
+	if err := p.advance(sectionQuestion); err != nil {
+		return RawQuestion{}, err
+	}
+	if p.left == 0 || p.off == len(p.msg) {
+		return RawQuestion{}, ErrSectionDone
+	}
+	q := RawQuestion{Name: RawName{p.msg, p.off}}
+	off, err := skipDomainName(p.msg, p.off)
+	if err != nil {
+		return RawQuestion{}, err
+	}
+	if q.Qtype, off, err = unpackUint16(p.msg, off); err != nil {
+		return RawQuestion{}, err
+	}
+	if q.Qclass, off, err = unpackUint16(p.msg, off); err != nil {
+		return RawQuestion{}, err
+	}
+	p.off = off
+	p.left--
+	return q, nil
+}
+
+// AnswerHeader returns the header of the next record in the answer section.
+func (p *Parser) AnswerHeader() (RawHeader, error) { return p.header(sectionAnswer) }
+
+// AuthorityHeader returns the header of the next record in the authority
+// section.
+func (p *Parser) AuthorityHeader() (RawHeader, error) { return p.header(sectionAuthority) }
+
+// AdditionalHeader returns the header of the next record in the additional
+// section.
+func (p *Parser) AdditionalHeader() (RawHeader, error) { return p.header(sectionAdditional) }
+
+// SkipQuestions skips the questions that have not been parsed.
+func (p *Parser) SkipQuestions() error { return p.skip(sectionQuestion) }
+
+// SkipAnswers skips the records of the answer section that have not been
+// parsed.
+func (p *Parser) SkipAnswers() error { return p.skip(sectionAnswer) }
+
+// SkipAuthorities skips the records of the authority section that have not
+// been parsed.
+func (p *Parser) SkipAuthorities() error { return p.skip(sectionAuthority) }
+
+// SkipAdditionals skips the records of the additional section that have not
+// been parsed.
+func (p *Parser) SkipAdditionals() error { return p.skip(sectionAdditional) }
+
+// Rdata returns the rdata of the record of the last header, which refers to
+// the message. The names in it may be compressed.
+func (p *Parser) Rdata() ([]byte, error) {
+	if !p.pending {
+		return nil, &Error{err: "no record to read rdata from"}
+	}
+	p.pending = false
+	p.off = p.rdEnd
+	return p.msg[p.rdOff:p.rdEnd], nil
+}
+
+// A returns the address of the A record of the last header.
+func (p *Parser) A() ([4]byte, error) {
+	var a [4]byte
+	if p.pending && p.rrtype != TypeA {
+		return a, &Error{err: "not an A record"}
+	}
+	rd, err := p.Rdata()
+	if err != nil {
+		return a, err
+	}
+	if len(rd) != len(a) {
+		return a, ErrRdata
+	}
+	copy(a[:], rd)
+	return a, nil
+}
+
+// AAAA returns the address of the AAAA record of the last header.
+func (p *Parser) AAAA() ([16]byte, error) {
+	var aaaa [16]byte
+	if p.pending && p.rrtype != TypeAAAA {
+		return aaaa, &Error{err: "not an AAAA record"}
+	}
+	rd, err := p.Rdata()
+	if err != nil {
+		return aaaa, err
+	}
+	if len(rd) != len(aaaa) {
+		return aaaa, ErrRdata
+	}
+	copy(aaaa[:], rd)
+	return aaaa, nil
+}
+
+// RR unpacks the record of the last header into one of this package's RR
+// types, as Msg.Unpack does.
+func (p *Parser) RR() (RR, error) {
+	if !p.pending {
+		return nil, &Error{err: "no record to unpack"}
+	}
+	rr, _, err := UnpackRR(p.msg, p.rrOff)
+	if err != nil {
+		return nil, err
+	}
+	p.pending = false
+	p.off = p.rdEnd
+	return rr, nil
+}
+
+// advance moves p to section sec, skipping what is left of the sections
+// before it.
+func (p *Parser) advance(sec int) error {
+	if p.msg == nil {
+		return &Error{err: "parser not started"}
+	}
+	if p.section > sec {
+		return ErrSectionDone
+	}
+	for p.section < sec {
+		if err := p.skipLeft(); err != nil {
+			return err
+		}
+		p.section++
+		if p.section < sectionDone {
+			p.left = int(p.counts[p.section])
+		}
+	}
+	return nil
+}
+
+// skip skips the records left in section sec.
+func (p *Parser) skip(sec int) error {
+	if err := p.advance(sec); err != nil {
+		return err
+	}
+	return p.skipLeft()
+}
+
+// skipLeft skips the records left in the current section.
+func (p *Parser) skipLeft() error {
+	if p.pending {
+		p.pending = false
+		p.off = p.rdEnd
+	}
+	for ; p.left > 0 && p.off < len(p.msg); p.left-- {
+		off, err := skipDomainName(p.msg, p.off)
+		if err != nil {
+			return err
+		}
+		if p.section == sectionQuestion {
+			off += 4
+		} else {
+			if off+10 > len(p.msg) {
+				return ErrBuf
+			}
+			rdlength, _, _ := unpackUint16(p.msg, off+8)
+			off += 10 + int(rdlength)
+		}
+		if off > len(p.msg) {
+			return ErrBuf
+		}
+		p.off = off
+	}
+	p.left = 0
+	return nil
+}
+
+// header returns the header of the next record in section sec.
+func (p *Parser) header(sec int) (RawHeader, error) {
+	if err := p.advance(sec); err != nil {
+		return RawHeader{}, err
+	}
+	if p.pending {
+		p.pending = false
+		p.off = p.rdEnd
+	}
+	if p.left == 0 || p.off == len(p.msg) {
+		return RawHeader{}, ErrSectionDone
+	}
+	h := RawHeader{Name: RawName{p.msg, p.off}}
+	off, err := skipDomainName(p.msg, p.off)
+	if err != nil {
+		return RawHeader{}, err
+	}
+	if off+10 > len(p.msg) {
+		return RawHeader{}, ErrBuf
+	}
+	h.Rrtype, off, _ = unpackUint16(p.msg, off)
+	h.Class, off, _ = unpackUint16(p.msg, off)
+	h.Ttl, off, _ = unpackUint32(p.msg, off)
+	h.Rdlength, off, _ = unpackUint16(p.msg, off)
+	if off+int(h.Rdlength) > len(p.msg) {
+		return RawHeader{}, ErrBuf
+	}
+
+	p.pending = true
+	p.rrOff = p.off
+	p.rrtype = h.Rrtype
+	p.rdOff = off
+	p.rdEnd = off + int(h.Rdlength)
+	p.off = off
+	p.left--
+	return h, nil
+}
+
+// skipDomainName returns the offset after the domain name at msg[off:],
+// without following compression pointers.
+func skipDomainName(msg []byte, off int) (int, error) {
+	for {
+		if off >= len(msg) {
+			return len(msg), ErrBuf
+		}
+		c := int(msg[off])
+		off++
+		switch c & 0xC0 {
+		case 0x00:
+			if c == 0x00 {
+				return off, nil
+			}
+			off += c
+		case 0xC0:
+			if off >= len(msg) {
+				return len(msg), ErrBuf
+			}
+			return off + 1, nil
+		default:
+			// 0x80 and 0x40 are reserved
+			return len(msg), ErrRdata
+		}
+	}
+}
+
+// String returns the name in presentation format, the empty string if it can
+// not be unpacked.
+func (n RawName) String() string {
+	s, _, err := UnpackDomainName(n.msg, n.off)
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// AppendTo appends the name in presentation format to dst, like String does,
+// and returns the extended buffer.
+func (n RawName) AppendTo(dst []byte) ([]byte, error) {
+	s, _, err := appendDomainName(dst, n.msg, n.off)
+	if err != nil {
+		return dst, err
+	}
+	return s, nil
+}
+
+// Equal reports whether the name is equal to name, which is in presentation
+// format and may lack the closing dot. Like all domain names they are compared
+// case insensitively.
+func (n RawName) Equal(name string) bool {
+	var buf [4 * maxDomainNameWireOctets]byte
+	s, err := n.AppendTo(buf[:0])
+	if err != nil {
+		return false
+	}
+	if !IsFqdn(name) && len(s) > 1 {
+		s = s[:len(s)-1]
+	}
+	if len(s) != len(name) {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		a, b := s[i], name[i]
+		if 'A' <= a && a <= 'Z' {
+			a += 'a' - 'A'
+		}
+		if 'A' <= b && b <= 'Z' {
+			b += 'a' - 'A'
+		}
+		if a != b {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,181 @@
+package dns
+
+import (
+	"net"
+	"testing"
+)
+
+func parserTestMsg(t *testing.T) []byte {
+	m := new(Msg)
+	m.SetQuestion("www.Example.org.", TypeA)
+	m.Response = true
+	m.Rcode = RcodeSuccess
+	m.Answer = []RR{
+		testRR("www.Example.org. 300 IN CNAME web.example.org."),
+		testRR("web.example.org. 300 IN A 192.0.2.1"),
+		testRR("web.example.org. 300 IN A 192.0.2.2"),
+	}
+	m.Ns = []RR{testRR("example.org. 300 IN NS ns.example.org.")}
+	m.Extra = []RR{testRR("ns.example.org. 300 IN AAAA 2001:db8::53")}
+	m.SetEdns0(4096, true)
+	m.Compress = true
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestParser(t *testing.T) {
+	buf := parserTestMsg(t)
+
+	var p Parser
+	h, err := p.Start(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !h.Response || h.Rcode != RcodeSuccess || !h.RecursionDesired {
+		t.Errorf("got header %v", &h)
+	}
+	q, err := p.Question()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Name.Equal("www.example.org") || q.Name.String() != "www.Example.org." || q.Qtype != TypeA {
+		t.Errorf("got question %s %d", q.Name, q.Qtype)
+	}
+	if _, err := p.Question(); err != ErrSectionDone {
+		t.Errorf("got error %v after the last question", err)
+	}
+
+	var addrs []net.IP
+	for {
+		h, err := p.AnswerHeader()
+		if err == ErrSectionDone {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.Rrtype != TypeA {
+			continue // the rdata is skipped
+		}
+		if !h.Name.Equal("WEB.example.org.") || h.Ttl != 300 || h.Rdlength != 4 {
+			t.Errorf("got header %s %d %d", h.Name, h.Ttl, h.Rdlength)
+		}
+		a, err := p.A()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addrs = append(addrs, net.IP(a[:]))
+	}
+	if len(addrs) != 2 || !addrs[1].Equal(net.ParseIP("192.0.2.2")) {
+		t.Errorf("got addresses %v", addrs)
+	}
+
+	// The authority section is skipped.
+	h1, err := p.AdditionalHeader()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.A(); err == nil {
+		t.Error("got an A record from an AAAA record")
+	}
+	aaaa, err := p.AAAA()
+	if err != nil || !net.IP(aaaa[:]).Equal(net.ParseIP("2001:db8::53")) || h1.Name.String() != "ns.example.org." {
+		t.Errorf("got AAAA %v for %s: %v", net.IP(aaaa[:]), h1.Name, err)
+	}
+	if _, err := p.AdditionalHeader(); err != nil {
+		t.Fatal(err)
+	}
+	rr, err := p.RR()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opt, ok := rr.(*OPT); !ok || !opt.Do() || opt.UDPSize() != 4096 {
+		t.Errorf("got %v for the OPT RR", rr)
+	}
+	if _, err := p.AdditionalHeader(); err != ErrSectionDone {
+		t.Errorf("got error %v after the last record", err)
+	}
+	if _, err := p.AuthorityHeader(); err != ErrSectionDone {
+		t.Errorf("got error %v for a skipped section", err)
+	}
+
+	// The names in the rdata are compressed.
+	p.Start(buf)
+	p.SkipQuestions()
+	p.AnswerHeader()
+	rd, err := p.Rdata()
+	if err != nil || len(rd) < 2 || rd[len(rd)-2]&0xC0 != 0xC0 {
+		t.Errorf("got rdata %v: %v", rd, err)
+	}
+}
+
+func TestParserAllocs(t *testing.T) {
+	buf := parserTestMsg(t)
+	var p Parser
+	n := testing.AllocsPerRun(100, func() {
+		p.Start(buf)
+		q, _ := p.Question()
+		if !q.Name.Equal("www.example.org.") {
+			t.Fatal("question does not match")
+		}
+		for {
+			h, err := p.AnswerHeader()
+			if err != nil {
+				break
+			}
+			if h.Rrtype == TypeA {
+				p.A()
+			}
+		}
+		p.SkipAuthorities()
+		p.SkipAdditionals()
+	})
+	if n != 0 {
+		t.Errorf("got %.1f allocations, want none", n)
+	}
+}
+
+func TestParserTruncated(t *testing.T) {
+	buf := parserTestMsg(t)
+	var p Parser
+	p.Start(buf)
+	if err := p.SkipAnswers(); err != nil {
+		t.Fatal(err)
+	}
+	answersEnd := p.off
+
+	// Parsing a truncated message ends with ErrSectionDone or another error,
+	// never with a panic.
+	for i := 0; i < len(buf); i++ {
+		var p Parser
+		if _, err := p.Start(buf[:i]); err != nil {
+			if i >= headerSize {
+				t.Errorf("%d: got error %v for the header", i, err)
+			}
+			continue
+		}
+		n := 0
+		for {
+			if _, err := p.Question(); err != nil {
+				break
+			}
+			n++
+		}
+		for {
+			if _, err := p.AnswerHeader(); err != nil {
+				break
+			}
+			if _, err := p.RR(); err != nil {
+				t.Errorf("%d: got error %v for a record that is not truncated", i, err)
+			}
+			n++
+		}
+		p.SkipAdditionals()
+		if i < answersEnd && n == 4 {
+			t.Errorf("%d: parsed all of a truncated message", i)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package dns
+
+import "strings"
+
+// PrivateOptionData is an interface used for implementing EDNS0 options that
+// are not built in, like the ones in the local range EDNS0LOCALSTART to
+// EDNS0LOCALEND. Also see dns.PrivateOptionHandle and
+// dns.PrivateOptionHandleRemove.
+type PrivateOptionData interface {
+	// String returns the text presentation of the option data.
+	String() string
+	// Pack returns the option data in wire format.
+	Pack() ([]byte, error)
+	// Unpack sets the option data from b, which is all of it.
+	Unpack(b []byte) error
+}
+
+// EDNS0_PRIVATE is an EDNS0 option with a PrivateOptionData user-defined
+// type. Options with a code registered with PrivateOptionHandle are unpacked
+// into one, with Data made by the generator of the code.
+type EDNS0_PRIVATE struct {
+	Code uint16
+	Data PrivateOptionData
+}
+
+// Option implements the EDNS0 interface.
+func (e *EDNS0_PRIVATE) Option() uint16        { return e.Code }
+func (e *EDNS0_PRIVATE) String() string        { return e.Data.String() }
+func (e *EDNS0_PRIVATE) pack() ([]byte, error) { return e.Data.Pack() }
+func (e *EDNS0_PRIVATE) unpack(b []byte) error { return e.Data.Unpack(b) }
+
+type privateOption struct {
+	name      string
+	generator func() PrivateOptionData
+}
+
+// privateOptions has the EDNS0 options registered with PrivateOptionHandle.
+var privateOptions = map[uint16]privateOption{}
+
+// PrivateOptionHandle registers a private EDNS0 option. It requires the name
+// and code of the option and a generator function for its data as argument.
+// Options with the code are unpacked into an EDNS0_PRIVATE. The code must not
+// be that of a built-in option, like EDNS0NSID, those are always unpacked
+// into their own type.
+func PrivateOptionHandle(name string, code uint16, generator func() PrivateOptionData) {
+	privateOptions[code] = privateOption{strings.ToUpper(name), generator}
+}
+
+// PrivateOptionHandleRemove removes the registration of a private EDNS0
+// option.
+func PrivateOptionHandleRemove(code uint16) {
+	delete(privateOptions, code)
+}
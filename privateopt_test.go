@@ -0,0 +1,81 @@
+package dns_test
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+const EDNS0TENANT = dns.EDNS0LOCALSTART + 1
+
+// An in-house option with the tenant a query is for.
+type TenantTag struct {
+	ID uint32
+}
+
+func NewTenantTag() dns.PrivateOptionData { return new(TenantTag) }
+
+func (t *TenantTag) String() string { return "tenant " + strconv.Itoa(int(t.ID)) }
+
+func (t *TenantTag) Pack() ([]byte, error) {
+	b := make([]byte, 4)
+	binary.BigEndian.PutUint32(b, t.ID)
+	return b, nil
+}
+
+func (t *TenantTag) Unpack(b []byte) error {
+	if len(b) != 4 {
+		return errors.New("bad tenant tag length")
+	}
+	t.ID = binary.BigEndian.Uint32(b)
+	return nil
+}
+
+func TestPrivateOption(t *testing.T) {
+	m := new(dns.Msg)
+	m.SetQuestion("example.org.", dns.TypeA)
+	m.SetEdns0(4096, false)
+	opt := m.IsEdns0()
+	opt.Option = append(opt.Option, &dns.EDNS0_PRIVATE{Code: EDNS0TENANT, Data: &TenantTag{ID: 42}})
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Without registration the option is unpacked as a local one.
+	m1 := new(dns.Msg)
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m1.IsEdns0().Option[0].(*dns.EDNS0_LOCAL); !ok {
+		t.Errorf("got %T for an unregistered option", m1.IsEdns0().Option[0])
+	}
+
+	dns.PrivateOptionHandle("tenant", EDNS0TENANT, NewTenantTag)
+	defer dns.PrivateOptionHandleRemove(EDNS0TENANT)
+
+	if err := m1.Unpack(buf); err != nil {
+		t.Fatal(err)
+	}
+	e, ok := m1.IsEdns0().Option[0].(*dns.EDNS0_PRIVATE)
+	if !ok {
+		t.Fatalf("got %T for a registered option", m1.IsEdns0().Option[0])
+	}
+	if tag, ok := e.Data.(*TenantTag); !ok || tag.ID != 42 || e.Option() != EDNS0TENANT {
+		t.Errorf("unpacked %v as %v", opt.Option[0], e)
+	}
+	if !strings.Contains(m1.IsEdns0().String(), "; TENANT: tenant 42") {
+		t.Errorf("got %q", m1.IsEdns0().String())
+	}
+
+	// Unpack errors of the option are those of the message.
+	buf[len(buf)-9]--   // rdlength
+	buf[len(buf)-5] = 3 // option length
+	if err := m1.Unpack(buf[:len(buf)-1]); err == nil || err.Error() != "bad tenant tag length" {
+		t.Errorf("got error %v for a bad option", err)
+	}
+}
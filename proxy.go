@@ -0,0 +1,261 @@
+package dns
+
+// Support for the HAProxy PROXY protocol, see
+// https://www.haproxy.org/download/1.8/doc/proxy-protocol.txt
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// ProxyProtocol configures a Server to accept HAProxy PROXY protocol headers,
+// as prepended by layer 4 load balancers, from a set of trusted sources. The
+// client address carried in the header is then returned by
+// ResponseWriter.RemoteAddr.
+//
+// TCP connections, including DNS over TLS, may use version 1 or 2 of the
+// protocol, UDP messages may only use version 2. A connection or message from
+// an untrusted source that starts with a PROXY header, or one from a trusted
+// source with a malformed header, is rejected.
+type ProxyProtocol struct {
+	// Trusted lists the networks allowed to send PROXY headers.
+	Trusted []*net.IPNet
+	// Optional allows trusted sources to omit the header, the connection or message is then
+	// served with its own source address. By default the header is required from trusted sources.
+	Optional bool
+}
+
+var (
+	proxyV1Prefix    = []byte("PROXY ")
+	proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+const (
+	proxyV1MaxLen    = 107 // including the CRLF
+	proxyV2HeaderLen = 16  // signature, version and command, family and length
+)
+
+// trusts returns true if a is one of the trusted sources.
+func (p *ProxyProtocol) trusts(a net.Addr) bool {
+	var ip net.IP
+	switch a := a.(type) {
+	case *net.TCPAddr:
+		ip = a.IP
+	case *net.UDPAddr:
+		ip = a.IP
+	default:
+		return false
+	}
+	for _, n := range p.Trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseUDP strips the PROXY header from the UDP message m received from src
+// and returns the remainder of the message and the address of the client.
+func (p *ProxyProtocol) parseUDP(m []byte, src net.Addr) ([]byte, net.Addr, error) {
+	hasHeader := bytes.HasPrefix(m, proxyV2Signature)
+	if !p.trusts(src) {
+		if hasHeader {
+			return nil, nil, &Error{err: "PROXY header from untrusted source"}
+		}
+		return m, src, nil
+	}
+	if !hasHeader {
+		if p.Optional {
+			return m, src, nil
+		}
+		return nil, nil, &Error{err: "missing PROXY header"}
+	}
+	if len(m) < proxyV2HeaderLen {
+		return nil, nil, &Error{err: "short PROXY header"}
+	}
+	n := proxyV2HeaderLen + int(binary.BigEndian.Uint16(m[14:]))
+	if len(m) < n {
+		return nil, nil, &Error{err: "short PROXY header"}
+	}
+	a, err := parseProxyV2(m[:n], src)
+	if err != nil {
+		return nil, nil, err
+	}
+	return m[n:], a, nil
+}
+
+// parseProxyV1 parses the version 1 header line, which must include the
+// trailing CRLF. If the header carries no address, src is returned.
+func parseProxyV1(line []byte, src net.Addr) (net.Addr, error) {
+	if len(line) > proxyV1MaxLen || !bytes.HasPrefix(line, proxyV1Prefix) || !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, &Error{err: "bad PROXY v1 header"}
+	}
+	f := strings.Split(string(line[len(proxyV1Prefix):len(line)-2]), " ")
+	if f[0] == "UNKNOWN" {
+		return src, nil
+	}
+	if len(f) != 5 || (f[0] != "TCP4" && f[0] != "TCP6") {
+		return nil, &Error{err: "bad PROXY v1 header"}
+	}
+	ip := net.ParseIP(f[1])
+	if ip == nil || net.ParseIP(f[2]) == nil || (ip.To4() != nil) != (f[0] == "TCP4") {
+		return nil, &Error{err: "bad PROXY v1 address"}
+	}
+	port, err := strconv.ParseUint(f[3], 10, 16)
+	if err != nil {
+		return nil, &Error{err: "bad PROXY v1 port"}
+	}
+	if _, err := strconv.ParseUint(f[4], 10, 16); err != nil {
+		return nil, &Error{err: "bad PROXY v1 port"}
+	}
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// parseProxyV2 parses the complete version 2 header in b, including any
+// TLVs, which are ignored. If the header carries no address, as is the
+// case for the LOCAL command, src is returned.
+func parseProxyV2(b []byte, src net.Addr) (net.Addr, error) {
+	if len(b) < proxyV2HeaderLen || !bytes.HasPrefix(b, proxyV2Signature) {
+		return nil, &Error{err: "bad PROXY v2 header"}
+	}
+	if b[12]>>4 != 2 {
+		return nil, &Error{err: "bad PROXY version"}
+	}
+	switch b[12] & 0xF {
+	case 0: // LOCAL, e.g. health checks from the proxy itself
+		return src, nil
+	case 1: // PROXY
+	default:
+		return nil, &Error{err: "bad PROXY v2 command"}
+	}
+
+	addrs := b[proxyV2HeaderLen:]
+	var ip net.IP
+	var port uint16
+	switch b[13] >> 4 {
+	case 0: // AF_UNSPEC
+		return src, nil
+	case 1: // AF_INET
+		if len(addrs) < 12 {
+			return nil, &Error{err: "short PROXY v2 address"}
+		}
+		ip = net.IP(append([]byte(nil), addrs[:4]...))
+		port = binary.BigEndian.Uint16(addrs[8:])
+	case 2: // AF_INET6
+		if len(addrs) < 36 {
+			return nil, &Error{err: "short PROXY v2 address"}
+		}
+		ip = net.IP(append([]byte(nil), addrs[:16]...))
+		port = binary.BigEndian.Uint16(addrs[32:])
+	default:
+		return nil, &Error{err: "unsupported PROXY v2 address family"}
+	}
+	switch b[13] & 0xF {
+	case 1: // SOCK_STREAM
+		return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+	case 2: // SOCK_DGRAM
+		return &net.UDPAddr{IP: ip, Port: int(port)}, nil
+	}
+	return nil, &Error{err: "unsupported PROXY v2 transport"}
+}
+
+// proxyListener wraps the connections accepted by a net.Listener in a
+// proxyConn.
+type proxyListener struct {
+	net.Listener
+	p *ProxyProtocol
+}
+
+func (l *proxyListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return &proxyConn{Conn: c, p: l.p, r: bufio.NewReaderSize(c, 2*proxyV1MaxLen)}, nil
+}
+
+// proxyConn reads the PROXY header on the first call to Read, it is read
+// under the deadline set for that Read. Until then RemoteAddr returns the
+// address of the peer.
+type proxyConn struct {
+	net.Conn
+	p      *ProxyProtocol
+	r      *bufio.Reader
+	read   bool // true when the header has been read
+	err    error
+	remote net.Addr
+}
+
+func (c *proxyConn) Read(b []byte) (int, error) {
+	if !c.read {
+		c.read = true
+		c.remote, c.err = c.readHeader()
+	}
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+// RemoteAddr returns the client address from the PROXY header, if any.
+func (c *proxyConn) RemoteAddr() net.Addr {
+	if c.remote != nil {
+		return c.remote
+	}
+	return c.Conn.RemoteAddr()
+}
+
+func (c *proxyConn) readHeader() (net.Addr, error) {
+	src := c.Conn.RemoteAddr()
+	trusted := c.p.trusts(src)
+
+	// Both a DNS message and the PROXY header are longer than this.
+	start, err := c.r.Peek(2)
+	if err != nil {
+		return nil, err
+	}
+	var version int
+	switch {
+	case start[0] == proxyV1Prefix[0] && start[1] == proxyV1Prefix[1]:
+		if b, err := c.r.Peek(len(proxyV1Prefix)); err == nil && bytes.Equal(b, proxyV1Prefix) {
+			version = 1
+		}
+	case start[0] == proxyV2Signature[0] && start[1] == proxyV2Signature[1]:
+		if b, err := c.r.Peek(len(proxyV2Signature)); err == nil && bytes.Equal(b, proxyV2Signature) {
+			version = 2
+		}
+	}
+
+	switch {
+	case version == 0 && (!trusted || c.p.Optional):
+		return src, nil
+	case version == 0:
+		return nil, &Error{err: "missing PROXY header"}
+	case !trusted:
+		return nil, &Error{err: "PROXY header from untrusted source"}
+	case version == 1:
+		line, err := c.r.ReadSlice('\n')
+		if err != nil {
+			if err == bufio.ErrBufferFull {
+				return nil, &Error{err: "bad PROXY v1 header"}
+			}
+			return nil, err
+		}
+		return parseProxyV1(line, src)
+	}
+
+	h := make([]byte, proxyV2HeaderLen)
+	if _, err := io.ReadFull(c.r, h); err != nil {
+		return nil, err
+	}
+	h = append(h, make([]byte, binary.BigEndian.Uint16(h[14:]))...)
+	if _, err := io.ReadFull(c.r, h[proxyV2HeaderLen:]); err != nil {
+		return nil, err
+	}
+	return parseProxyV2(h, src)
+}
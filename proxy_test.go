@@ -0,0 +1,191 @@
+package dns
+
+import (
+	"encoding/binary"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func proxyV2Header(cmd, fam byte, addrs []byte) []byte {
+	h := append([]byte(nil), proxyV2Signature...)
+	h = append(h, 0x20|cmd, fam, 0, 0)
+	binary.BigEndian.PutUint16(h[14:], uint16(len(addrs)))
+	return append(h, addrs...)
+}
+
+func proxyV2TCP4(src net.IP, port uint16) []byte {
+	addrs := make([]byte, 12)
+	copy(addrs, src.To4())
+	copy(addrs[4:], net.IPv4(192, 0, 2, 53).To4())
+	binary.BigEndian.PutUint16(addrs[8:], port)
+	binary.BigEndian.PutUint16(addrs[10:], 53)
+	return proxyV2Header(1, 0x11, addrs)
+}
+
+func proxyTrustLocalhost() *ProxyProtocol {
+	_, n, _ := net.ParseCIDR("127.0.0.0/8")
+	return &ProxyProtocol{Trusted: []*net.IPNet{n}}
+}
+
+func TestParseProxyV1(t *testing.T) {
+	src := &net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+	tests := []struct {
+		line string
+		want string // empty for an error
+	}{
+		{"PROXY TCP4 192.0.2.1 192.0.2.53 4321 53\r\n", "192.0.2.1:4321"},
+		{"PROXY TCP6 2001:db8::1 2001:db8::53 4321 53\r\n", "[2001:db8::1]:4321"},
+		{"PROXY UNKNOWN\r\n", "127.0.0.1:1"},
+		{"PROXY UNKNOWN ffff::1 ffff::2 1 2\r\n", "127.0.0.1:1"},
+		{"PROXY TCP4 2001:db8::1 192.0.2.53 4321 53\r\n", ""},
+		{"PROXY TCP4 192.0.2.1 192.0.2.53 65536 53\r\n", ""},
+		{"PROXY TCP4 192.0.2.1 192.0.2.53 4321\r\n", ""},
+		{"PROXY UDP4 192.0.2.1 192.0.2.53 4321 53\r\n", ""},
+		{"PROXY TCP4 192.0.2.1 192.0.2.53 4321 53\n", ""},
+	}
+	for _, tc := range tests {
+		a, err := parseProxyV1([]byte(tc.line), src)
+		switch {
+		case tc.want == "" && err == nil:
+			t.Errorf("%q: expected an error, got %v", tc.line, a)
+		case tc.want != "" && err != nil:
+			t.Errorf("%q: %v", tc.line, err)
+		case tc.want != "" && a.String() != tc.want:
+			t.Errorf("%q: got %v, want %s", tc.line, a, tc.want)
+		}
+	}
+}
+
+func TestParseProxyV2(t *testing.T) {
+	src := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+
+	a, err := parseProxyV2(proxyV2TCP4(net.IPv4(192, 0, 2, 1), 4321), src)
+	if err != nil || a.String() != "192.0.2.1:4321" {
+		t.Errorf("TCP4: got %v, %v", a, err)
+	}
+	if _, ok := a.(*net.TCPAddr); !ok {
+		t.Errorf("TCP4: got %T, want *net.TCPAddr", a)
+	}
+
+	addrs := make([]byte, 36+7) // with a trailing TLV
+	copy(addrs, net.ParseIP("2001:db8::1"))
+	copy(addrs[16:], net.ParseIP("2001:db8::53"))
+	binary.BigEndian.PutUint16(addrs[32:], 4321)
+	binary.BigEndian.PutUint16(addrs[34:], 53)
+	a, err = parseProxyV2(proxyV2Header(1, 0x22, addrs), src)
+	if err != nil || a.String() != "[2001:db8::1]:4321" {
+		t.Errorf("UDP6: got %v, %v", a, err)
+	}
+	if _, ok := a.(*net.UDPAddr); !ok {
+		t.Errorf("UDP6: got %T, want *net.UDPAddr", a)
+	}
+
+	if a, err = parseProxyV2(proxyV2Header(0, 0, nil), src); err != nil || a != src {
+		t.Errorf("LOCAL: got %v, %v", a, err)
+	}
+
+	for _, h := range [][]byte{
+		proxyV2Header(1, 0x11, make([]byte, 4)),   // short address
+		proxyV2Header(2, 0x11, make([]byte, 12)),  // bad command
+		proxyV2Header(1, 0x13, make([]byte, 12)),  // bad transport
+		proxyV2Header(1, 0x31, make([]byte, 216)), // AF_UNIX
+	} {
+		if a, err := parseProxyV2(h, src); err == nil {
+			t.Errorf("%x: expected an error, got %v", h, a)
+		}
+	}
+}
+
+func TestProxyProtocolParseUDP(t *testing.T) {
+	p := proxyTrustLocalhost()
+	trusted := &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1}
+	untrusted := &net.UDPAddr{IP: net.IPv4(192, 0, 2, 2), Port: 1}
+
+	msg := make([]byte, headerSize)
+	m, a, err := p.parseUDP(append(proxyV2TCP4(net.IPv4(192, 0, 2, 1), 4321), msg...), trusted)
+	if err != nil || len(m) != headerSize || a.String() != "192.0.2.1:4321" {
+		t.Errorf("trusted: got %v, %v", a, err)
+	}
+	if _, _, err := p.parseUDP(msg, trusted); err == nil {
+		t.Error("trusted: expected an error for a missing header")
+	}
+	if _, _, err := p.parseUDP(append(proxyV2TCP4(net.IPv4(192, 0, 2, 1), 4321), msg...), untrusted); err == nil {
+		t.Error("untrusted: expected an error for a header")
+	}
+	if m, a, err := p.parseUDP(msg, untrusted); err != nil || a != untrusted || len(m) != headerSize {
+		t.Errorf("untrusted: got %v, %v", a, err)
+	}
+
+	p.Optional = true
+	if m, a, err := p.parseUDP(msg, trusted); err != nil || a != trusted || len(m) != headerSize {
+		t.Errorf("optional: got %v, %v", a, err)
+	}
+}
+
+func TestServingProxyProtocol(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServerEchoAddrPort)
+	defer HandleRemove("miek.nl.")
+
+	m := new(Msg)
+	m.SetQuestion("miek.nl.", TypeTXT)
+	query, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, network := range []string{"udp", "tcp"} {
+		waitLock := sync.Mutex{}
+		server := &Server{Addr: "127.0.0.1:0", Net: network, ProxyProtocol: proxyTrustLocalhost(),
+			ReadTimeout: time.Hour, WriteTimeout: time.Hour, NotifyStartedFunc: waitLock.Unlock}
+		waitLock.Lock()
+		go server.ListenAndServe()
+		waitLock.Lock()
+
+		var addr string
+		if network == "udp" {
+			addr = server.PacketConn.LocalAddr().String()
+		} else {
+			addr = server.Listener.Addr().String()
+		}
+
+		headers := [][]byte{proxyV2TCP4(net.IPv4(192, 0, 2, 1), 4321)}
+		if network == "tcp" {
+			headers = append(headers, []byte("PROXY TCP4 192.0.2.1 192.0.2.53 4321 53\r\n"))
+		}
+		for _, h := range headers {
+			c, err := net.Dial(network, addr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			co := &Conn{Conn: c}
+			co.SetDeadline(time.Now().Add(2 * time.Second))
+			if network == "tcp" {
+				l := make([]byte, 2)
+				binary.BigEndian.PutUint16(l, uint16(len(query)))
+				_, err = c.Write(append(append(h, l...), query...))
+			} else {
+				_, err = c.Write(append(h, query...))
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			r, err := co.ReadMsg()
+			if err != nil {
+				t.Fatalf("%s: failed to read the response: %v", network, err)
+			}
+			if txt := r.Extra[0].(*TXT).Txt[0]; txt != "192.0.2.1:4321" {
+				t.Errorf("%s: got remote address %s, want 192.0.2.1:4321", network, txt)
+			}
+			c.Close()
+		}
+
+		// A trusted source must send the header.
+		c := &Client{Net: network, Timeout: 500 * time.Millisecond}
+		if _, _, err := c.Exchange(m, addr); err == nil {
+			t.Errorf("%s: expected an error without a PROXY header", network)
+		}
+		server.Shutdown()
+	}
+}
@@ -660,6 +660,242 @@ func setSRV(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	return rr, nil, ""
 }
 
+func setDSYNC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(DSYNC)
+	rr.Hdr = h
+
+	l := <-c
+	if l.length == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	if t, ok := StringToType[l.tokenUpper]; ok {
+		rr.Type = t
+	} else if t, ok := typeToInt(l.tokenUpper); ok && strings.HasPrefix(l.tokenUpper, "TYPE") {
+		rr.Type = t
+	} else {
+		return nil, &ParseError{f, "bad DSYNC Type", l}, ""
+	}
+
+	<-c     // zBlank
+	l = <-c // zString
+	i, e := strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad DSYNC Scheme", l}, ""
+	}
+	rr.Scheme = uint8(i)
+
+	<-c     // zBlank
+	l = <-c // zString
+	i, e = strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad DSYNC Port", l}, ""
+	}
+	rr.Port = uint16(i)
+
+	<-c     // zBlank
+	l = <-c // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad DSYNC Target", l}, ""
+	}
+	rr.Target = name
+	return rr, nil, ""
+}
+
+func setIPSECKEY(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(IPSECKEY)
+	rr.Hdr = h
+
+	l := <-c
+	if l.length == 0 { // dynamic update rr.
+		return rr, nil, l.comment
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad IPSECKEY Precedence", l}, ""
+	}
+	rr.Precedence = uint8(i)
+
+	<-c     // zBlank
+	l = <-c // zString
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err || i > uint64(IPSECGatewayHost) {
+		return nil, &ParseError{f, "bad IPSECKEY GatewayType", l}, ""
+	}
+	rr.GatewayType = uint8(i)
+
+	<-c     // zBlank
+	l = <-c // zString
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad IPSECKEY Algorithm", l}, ""
+	}
+	rr.Algorithm = uint8(i)
+
+	<-c     // zBlank
+	l = <-c // zString
+	var ok bool
+	rr.GatewayAddr, rr.GatewayHost, ok = parseIPSECGateway(rr.GatewayType, l, o)
+	if !ok {
+		return nil, &ParseError{f, "bad IPSECKEY Gateway", l}, ""
+	}
+
+	s, e1, c1 := endingToString(c, "bad IPSECKEY PublicKey", f)
+	if e1 != nil {
+		return nil, e1, c1
+	}
+	rr.PublicKey = s
+	return rr, nil, c1
+}
+
+func setAMTRELAY(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(AMTRELAY)
+	rr.Hdr = h
+
+	l := <-c
+	if l.length == 0 { // dynamic update rr.
+		return rr, nil, ""
+	}
+
+	i, e := strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad AMTRELAY Precedence", l}, ""
+	}
+	rr.Precedence = uint8(i)
+
+	<-c     // zBlank
+	l = <-c // zString
+	switch l.token {
+	case "0":
+	case "1":
+		rr.GatewayType = 0x80
+	default:
+		return nil, &ParseError{f, "bad AMTRELAY Discovery", l}, ""
+	}
+
+	<-c     // zBlank
+	l = <-c // zString
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err || i > uint64(IPSECGatewayHost) {
+		return nil, &ParseError{f, "bad AMTRELAY GatewayType", l}, ""
+	}
+	rr.GatewayType |= uint8(i)
+
+	<-c     // zBlank
+	l = <-c // zString
+	var ok bool
+	rr.GatewayAddr, rr.GatewayHost, ok = parseIPSECGateway(rr.GatewayType&0x7f, l, o)
+	if !ok {
+		return nil, &ParseError{f, "bad AMTRELAY Gateway", l}, ""
+	}
+	return rr, nil, ""
+}
+
+// parseIPSECGateway parses the gateway of an IPSECKEY or AMTRELAY RR of
+// gateway type typ. A type without a gateway has "." in its place.
+func parseIPSECGateway(typ uint8, l lex, o string) (net.IP, string, bool) {
+	if l.err {
+		return nil, "", false
+	}
+	switch typ {
+	case IPSECGatewayNone:
+		return nil, "", l.token == "."
+	case IPSECGatewayIPv4:
+		addr := net.ParseIP(l.token)
+		if addr == nil || addr.To4() == nil || strings.Contains(l.token, ":") {
+			return nil, "", false
+		}
+		return addr.To4(), "", true
+	case IPSECGatewayIPv6:
+		addr := net.ParseIP(l.token)
+		if addr == nil || !strings.Contains(l.token, ":") {
+			return nil, "", false
+		}
+		return addr, "", true
+	case IPSECGatewayHost:
+		name, ok := toAbsoluteName(l.token, o)
+		return nil, name, ok
+	}
+	return nil, "", false
+}
+
+func setAPL(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(APL)
+	rr.Hdr = h
+
+	var prefixes []APLPrefix
+	l := <-c
+	for l.value != zNewline && l.value != zEOF {
+		if l.err {
+			return nil, &ParseError{f, "bad APL prefix", l}, ""
+		}
+		switch l.value {
+		case zBlank: // Ok
+		case zString:
+			p, ok := parseAPLPrefix(l.token)
+			if !ok {
+				return nil, &ParseError{f, "bad APL prefix", l}, ""
+			}
+			prefixes = append(prefixes, p)
+		default:
+			return nil, &ParseError{f, "bad APL prefix", l}, ""
+		}
+		l = <-c
+	}
+	rr.Prefixes = prefixes
+	return rr, nil, l.comment
+}
+
+// parseAPLPrefix parses an APL prefix in the presentation format of RFC 3123
+// section 5, "[!]afi:address/prefix".
+func parseAPLPrefix(s string) (APLPrefix, bool) {
+	var p APLPrefix
+	if strings.HasPrefix(s, "!") {
+		p.Negation = true
+		s = s[1:]
+	}
+	colon := strings.IndexByte(s, ':')
+	if colon < 0 {
+		return p, false
+	}
+	family, s := s[:colon], s[colon+1:]
+	slash := strings.IndexByte(s, '/')
+	if slash < 0 {
+		return p, false
+	}
+	addr := net.ParseIP(s[:slash])
+	bits, e := strconv.ParseUint(s[slash+1:], 10, 8)
+	if addr == nil || e != nil {
+		return p, false
+	}
+	switch family {
+	case "1":
+		if strings.Contains(s[:slash], ":") {
+			return p, false
+		}
+		addr = addr.To4()
+		if bits > 8*net.IPv4len {
+			return p, false
+		}
+		p.Network.Mask = net.CIDRMask(int(bits), 8*net.IPv4len)
+	case "2":
+		if !strings.Contains(s[:slash], ":") || bits > 8*net.IPv6len {
+			return p, false
+		}
+		p.Network.Mask = net.CIDRMask(int(bits), 8*net.IPv6len)
+	default:
+		return p, false
+	}
+	// The bits of the address beyond the prefix must be zero.
+	if !addr.Mask(p.Network.Mask).Equal(addr) {
+		return p, false
+	}
+	p.Network.IP = addr
+	return p, true
+}
+
 func setNAPTR(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	rr := new(NAPTR)
 	rr.Hdr = h
@@ -1074,6 +1310,125 @@ func setCSYNC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	return rr, nil, l.comment
 }
 
+func setZONEMD(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(ZONEMD)
+	rr.Hdr = h
+
+	l := <-c
+	if l.length == 0 { // dynamic update rr.
+		return rr, nil, l.comment
+	}
+	i, e := strconv.ParseUint(l.token, 10, 32)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad ZONEMD Serial", l}, ""
+	}
+	rr.Serial = uint32(i)
+	<-c // zBlank
+	l = <-c
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad ZONEMD Scheme", l}, ""
+	}
+	rr.Scheme = uint8(i)
+	<-c // zBlank
+	l = <-c
+	i, e = strconv.ParseUint(l.token, 10, 8)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad ZONEMD Hash", l}, ""
+	}
+	rr.Hash = uint8(i)
+	s, e2, c1 := endingToString(c, "bad ZONEMD Digest", f)
+	if e2 != nil {
+		return nil, e2, c1
+	}
+	rr.Digest = s
+	return rr, nil, c1
+}
+
+func setSVCB(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(SVCB)
+	rr.Hdr = h
+	return setSVCBRdata(rr, rr, c, o, f)
+}
+
+func setHTTPS(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(HTTPS)
+	rr.Hdr = h
+	return setSVCBRdata(rr, &rr.SVCB, c, o, f)
+}
+
+// setSVCBRdata parses the rdata of the SVCB or HTTPS record r into rr. The
+// SvcParams are key=value pairs, the value may be quoted and is absent for
+// keys without one, RFC 9460 section 2.1.
+func setSVCBRdata(r RR, rr *SVCB, c chan lex, o, f string) (RR, *ParseError, string) {
+	typ := Type(rr.Hdr.Rrtype).String()
+
+	l := <-c
+	if l.length == 0 { // dynamic update rr.
+		return r, nil, l.comment
+	}
+	i, e := strconv.ParseUint(l.token, 10, 16)
+	if e != nil || l.err {
+		return nil, &ParseError{f, "bad " + typ + " Priority", l}, ""
+	}
+	rr.Priority = uint16(i)
+
+	<-c     // zBlank
+	l = <-c // zString
+	name, nameOk := toAbsoluteName(l.token, o)
+	if l.err || !nameOk {
+		return nil, &ParseError{f, "bad " + typ + " Target", l}, ""
+	}
+	rr.Target = name
+
+	var xs []SVCBKeyValue
+	l = <-c
+	for l.value != zNewline && l.value != zEOF {
+		switch l.value {
+		case zBlank:
+			l = <-c
+			continue
+		case zString:
+		default:
+			return nil, &ParseError{f, "bad " + typ + " SvcParam", l}, ""
+		}
+		key, value := l.token, ""
+		if i := strings.IndexByte(l.token, '='); i >= 0 {
+			key, value = l.token[:i], l.token[i+1:]
+		}
+		kv := makeSVCBKeyValue(svcbStringToKey(key))
+		if kv == nil {
+			return nil, &ParseError{f, "bad " + typ + " SvcParamKey", l}, ""
+		}
+		pl := l
+		l = <-c
+		if value == "" && strings.HasSuffix(pl.token, "=") && l.value == zQuote {
+			// A quoted value, which may be empty.
+			l = <-c
+			if l.value == zString {
+				value = l.token
+				l = <-c
+			}
+			if l.value != zQuote {
+				return nil, &ParseError{f, "bad " + typ + " SvcParamValue", l}, ""
+			}
+			l = <-c
+		}
+		if l.value != zBlank && l.value != zNewline && l.value != zEOF {
+			return nil, &ParseError{f, "bad " + typ + " SvcParamValue", l}, ""
+		}
+		if err := kv.parse(value); err != nil {
+			return nil, &ParseError{f, "bad " + typ + " SvcParamValue: " + err.(*Error).err, pl}, ""
+		}
+		xs = append(xs, kv)
+	}
+	if err := checkSVCB(sortSVCB(xs)); err != nil {
+		return nil, &ParseError{f, "bad " + typ + " SvcParams: " + err.(*Error).err, l}, ""
+	}
+	rr.Value = xs
+	return r, nil, l.comment
+}
+
 func setSIG(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	r, e, s := setRRSIG(h, c, o, f)
 	if r != nil {
@@ -1794,6 +2149,30 @@ func setAVC(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	return rr, nil, c1
 }
 
+func setRESINFO(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(RESINFO)
+	rr.Hdr = h
+
+	s, e, c1 := endingToTxtSlice(c, "bad RESINFO Txt", f)
+	if e != nil {
+		return nil, e, ""
+	}
+	rr.Txt = s
+	return rr, nil, c1
+}
+
+func setWALLET(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
+	rr := new(WALLET)
+	rr.Hdr = h
+
+	s, e, c1 := endingToTxtSlice(c, "bad WALLET Txt", f)
+	if e != nil {
+		return nil, e, ""
+	}
+	rr.Txt = s
+	return rr, nil, c1
+}
+
 func setTXT(h RR_Header, c chan lex, o, f string) (RR, *ParseError, string) {
 	rr := new(TXT)
 	rr.Hdr = h
@@ -2185,6 +2564,12 @@ var typeToparserFunc = map[uint16]parserFunc{
 	TypeSOA:        {setSOA, false},
 	TypeSPF:        {setSPF, true},
 	TypeAVC:        {setAVC, true},
+	TypeAMTRELAY:   {setAMTRELAY, false},
+	TypeAPL:        {setAPL, true},
+	TypeDSYNC:      {setDSYNC, false},
+	TypeIPSECKEY:   {setIPSECKEY, true},
+	TypeRESINFO:    {setRESINFO, true},
+	TypeWALLET:     {setWALLET, true},
 	TypeSRV:        {setSRV, false},
 	TypeSSHFP:      {setSSHFP, true},
 	TypeTALINK:     {setTALINK, false},
@@ -2196,4 +2581,7 @@ var typeToparserFunc = map[uint16]parserFunc{
 	TypeURI:        {setURI, true},
 	TypeX25:        {setX25, false},
 	TypeTKEY:       {setTKEY, true},
+	TypeZONEMD:     {setZONEMD, true},
+	TypeSVCB:       {setSVCB, true},
+	TypeHTTPS:      {setHTTPS, true},
 }
@@ -0,0 +1,369 @@
+package dns
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// ServeMux is an DNS request multiplexer. It matches the
+// zone name of each incoming request against a list of
+// registered patterns add calls the handler for the pattern
+// that most closely matches the zone name.
+//
+// A pattern is either a zone name, such as "example.org.", which matches the
+// name itself and every name below it, or an explicit wildcard, such as
+// "*.example.org.", which only matches names strictly below example.org. When
+// both are registered for the same zone the wildcard takes precedence for the
+// names below it.
+//
+// Each pattern may be further restricted to a qtype, qclass and opcode with
+// HandleRoute. Within a zone, a route that restricts the opcode is preferred
+// over one that restricts the qtype, which in turn is preferred over one that
+// restricts the qclass.
+//
+// ServeMux is DNSSEC aware, meaning that queries for the DS record are
+// redirected to the parent zone (if that is also registered), otherwise the
+// child gets the query.
+//
+// ServeMux is also safe for concurrent access from multiple goroutines.
+// Matching a request neither takes a lock nor allocates.
+type ServeMux struct {
+	m sync.Mutex   // serializes writers
+	t atomic.Value // *muxTable, replaced wholesale on every change
+}
+
+// A Route restricts a pattern registered with a ServeMux to a subset of the
+// requests whose question name matches it.
+type Route struct {
+	// Qtype is the question type to match, zero matches any type.
+	Qtype uint16
+	// Qclass is the question class to match, zero matches any class.
+	Qclass uint16
+	// Opcode is the opcode to match, OpcodeAny matches any opcode. Note that
+	// the zero value only matches OpcodeQuery.
+	Opcode int
+}
+
+// OpcodeAny is used in a Route to match messages with any opcode.
+const OpcodeAny = -1
+
+// muxTable is an immutable snapshot of the patterns registered with a
+// ServeMux.
+type muxTable struct {
+	z        map[string]*muxZone // lower cased fully qualified zone name
+	notFound Handler
+}
+
+type muxZone struct {
+	exact    []muxRoute // for the zone and all names below it
+	wildcard []muxRoute // for the names strictly below the zone
+}
+
+type muxRoute struct {
+	Route
+	h Handler
+}
+
+// NewServeMux allocates and returns a new ServeMux.
+func NewServeMux() *ServeMux { return new(ServeMux) }
+
+// DefaultServeMux is the default ServeMux used by Serve.
+var DefaultServeMux = NewServeMux()
+
+// specificity orders the routes of a zone, the first route to match wins.
+func (r Route) specificity() int {
+	s := 0
+	if r.Opcode != OpcodeAny {
+		s += 4
+	}
+	if r.Qtype != 0 {
+		s += 2
+	}
+	if r.Qclass != 0 {
+		s++
+	}
+	return s
+}
+
+func (r Route) matches(qtype, qclass uint16, opcode int) bool {
+	return (r.Opcode == OpcodeAny || r.Opcode == opcode) &&
+		(r.Qtype == 0 || r.Qtype == qtype) &&
+		(r.Qclass == 0 || r.Qclass == qclass)
+}
+
+func lookupRoute(routes []muxRoute, qtype, qclass uint16, opcode int) *muxRoute {
+	for i := range routes {
+		if routes[i].matches(qtype, qclass, opcode) {
+			return &routes[i]
+		}
+	}
+	return nil
+}
+
+// lookup returns the best route in z, below is true when the question name is
+// strictly below the zone.
+func (z *muxZone) lookup(below bool, qtype, qclass uint16, opcode int) *muxRoute {
+	if !below {
+		return lookupRoute(z.exact, qtype, qclass, opcode)
+	}
+	w := lookupRoute(z.wildcard, qtype, qclass, opcode)
+	e := lookupRoute(z.exact, qtype, qclass, opcode)
+	if w == nil || (e != nil && e.specificity() > w.specificity()) {
+		return e
+	}
+	return w
+}
+
+func (mux *ServeMux) table() *muxTable {
+	t, _ := mux.t.Load().(*muxTable)
+	return t
+}
+
+func (mux *ServeMux) match(q string, qtype, qclass uint16, opcode int) Handler {
+	t := mux.table()
+	if t == nil {
+		return nil
+	}
+
+	var buf [256]byte
+	var b []byte
+	if len(q) <= len(buf) {
+		b = buf[:len(q)]
+	} else {
+		b = make([]byte, len(q))
+	}
+	for i := 0; i < len(q); i++ {
+		c := q[i]
+		if c >= 'A' && c <= 'Z' {
+			c |= 'a' - 'A'
+		}
+		b[i] = c
+	}
+
+	var handler Handler
+	off := 0
+	end := len(q) == 0
+	for !end {
+		// The string conversion in the map index does not allocate.
+		if z, ok := t.z[string(b[off:])]; ok {
+			if r := z.lookup(off > 0, qtype, qclass, opcode); r != nil {
+				// Continue for DS to see if we have a parent too, if so
+				// delegate to the parent. A route explicitly registered
+				// for DS keeps the query.
+				if qtype != TypeDS || off > 0 || r.Qtype == TypeDS {
+					return r.h
+				}
+				if handler == nil {
+					handler = r.h
+				}
+			}
+		}
+		off, end = NextLabel(q, off)
+	}
+
+	// Wildcard match, if we have found nothing try the root zone as a last resort.
+	if q != "." {
+		if z, ok := t.z["."]; ok {
+			if r := z.lookup(true, qtype, qclass, opcode); r != nil {
+				return r.h
+			}
+		}
+	}
+	return handler
+}
+
+// update applies fn to a copy of the current table and publishes the result.
+func (mux *ServeMux) update(fn func(t *muxTable)) {
+	mux.m.Lock()
+	defer mux.m.Unlock()
+
+	t := &muxTable{z: make(map[string]*muxZone)}
+	if old := mux.table(); old != nil {
+		for k, z := range old.z {
+			t.z[k] = z
+		}
+		t.notFound = old.notFound
+	}
+	fn(t)
+	mux.t.Store(t)
+}
+
+// muxPattern returns the table key for pattern and whether it is an explicit
+// wildcard.
+func muxPattern(pattern string) (string, bool) {
+	if pattern == "" {
+		panic("dns: invalid pattern " + pattern)
+	}
+	pattern = strings.ToLower(Fqdn(pattern))
+	if strings.HasPrefix(pattern, "*.") {
+		if pattern == "*." {
+			return ".", true
+		}
+		return pattern[2:], true
+	}
+	return pattern, false
+}
+
+// withRoute returns routes with r added, replacing an existing identical route.
+func withRoute(routes []muxRoute, r muxRoute) []muxRoute {
+	rs := make([]muxRoute, 0, len(routes)+1)
+	for _, o := range routes {
+		if o.Route != r.Route {
+			rs = append(rs, o)
+		}
+	}
+	i := len(rs)
+	for i > 0 && rs[i-1].specificity() < r.specificity() {
+		i--
+	}
+	rs = append(rs, muxRoute{})
+	copy(rs[i+1:], rs[i:])
+	rs[i] = r
+	return rs
+}
+
+// withoutRoute returns routes with r removed.
+func withoutRoute(routes []muxRoute, r Route) []muxRoute {
+	var rs []muxRoute
+	for _, o := range routes {
+		if o.Route != r {
+			rs = append(rs, o)
+		}
+	}
+	return rs
+}
+
+// Handle adds a handler to the ServeMux for pattern.
+func (mux *ServeMux) Handle(pattern string, handler Handler) {
+	mux.HandleRoute(pattern, Route{Opcode: OpcodeAny}, handler)
+}
+
+// HandleFunc adds a handler function to the ServeMux for pattern.
+func (mux *ServeMux) HandleFunc(pattern string, handler func(ResponseWriter, *Msg)) {
+	mux.Handle(pattern, HandlerFunc(handler))
+}
+
+// HandleRoute adds a handler to the ServeMux for the requests matching both
+// pattern and route. A previous handler for the same pattern and route is
+// replaced.
+func (mux *ServeMux) HandleRoute(pattern string, route Route, handler Handler) {
+	key, wildcard := muxPattern(pattern)
+	mux.update(func(t *muxTable) {
+		z := new(muxZone)
+		if old, ok := t.z[key]; ok {
+			*z = *old
+		}
+		r := muxRoute{Route: route, h: handler}
+		if wildcard {
+			z.wildcard = withRoute(z.wildcard, r)
+		} else {
+			z.exact = withRoute(z.exact, r)
+		}
+		t.z[key] = z
+	})
+}
+
+// HandleRouteFunc adds a handler function to the ServeMux for the requests
+// matching both pattern and route.
+func (mux *ServeMux) HandleRouteFunc(pattern string, route Route, handler func(ResponseWriter, *Msg)) {
+	mux.HandleRoute(pattern, route, HandlerFunc(handler))
+}
+
+// HandleRemove deregistrars the handler specific for pattern from the ServeMux.
+// All the routes registered for pattern are removed.
+func (mux *ServeMux) HandleRemove(pattern string) {
+	key, wildcard := muxPattern(pattern)
+	mux.update(func(t *muxTable) {
+		old, ok := t.z[key]
+		if !ok {
+			return
+		}
+		z := *old
+		if wildcard {
+			z.wildcard = nil
+		} else {
+			z.exact = nil
+		}
+		t.setZone(key, &z)
+	})
+}
+
+// HandleRemoveRoute deregisters the handler for pattern and route from the
+// ServeMux.
+func (mux *ServeMux) HandleRemoveRoute(pattern string, route Route) {
+	key, wildcard := muxPattern(pattern)
+	mux.update(func(t *muxTable) {
+		old, ok := t.z[key]
+		if !ok {
+			return
+		}
+		z := *old
+		if wildcard {
+			z.wildcard = withoutRoute(z.wildcard, route)
+		} else {
+			z.exact = withoutRoute(z.exact, route)
+		}
+		t.setZone(key, &z)
+	})
+}
+
+// setZone stores z under key, or removes key if z no longer holds any routes.
+func (t *muxTable) setZone(key string, z *muxZone) {
+	if len(z.exact) == 0 && len(z.wildcard) == 0 {
+		delete(t.z, key)
+		return
+	}
+	t.z[key] = z
+}
+
+// HandleNotFound sets the handler that is called when no pattern matches the
+// request, or when the request has no question. If handler is nil, the default
+// HandleFailed is used.
+func (mux *ServeMux) HandleNotFound(handler Handler) {
+	mux.update(func(t *muxTable) { t.notFound = handler })
+}
+
+// ServeDNS dispatches the request to the handler whose
+// pattern most closely matches the request message. If DefaultServeMux
+// is used the correct thing for DS queries is done: a possible parent
+// is sought.
+// If no handler is found, or the request message does not have a question,
+// the not found handler is called, by default a standard SERVFAIL message
+// is returned.
+func (mux *ServeMux) ServeDNS(w ResponseWriter, request *Msg) {
+	var h Handler
+	if len(request.Question) >= 1 { // allow more than one question
+		q := &request.Question[0]
+		h = mux.match(q.Name, q.Qtype, q.Qclass, request.Opcode)
+	}
+	if h == nil {
+		if t := mux.table(); t != nil && t.notFound != nil {
+			h = t.notFound
+		} else {
+			h = failedHandler()
+		}
+	}
+	h.ServeDNS(w, request)
+}
+
+// Handle registers the handler with the given pattern
+// in the DefaultServeMux. The documentation for
+// ServeMux explains how patterns are matched.
+func Handle(pattern string, handler Handler) { DefaultServeMux.Handle(pattern, handler) }
+
+// HandleRoute registers the handler with the given pattern and route
+// in the DefaultServeMux.
+func HandleRoute(pattern string, route Route, handler Handler) {
+	DefaultServeMux.HandleRoute(pattern, route, handler)
+}
+
+// HandleRemove deregisters the handle with the given pattern
+// in the DefaultServeMux.
+func HandleRemove(pattern string) { DefaultServeMux.HandleRemove(pattern) }
+
+// HandleFunc registers the handler function with the given pattern
+// in the DefaultServeMux.
+func HandleFunc(pattern string, handler func(ResponseWriter, *Msg)) {
+	DefaultServeMux.HandleFunc(pattern, handler)
+}
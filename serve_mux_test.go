@@ -0,0 +1,184 @@
+package dns
+
+import "testing"
+
+func TestDotAsCatchAllWildcard(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle(".", HandlerFunc(HelloServer))
+	mux.Handle("example.com.", HandlerFunc(AnotherHelloServer))
+
+	handler := mux.match("www.miek.nl.", TypeTXT, ClassINET, OpcodeQuery)
+	if handler == nil {
+		t.Error("wildcard match failed")
+	}
+
+	handler = mux.match("www.example.com.", TypeTXT, ClassINET, OpcodeQuery)
+	if handler == nil {
+		t.Error("example.com match failed")
+	}
+
+	handler = mux.match("a.www.example.com.", TypeTXT, ClassINET, OpcodeQuery)
+	if handler == nil {
+		t.Error("a.www.example.com match failed")
+	}
+
+	handler = mux.match("boe.", TypeTXT, ClassINET, OpcodeQuery)
+	if handler == nil {
+		t.Error("boe. match failed")
+	}
+}
+
+func TestCaseFolding(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("_udp.example.com.", HandlerFunc(HelloServer))
+
+	handler := mux.match("_dns._udp.example.com.", TypeSRV, ClassINET, OpcodeQuery)
+	if handler == nil {
+		t.Error("case sensitive characters folded")
+	}
+
+	handler = mux.match("_DNS._UDP.EXAMPLE.COM.", TypeSRV, ClassINET, OpcodeQuery)
+	if handler == nil {
+		t.Error("case insensitive characters not folded")
+	}
+}
+
+func TestRootServer(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle(".", HandlerFunc(HelloServer))
+
+	handler := mux.match(".", TypeNS, ClassINET, OpcodeQuery)
+	if handler == nil {
+		t.Error("root match failed")
+	}
+}
+
+// muxName is a comparable Handler used to tell which route matched.
+type muxName string
+
+func (n muxName) ServeDNS(w ResponseWriter, r *Msg) {}
+
+func TestServeMuxRoutes(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("example.org.", muxName("zone"))
+	mux.Handle("*.example.org.", muxName("wildcard"))
+	mux.Handle("Sub.Example.ORG", muxName("sub"))
+	mux.HandleRoute("example.org.", Route{Opcode: OpcodeNotify}, muxName("notify"))
+	mux.HandleRoute("example.org.", Route{Qtype: TypeMX, Opcode: OpcodeAny}, muxName("mx"))
+	mux.HandleRoute("*.example.org.", Route{Qtype: TypeTXT, Opcode: OpcodeAny}, muxName("wildcard-txt"))
+	mux.HandleRoute("bind.", Route{Qclass: ClassCHAOS, Opcode: OpcodeAny}, muxName("chaos"))
+	mux.HandleRoute(".", Route{Opcode: OpcodeUpdate}, muxName("update"))
+
+	tests := []struct {
+		name   string
+		qtype  uint16
+		qclass uint16
+		opcode int
+		want   muxName
+	}{
+		{"example.org.", TypeA, ClassINET, OpcodeQuery, "zone"},
+		{"EXAMPLE.org.", TypeA, ClassINET, OpcodeQuery, "zone"},
+		{"www.example.org.", TypeA, ClassINET, OpcodeQuery, "wildcard"},
+		{"a.b.example.org.", TypeA, ClassINET, OpcodeQuery, "wildcard"},
+		{"www.example.org.", TypeTXT, ClassINET, OpcodeQuery, "wildcard-txt"},
+		{"example.org.", TypeTXT, ClassINET, OpcodeQuery, "zone"},
+		{"example.org.", TypeMX, ClassINET, OpcodeQuery, "mx"},
+		{"www.example.org.", TypeMX, ClassINET, OpcodeQuery, "mx"},
+		{"example.org.", TypeSOA, ClassINET, OpcodeNotify, "notify"},
+		{"www.sub.example.org.", TypeA, ClassINET, OpcodeQuery, "sub"},
+		{"version.bind.", TypeTXT, ClassCHAOS, OpcodeQuery, "chaos"},
+		{"version.bind.", TypeTXT, ClassINET, OpcodeQuery, ""},
+		{"example.net.", TypeSOA, ClassINET, OpcodeUpdate, "update"},
+		{"example.net.", TypeSOA, ClassINET, OpcodeQuery, ""},
+	}
+	for _, tc := range tests {
+		h := mux.match(tc.name, tc.qtype, tc.qclass, tc.opcode)
+		got, _ := h.(muxName)
+		if got != tc.want {
+			t.Errorf("match(%q, %s, %s, %s) = %q, want %q", tc.name, TypeToString[tc.qtype],
+				ClassToString[tc.qclass], OpcodeToString[tc.opcode], got, tc.want)
+		}
+	}
+
+	mux.HandleRemove("*.example.org.")
+	if h, _ := mux.match("www.example.org.", TypeA, ClassINET, OpcodeQuery).(muxName); h != "zone" {
+		t.Errorf("after removing the wildcard, got %q, want %q", h, "zone")
+	}
+	mux.HandleRemoveRoute("example.org.", Route{Qtype: TypeMX, Opcode: OpcodeAny})
+	if h, _ := mux.match("example.org.", TypeMX, ClassINET, OpcodeQuery).(muxName); h != "zone" {
+		t.Errorf("after removing the MX route, got %q, want %q", h, "zone")
+	}
+}
+
+func TestServeMuxDS(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("org.", muxName("org"))
+	mux.Handle("example.org.", muxName("example"))
+	mux.Handle("sub.example.org.", muxName("sub"))
+
+	if h, _ := mux.match("sub.example.org.", TypeDS, ClassINET, OpcodeQuery).(muxName); h != "example" {
+		t.Errorf("DS query went to %q, want the parent %q", h, "example")
+	}
+	if h, _ := mux.match("www.sub.example.org.", TypeDS, ClassINET, OpcodeQuery).(muxName); h != "sub" {
+		t.Errorf("DS query went to %q, want %q", h, "sub")
+	}
+
+	mux.HandleRoute("sub.example.org.", Route{Qtype: TypeDS}, muxName("sub-ds"))
+	if h, _ := mux.match("sub.example.org.", TypeDS, ClassINET, OpcodeQuery).(muxName); h != "sub-ds" {
+		t.Errorf("DS query went to %q, want %q", h, "sub-ds")
+	}
+
+	mux = NewServeMux()
+	mux.Handle("example.org.", muxName("example"))
+	if h, _ := mux.match("example.org.", TypeDS, ClassINET, OpcodeQuery).(muxName); h != "example" {
+		t.Errorf("DS query without a parent went to %q, want %q", h, "example")
+	}
+}
+
+func TestServeMuxNotFound(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle("example.org.", muxName("example"))
+
+	var called bool
+	mux.HandleNotFound(HandlerFunc(func(w ResponseWriter, r *Msg) { called = true }))
+
+	m := new(Msg)
+	m.SetQuestion("example.net.", TypeA)
+	mux.ServeDNS(nil, m)
+	if !called {
+		t.Error("not found handler not called for unmatched question")
+	}
+
+	called = false
+	mux.ServeDNS(nil, new(Msg))
+	if !called {
+		t.Error("not found handler not called for empty question section")
+	}
+}
+
+func TestServeMuxMatchAllocs(t *testing.T) {
+	mux := NewServeMux()
+	mux.Handle(".", muxName("root"))
+	mux.Handle("example.org.", muxName("example"))
+	mux.Handle("*.example.org.", muxName("wildcard"))
+
+	allocs := testing.AllocsPerRun(100, func() {
+		mux.match("WWW.Sub.Example.Org.", TypeDS, ClassINET, OpcodeQuery)
+	})
+	if allocs != 0 {
+		t.Errorf("match allocated %v times, want 0", allocs)
+	}
+}
+
+func BenchmarkServeMuxMatch(b *testing.B) {
+	mux := NewServeMux()
+	mux.Handle("example.org.", muxName("example"))
+	mux.Handle("*.example.net.", muxName("wildcard"))
+	mux.HandleRoute("example.org.", Route{Qtype: TypeTXT}, muxName("txt"))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		mux.match("www.sub.Example.Net.", TypeA, ClassINET, OpcodeQuery)
+	}
+}
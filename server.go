@@ -8,6 +8,7 @@ import (
 	"encoding/binary"
 	"io"
 	"net"
+	"strings"
 	"sync"
 	"time"
 )
@@ -33,7 +34,8 @@ type ResponseWriter interface {
 	Write([]byte) (int, error)
 	// Close closes the connection.
 	Close() error
-	// TsigStatus returns the status of the Tsig.
+	// TsigStatus returns the status of the Tsig. It is ErrKey if the key is
+	// not known, otherwise the result of TsigVerify.
 	TsigStatus() error
 	// TsigTimersOnly sets the tsig timers only boolean.
 	TsigTimersOnly(bool)
@@ -42,37 +44,41 @@ type ResponseWriter interface {
 	Hijack()
 }
 
+// Sig0Writer is implemented by the ResponseWriters that know the status of the
+// SIG(0) of the request, such as the one a Server passes to its Handler.
+// Handlers get it with a type assertion:
+//
+//	if sw, ok := w.(dns.Sig0Writer); ok && sw.Sig0Status() == nil {
+//		...
+//	}
+type Sig0Writer interface {
+	// Sig0Status returns the status of the SIG(0). It is ErrKey if the key is
+	// not known, otherwise the result of SIG.Verify.
+	Sig0Status() error
+}
+
+// A udpWriter writes UDP responses on behalf of a response, it is used to
+// batch the UDP writes of concurrent requests.
+type udpWriter interface {
+	WriteToSessionUDP(b []byte, session *SessionUDP) (int, error)
+}
+
 type response struct {
 	hijacked       bool // connection has been hijacked by handler
 	tsigStatus     error
 	tsigTimersOnly bool
 	tsigRequestMAC string
-	tsigSecret     map[string]string // the tsig secrets
-	udp            *net.UDPConn      // i/o connection if UDP was used
-	tcp            net.Conn          // i/o connection if TCP was used
-	udpSession     *SessionUDP       // oob data to get egress interface right
-	remoteAddr     net.Addr          // address of the client
-	writer         Writer            // writer to output the raw DNS bits
+	tsigRequest    *TSIG        // the TSIG of the request, if any
+	tsigProvider   TsigProvider // the tsig secrets
+	sig0Status     error
+	udp            *net.UDPConn // i/o connection if UDP was used
+	tcp            net.Conn     // i/o connection if TCP was used
+	udpSession     *SessionUDP  // oob data to get egress interface right
+	udpWriter      udpWriter    // batches UDP writes, nil if not batching
+	remoteAddr     net.Addr     // address of the client
+	writer         Writer       // writer to output the raw DNS bits
 }
 
-// ServeMux is an DNS request multiplexer. It matches the
-// zone name of each incoming request against a list of
-// registered patterns add calls the handler for the pattern
-// that most closely matches the zone name. ServeMux is DNSSEC aware, meaning
-// that queries for the DS record are redirected to the parent zone (if that
-// is also registered), otherwise the child gets the query.
-// ServeMux is also safe for concurrent access from multiple goroutines.
-type ServeMux struct {
-	z map[string]Handler
-	m *sync.RWMutex
-}
-
-// NewServeMux allocates and returns a new ServeMux.
-func NewServeMux() *ServeMux { return &ServeMux{z: make(map[string]Handler), m: new(sync.RWMutex)} }
-
-// DefaultServeMux is the default ServeMux used by Serve.
-var DefaultServeMux = NewServeMux()
-
 // The HandlerFunc type is an adapter to allow the use of
 // ordinary functions as DNS handlers.  If f is a function
 // with the appropriate signature, HandlerFunc(f) is a
@@ -132,99 +138,6 @@ func ActivateAndServe(l net.Listener, p net.PacketConn, handler Handler) error {
 	return server.ActivateAndServe()
 }
 
-func (mux *ServeMux) match(q string, t uint16) Handler {
-	mux.m.RLock()
-	defer mux.m.RUnlock()
-	var handler Handler
-	b := make([]byte, len(q)) // worst case, one label of length q
-	off := 0
-	end := false
-	for {
-		l := len(q[off:])
-		for i := 0; i < l; i++ {
-			b[i] = q[off+i]
-			if b[i] >= 'A' && b[i] <= 'Z' {
-				b[i] |= ('a' - 'A')
-			}
-		}
-		if h, ok := mux.z[string(b[:l])]; ok { // causes garbage, might want to change the map key
-			if t != TypeDS {
-				return h
-			}
-			// Continue for DS to see if we have a parent too, if so delegeate to the parent
-			handler = h
-		}
-		off, end = NextLabel(q, off)
-		if end {
-			break
-		}
-	}
-	// Wildcard match, if we have found nothing try the root zone as a last resort.
-	if h, ok := mux.z["."]; ok {
-		return h
-	}
-	return handler
-}
-
-// Handle adds a handler to the ServeMux for pattern.
-func (mux *ServeMux) Handle(pattern string, handler Handler) {
-	if pattern == "" {
-		panic("dns: invalid pattern " + pattern)
-	}
-	mux.m.Lock()
-	mux.z[Fqdn(pattern)] = handler
-	mux.m.Unlock()
-}
-
-// HandleFunc adds a handler function to the ServeMux for pattern.
-func (mux *ServeMux) HandleFunc(pattern string, handler func(ResponseWriter, *Msg)) {
-	mux.Handle(pattern, HandlerFunc(handler))
-}
-
-// HandleRemove deregistrars the handler specific for pattern from the ServeMux.
-func (mux *ServeMux) HandleRemove(pattern string) {
-	if pattern == "" {
-		panic("dns: invalid pattern " + pattern)
-	}
-	mux.m.Lock()
-	delete(mux.z, Fqdn(pattern))
-	mux.m.Unlock()
-}
-
-// ServeDNS dispatches the request to the handler whose
-// pattern most closely matches the request message. If DefaultServeMux
-// is used the correct thing for DS queries is done: a possible parent
-// is sought.
-// If no handler is found a standard SERVFAIL message is returned
-// If the request message does not have exactly one question in the
-// question section a SERVFAIL is returned, unlesss Unsafe is true.
-func (mux *ServeMux) ServeDNS(w ResponseWriter, request *Msg) {
-	var h Handler
-	if len(request.Question) < 1 { // allow more than one question
-		h = failedHandler()
-	} else {
-		if h = mux.match(request.Question[0].Name, request.Question[0].Qtype); h == nil {
-			h = failedHandler()
-		}
-	}
-	h.ServeDNS(w, request)
-}
-
-// Handle registers the handler with the given pattern
-// in the DefaultServeMux. The documentation for
-// ServeMux explains how patterns are matched.
-func Handle(pattern string, handler Handler) { DefaultServeMux.Handle(pattern, handler) }
-
-// HandleRemove deregisters the handle with the given pattern
-// in the DefaultServeMux.
-func HandleRemove(pattern string) { DefaultServeMux.HandleRemove(pattern) }
-
-// HandleFunc registers the handler function with the given pattern
-// in the DefaultServeMux.
-func HandleFunc(pattern string, handler func(ResponseWriter, *Msg)) {
-	DefaultServeMux.HandleFunc(pattern, handler)
-}
-
 // Writer writes raw DNS messages; each call to Write should send an entire message.
 type Writer interface {
 	io.Writer
@@ -287,6 +200,10 @@ type Server struct {
 	IdleTimeout func() time.Duration
 	// Secret(s) for Tsig map[<zonename>]<base64 secret>. The zonename must be in canonical form (lowercase, fqdn, see RFC 4034 Section 6.2).
 	TsigSecret map[string]string
+	// TsigProvider looks up Tsig secrets at runtime, it takes precedence over TsigSecret.
+	TsigProvider TsigProvider
+	// Sig0Provider looks up the KEY records to verify SIG(0) signed requests with, see Sig0Writer.
+	Sig0Provider Sig0KeyProvider
 	// Unsafe instructs the server to disregard any sanity checks and directly hand the message to
 	// the handler. It will specifically not check if the query has the QR bit not set.
 	Unsafe bool
@@ -296,10 +213,32 @@ type Server struct {
 	DecorateReader DecorateReader
 	// DecorateWriter is optional, allows customization of the process that writes raw DNS messages.
 	DecorateWriter DecorateWriter
+	// UDPBatchSize, if larger than one, makes the server read and write up to this many UDP
+	// messages with a single system call. On Linux this uses recvmmsg and sendmmsg, elsewhere
+	// messages are still read and written one at a time. It is ignored on Windows and if
+	// DecorateReader is set. The wire buffers of batched requests are reused, so EDNS0 options
+	// that alias them, such as EDNS0_PADDING, must be copied if retained after ServeDNS returns.
+	UDPBatchSize int
+	// Number of goroutines serving batched UDP requests, defaults to runtime.GOMAXPROCS(0).
+	UDPWorkers int
+	// ReusePort is the number of sockets ListenAndServe opens on Addr with SO_REUSEPORT, each
+	// served by its own loop, to let the kernel spread the load across them. Only supported on
+	// Linux, with Go 1.11 or later. If zero or one, a single socket is opened without SO_REUSEPORT.
+	ReusePort int
+	// ProxyProtocol, if not nil, enables the PROXY protocol for the trusted sources it lists.
+	// With ActivateAndServe the PROXY header is read from the accepted connections of Listener,
+	// so DNS over TLS requires ListenAndServe.
+	ProxyProtocol *ProxyProtocol
+	// TCPFastOpen, if positive, enables TCP Fast Open on the TCP listeners opened by
+	// ListenAndServe, with this maximum length of the queue of pending SYNs. Only supported
+	// on Linux, with Go 1.11 or later.
+	TCPFastOpen int
 
 	// Shutdown handling
-	lock    sync.RWMutex
-	started bool
+	lock        sync.RWMutex
+	started     bool
+	listeners   []net.Listener   // all the listeners opened by ListenAndServe
+	packetConns []net.PacketConn // all the packet conns opened by ListenAndServe
 }
 
 // ListenAndServe starts a nameserver on the configured address in *Server.
@@ -316,62 +255,113 @@ func (srv *Server) ListenAndServe() error {
 	if srv.UDPSize == 0 {
 		srv.UDPSize = MinMsgSize
 	}
+	n := srv.ReusePort
+	if n < 1 {
+		n = 1
+	}
 	switch srv.Net {
-	case "tcp", "tcp4", "tcp6":
-		a, err := net.ResolveTCPAddr(srv.Net, addr)
-		if err != nil {
-			return err
+	case "tcp", "tcp4", "tcp6", "tcp-tls", "tcp4-tls", "tcp6-tls":
+		network := strings.TrimSuffix(srv.Net, "-tls")
+		if network != srv.Net && !hasTLSCertificates(srv.TLSConfig) {
+			return &Error{err: "neither Certificates, GetCertificate, nor GetConfigForClient set in TLSConfig"}
 		}
-		l, err := net.ListenTCP(srv.Net, a)
-		if err != nil {
-			return err
+		ls := make([]net.Listener, 0, n)
+		for i := 0; i < n; i++ {
+			l, err := srv.listenTCP(network, addr)
+			if err != nil {
+				closeListeners(ls)
+				return err
+			}
+			if srv.ProxyProtocol != nil {
+				l = &proxyListener{Listener: l, p: srv.ProxyProtocol}
+			}
+			if network != srv.Net {
+				l = tls.NewListener(l, srv.TLSConfig)
+			}
+			ls = append(ls, l)
+			// Bind the remaining sockets to the same port if addr asked for any port.
+			addr = l.Addr().String()
 		}
-		srv.Listener = l
+		srv.Listener = ls[0]
+		srv.listeners = ls
 		srv.started = true
 		srv.lock.Unlock()
-		err = srv.serveTCP(l)
-		srv.lock.Lock() // to satisfy the defer at the top
-		return err
-	case "tcp-tls", "tcp4-tls", "tcp6-tls":
-		network := "tcp"
-		if srv.Net == "tcp4-tls" {
-			network = "tcp4"
-		} else if srv.Net == "tcp6-tls" {
-			network = "tcp6"
-		}
-
-		l, err := tls.Listen(network, addr, srv.TLSConfig)
-		if err != nil {
-			return err
+		if srv.NotifyStartedFunc != nil {
+			srv.NotifyStartedFunc()
 		}
-		srv.Listener = l
-		srv.started = true
-		srv.lock.Unlock()
-		err = srv.serveTCP(l)
+		err := srv.serveSockets(n, func(i int) error { return srv.serveTCP(ls[i]) }, func() { closeListeners(ls) })
 		srv.lock.Lock() // to satisfy the defer at the top
 		return err
 	case "udp", "udp4", "udp6":
-		a, err := net.ResolveUDPAddr(srv.Net, addr)
-		if err != nil {
-			return err
+		ls := make([]*net.UDPConn, 0, n)
+		closeAll := func() {
+			for _, l := range ls {
+				l.Close()
+			}
 		}
-		l, err := net.ListenUDP(srv.Net, a)
-		if err != nil {
-			return err
+		for i := 0; i < n; i++ {
+			l, err := srv.listenUDP(srv.Net, addr)
+			if err != nil {
+				closeAll()
+				return err
+			}
+			if e := setUDPSocketOptions(l); e != nil {
+				l.Close()
+				closeAll()
+				return e
+			}
+			ls = append(ls, l)
+			addr = l.LocalAddr().String()
 		}
-		if e := setUDPSocketOptions(l); e != nil {
-			return e
+		srv.PacketConn = ls[0]
+		srv.packetConns = make([]net.PacketConn, len(ls))
+		for i, l := range ls {
+			srv.packetConns[i] = l
 		}
-		srv.PacketConn = l
 		srv.started = true
 		srv.lock.Unlock()
-		err = srv.serveUDP(l)
+		if srv.NotifyStartedFunc != nil {
+			srv.NotifyStartedFunc()
+		}
+		err := srv.serveSockets(n, func(i int) error { return srv.serveUDP(ls[i]) }, closeAll)
 		srv.lock.Lock() // to satisfy the defer at the top
 		return err
 	}
 	return &Error{err: "bad network"}
 }
 
+// hasTLSCertificates mirrors the check done by tls.Listen.
+func hasTLSCertificates(c *tls.Config) bool {
+	return c != nil && (len(c.Certificates) > 0 || c.GetCertificate != nil || c.GetConfigForClient != nil)
+}
+
+func closeListeners(ls []net.Listener) {
+	for _, l := range ls {
+		l.Close()
+	}
+}
+
+// serveSockets runs serve for each of the n sockets, each in its own
+// goroutine, and waits for all of them to return. The first error is
+// returned, after closing all the sockets so that the others return too.
+func (srv *Server) serveSockets(n int, serve func(i int) error, closeAll func()) error {
+	if n == 1 {
+		return serve(0)
+	}
+	errs := make(chan error, n)
+	for i := 0; i < n; i++ {
+		go func(i int) { errs <- serve(i) }(i)
+	}
+	var err error
+	for i := 0; i < n; i++ {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+			closeAll()
+		}
+	}
+	return err
+}
+
 // ActivateAndServe starts a nameserver with the PacketConn or Listener
 // configured in *Server. Its main use is to start a server from systemd.
 func (srv *Server) ActivateAndServe() error {
@@ -394,14 +384,23 @@ func (srv *Server) ActivateAndServe() error {
 			}
 			srv.started = true
 			srv.lock.Unlock()
+			if srv.NotifyStartedFunc != nil {
+				srv.NotifyStartedFunc()
+			}
 			e := srv.serveUDP(t)
 			srv.lock.Lock() // to satisfy the defer at the top
 			return e
 		}
 	}
 	if l != nil {
+		if srv.ProxyProtocol != nil {
+			l = &proxyListener{Listener: l, p: srv.ProxyProtocol}
+		}
 		srv.started = true
 		srv.lock.Unlock()
+		if srv.NotifyStartedFunc != nil {
+			srv.NotifyStartedFunc()
+		}
 		e := srv.serveTCP(l)
 		srv.lock.Lock() // to satisfy the defer at the top
 		return e
@@ -418,6 +417,8 @@ func (srv *Server) Shutdown() error {
 		return &Error{err: "server not started"}
 	}
 	srv.started = false
+	listeners, packetConns := srv.listeners, srv.packetConns
+	srv.listeners, srv.packetConns = nil, nil
 	srv.lock.Unlock()
 
 	if srv.PacketConn != nil {
@@ -426,6 +427,12 @@ func (srv *Server) Shutdown() error {
 	if srv.Listener != nil {
 		srv.Listener.Close()
 	}
+	for _, l := range listeners {
+		l.Close()
+	}
+	for _, p := range packetConns {
+		p.Close()
+	}
 	return nil
 }
 
@@ -443,10 +450,6 @@ func (srv *Server) getReadTimeout() time.Duration {
 func (srv *Server) serveTCP(l net.Listener) error {
 	defer l.Close()
 
-	if srv.NotifyStartedFunc != nil {
-		srv.NotifyStartedFunc()
-	}
-
 	reader := Reader(&defaultReader{srv})
 	if srv.DecorateReader != nil {
 		reader = srv.DecorateReader(reader)
@@ -478,7 +481,7 @@ func (srv *Server) serveTCP(l net.Listener) error {
 				rw.Close()
 				return
 			}
-			srv.serve(rw.RemoteAddr(), handler, m, nil, nil, rw)
+			srv.serve(rw.RemoteAddr(), handler, m, nil, nil, rw, nil)
 		}()
 	}
 }
@@ -488,10 +491,6 @@ func (srv *Server) serveTCP(l net.Listener) error {
 func (srv *Server) serveUDP(l *net.UDPConn) error {
 	defer l.Close()
 
-	if srv.NotifyStartedFunc != nil {
-		srv.NotifyStartedFunc()
-	}
-
 	reader := Reader(&defaultReader{srv})
 	if srv.DecorateReader != nil {
 		reader = srv.DecorateReader(reader)
@@ -501,6 +500,9 @@ func (srv *Server) serveUDP(l *net.UDPConn) error {
 	if handler == nil {
 		handler = DefaultServeMux
 	}
+	if srv.UDPBatchSize > 1 && srv.DecorateReader == nil && udpBatchSupported {
+		return srv.serveUDPBatch(l, handler)
+	}
 	rtimeout := srv.getReadTimeout()
 	// deadline is not used here
 	for {
@@ -520,13 +522,19 @@ func (srv *Server) serveUDP(l *net.UDPConn) error {
 		if len(m) < headerSize {
 			continue
 		}
-		go srv.serve(s.RemoteAddr(), handler, m, l, s, nil)
+		a := s.RemoteAddr()
+		if srv.ProxyProtocol != nil {
+			if m, a, err = srv.ProxyProtocol.parseUDP(m, a); err != nil || len(m) < headerSize {
+				continue
+			}
+		}
+		go srv.serve(a, handler, m, l, s, nil, nil)
 	}
 }
 
 // Serve a new connection.
-func (srv *Server) serve(a net.Addr, h Handler, m []byte, u *net.UDPConn, s *SessionUDP, t net.Conn) {
-	w := &response{tsigSecret: srv.TsigSecret, udp: u, tcp: t, remoteAddr: a, udpSession: s}
+func (srv *Server) serve(a net.Addr, h Handler, m []byte, u *net.UDPConn, s *SessionUDP, t net.Conn, uw udpWriter) {
+	w := &response{tsigProvider: tsigProvider(srv.TsigProvider, srv.TsigSecret), udp: u, tcp: t, remoteAddr: a, udpSession: s, udpWriter: uw}
 	if srv.DecorateWriter != nil {
 		w.writer = srv.DecorateWriter(w)
 	} else {
@@ -552,16 +560,23 @@ Redo:
 		goto Exit
 	}
 
-	w.tsigStatus = nil
-	if w.tsigSecret != nil {
+	w.tsigStatus, w.tsigRequest = nil, nil
+	if w.tsigProvider != nil {
 		if t := req.IsTsig(); t != nil {
-			secret := t.Hdr.Name
-			if _, ok := w.tsigSecret[secret]; !ok {
-				w.tsigStatus = ErrKeyAlg
+			w.tsigRequest = t
+			if k, err := tsigLookup(w.tsigProvider, t); err != nil {
+				w.tsigStatus = ErrKey
+			} else {
+				w.tsigStatus = k.verify(m, "", false)
 			}
-			w.tsigStatus = TsigVerify(m, w.tsigSecret[secret], "", false)
 			w.tsigTimersOnly = false
-			w.tsigRequestMAC = req.Extra[len(req.Extra)-1].(*TSIG).MAC
+			w.tsigRequestMAC = t.MAC
+		}
+	}
+	w.sig0Status = nil
+	if srv.Sig0Provider != nil {
+		if sig := req.IsSig0(); sig != nil {
+			w.sig0Status = sig0Verify(srv.Sig0Provider, sig, m)
 		}
 	}
 	h.ServeDNS(w, req) // Writes back to the client
@@ -645,9 +660,24 @@ func (srv *Server) readUDP(conn *net.UDPConn, timeout time.Duration) ([]byte, *S
 // WriteMsg implements the ResponseWriter.WriteMsg method.
 func (w *response) WriteMsg(m *Msg) (err error) {
 	var data []byte
-	if w.tsigSecret != nil { // if no secrets, dont check for the tsig (which is a longer check)
+	if w.tsigProvider != nil { // if no secrets, dont check for the tsig (which is a longer check)
 		if t := m.IsTsig(); t != nil {
-			data, w.tsigRequestMAC, err = TsigGenerate(m, w.tsigSecret[t.Hdr.Name], w.tsigRequestMAC, w.tsigTimersOnly)
+			var k tsigKey
+			if w.tsigStatus != nil && w.tsigRequest != nil {
+				// The request failed to verify, reply with the error as
+				// described in RFC 8945 section 5.3.2.
+				k, _ = tsigLookup(w.tsigProvider, w.tsigRequest)
+				data, err = tsigErrorResponse(m, w.tsigRequest, w.tsigStatus, k, uint64(time.Now().Unix()))
+				if err != nil {
+					return err
+				}
+				_, err = w.writer.Write(data)
+				return err
+			}
+			if k, err = tsigLookup(w.tsigProvider, t); err != nil {
+				return err
+			}
+			data, w.tsigRequestMAC, err = k.generate(m, w.tsigRequestMAC, w.tsigTimersOnly)
 			if err != nil {
 				return err
 			}
@@ -667,6 +697,9 @@ func (w *response) WriteMsg(m *Msg) (err error) {
 func (w *response) Write(m []byte) (int, error) {
 	switch {
 	case w.udp != nil:
+		if w.udpWriter != nil {
+			return w.udpWriter.WriteToSessionUDP(m, w.udpSession)
+		}
 		n, err := WriteToSessionUDP(w.udp, m, w.udpSession)
 		return n, err
 	case w.tcp != nil:
@@ -701,6 +734,9 @@ func (w *response) RemoteAddr() net.Addr { return w.remoteAddr }
 // TsigStatus implements the ResponseWriter.TsigStatus method.
 func (w *response) TsigStatus() error { return w.tsigStatus }
 
+// Sig0Status implements the Sig0Writer.Sig0Status method.
+func (w *response) Sig0Status() error { return w.sig0Status }
+
 // TsigTimersOnly implements the ResponseWriter.TsigTimersOnly method.
 func (w *response) TsigTimersOnly(b bool) { w.tsigTimersOnly = b }
 
@@ -199,6 +199,83 @@ func TestServing(t *testing.T) {
 	}
 }
 
+func TestServingUDPBatch(t *testing.T) {
+	HandleFunc("miek.nl.", HelloServerEchoAddrPort)
+	defer HandleRemove("miek.nl.")
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	s := &Server{PacketConn: pc, UDPBatchSize: 16, UDPWorkers: 4, ReadTimeout: time.Hour, WriteTimeout: time.Hour}
+
+	waitLock := sync.Mutex{}
+	waitLock.Lock()
+	s.NotifyStartedFunc = waitLock.Unlock
+
+	fin := make(chan error, 1)
+	go func() {
+		fin <- s.ActivateAndServe()
+		pc.Close()
+	}()
+	waitLock.Lock()
+	addrstr := pc.LocalAddr().String()
+
+	const clients = 32
+	errs := make(chan error, clients)
+	for i := 0; i < clients; i++ {
+		go func() {
+			c := new(Client)
+			c.Net = "udp"
+			conn, err := c.Dial(addrstr)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer conn.Close()
+
+			for j := 0; j < 8; j++ {
+				m := new(Msg)
+				m.SetQuestion("miek.nl.", TypeTXT)
+				conn.SetDeadline(time.Now().Add(2 * time.Second))
+				if err := conn.WriteMsg(m); err != nil {
+					errs <- err
+					return
+				}
+				r, err := conn.ReadMsg()
+				if err != nil {
+					errs <- err
+					return
+				}
+				if len(r.Extra) == 0 || r.Id != m.Id {
+					errs <- fmt.Errorf("unexpected response: %v", r)
+					return
+				}
+				if txt := r.Extra[0].(*TXT).Txt[0]; txt != conn.LocalAddr().String() {
+					errs <- fmt.Errorf("remote address %s, want %s", txt, conn.LocalAddr())
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for i := 0; i < clients; i++ {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+
+	s.Shutdown()
+	select {
+	case err := <-fin:
+		if err != nil {
+			t.Errorf("error returned from ActivateAndServe, %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Error("could not shutdown test UDP server")
+	}
+}
+
 func TestServingTLS(t *testing.T) {
 	HandleFunc("miek.nl.", HelloServer)
 	HandleFunc("example.com.", AnotherHelloServer)
@@ -286,6 +363,64 @@ func TestServingListenAndServe(t *testing.T) {
 	server.Shutdown()
 }
 
+func TestServingReusePort(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("SO_REUSEPORT is only supported on Linux")
+	}
+	HandleFunc("example.com.", AnotherHelloServer)
+	defer HandleRemove("example.com.")
+
+	for _, network := range []string{"udp", "tcp"} {
+		waitLock := sync.Mutex{}
+		server := &Server{Addr: "127.0.0.1:0", Net: network, ReusePort: 4, ReadTimeout: time.Hour, WriteTimeout: time.Hour, NotifyStartedFunc: waitLock.Unlock}
+		if network == "tcp" {
+			server.TCPFastOpen = 16
+		}
+		waitLock.Lock()
+
+		fin := make(chan error, 1)
+		go func() {
+			fin <- server.ListenAndServe()
+		}()
+		waitLock.Lock()
+
+		var addr string
+		if network == "udp" {
+			if len(server.packetConns) != 4 {
+				t.Fatalf("%s: opened %d sockets, want 4", network, len(server.packetConns))
+			}
+			addr = server.PacketConn.LocalAddr().String()
+		} else {
+			if len(server.listeners) != 4 {
+				t.Fatalf("%s: opened %d listeners, want 4", network, len(server.listeners))
+			}
+			addr = server.Listener.Addr().String()
+		}
+
+		c, m := &Client{Net: network}, new(Msg)
+		for i := 0; i < 16; i++ {
+			m.SetQuestion("example.com.", TypeTXT)
+			r, _, err := c.Exchange(m, addr)
+			if err != nil {
+				t.Fatalf("%s: failed to exchange example.com: %v", network, err)
+			}
+			if txt := r.Extra[0].(*TXT).Txt[0]; txt != "Hello example" {
+				t.Errorf("%s: unexpected result for example.com %s != Hello example", network, txt)
+			}
+		}
+
+		server.Shutdown()
+		select {
+		case err := <-fin:
+			if err != nil {
+				t.Errorf("%s: error returned from ListenAndServe, %v", network, err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Errorf("%s: could not shutdown test server", network)
+		}
+	}
+}
+
 func TestServingListenAndServeTLS(t *testing.T) {
 	HandleFunc("example.com.", AnotherHelloServer)
 	defer HandleRemove("example.com.")
@@ -398,57 +533,6 @@ func BenchmarkServeCompress(b *testing.B) {
 	runtime.GOMAXPROCS(a)
 }
 
-func TestDotAsCatchAllWildcard(t *testing.T) {
-	mux := NewServeMux()
-	mux.Handle(".", HandlerFunc(HelloServer))
-	mux.Handle("example.com.", HandlerFunc(AnotherHelloServer))
-
-	handler := mux.match("www.miek.nl.", TypeTXT)
-	if handler == nil {
-		t.Error("wildcard match failed")
-	}
-
-	handler = mux.match("www.example.com.", TypeTXT)
-	if handler == nil {
-		t.Error("example.com match failed")
-	}
-
-	handler = mux.match("a.www.example.com.", TypeTXT)
-	if handler == nil {
-		t.Error("a.www.example.com match failed")
-	}
-
-	handler = mux.match("boe.", TypeTXT)
-	if handler == nil {
-		t.Error("boe. match failed")
-	}
-}
-
-func TestCaseFolding(t *testing.T) {
-	mux := NewServeMux()
-	mux.Handle("_udp.example.com.", HandlerFunc(HelloServer))
-
-	handler := mux.match("_dns._udp.example.com.", TypeSRV)
-	if handler == nil {
-		t.Error("case sensitive characters folded")
-	}
-
-	handler = mux.match("_DNS._UDP.EXAMPLE.COM.", TypeSRV)
-	if handler == nil {
-		t.Error("case insensitive characters not folded")
-	}
-}
-
-func TestRootServer(t *testing.T) {
-	mux := NewServeMux()
-	mux.Handle(".", HandlerFunc(HelloServer))
-
-	handler := mux.match(".", TypeNS)
-	if handler == nil {
-		t.Error("root match failed")
-	}
-}
-
 type maxRec struct {
 	max int
 	sync.RWMutex
@@ -11,6 +11,42 @@ import (
 	"time"
 )
 
+// A Sig0KeyProvider returns the KEY records used to verify SIG(0) signatures,
+// it can for instance look them up in a zone.
+type Sig0KeyProvider interface {
+	// Sig0Key returns the KEY of signer, in canonical form (lowercase,
+	// fqdn), with the given algorithm and key tag. If the key is not known it
+	// must return an error, ErrKey by convention.
+	Sig0Key(signer string, algorithm uint8, keyTag uint16) (*KEY, error)
+}
+
+// sig0Fudge is the validity, in seconds, before and after the current time
+// of the SIG(0) signatures made by Conn.
+const sig0Fudge = 300
+
+// sig0Sign signs m with the private key s of k.
+func sig0Sign(k *KEY, s crypto.Signer, m *Msg) ([]byte, error) {
+	now := uint32(time.Now().Unix())
+	sig := &SIG{RRSIG{
+		Algorithm:  k.Algorithm,
+		Expiration: now + sig0Fudge,
+		Inception:  now - sig0Fudge,
+		KeyTag:     k.KeyTag(),
+		SignerName: k.Hdr.Name,
+	}}
+	return sig.Sign(s, m)
+}
+
+// sig0Verify verifies the SIG(0) sig of the message buf with the key from p.
+// It returns ErrKey if the key is not known.
+func sig0Verify(p Sig0KeyProvider, sig *SIG, buf []byte) error {
+	k, err := p.Sig0Key(strings.ToLower(sig.SignerName), sig.Algorithm, sig.KeyTag)
+	if err != nil {
+		return ErrKey
+	}
+	return sig.Verify(k, buf)
+}
+
 // Sign signs a dns.Msg. It fills the signature with the appropriate data.
 // The SIG record should have the SignerName, KeyTag, Algorithm, Inception
 // and Expiration set.
@@ -2,6 +2,8 @@ package dns
 
 import (
 	"crypto"
+	"net"
+	"sync"
 	"testing"
 	"time"
 )
@@ -87,3 +89,86 @@ func TestSIG0(t *testing.T) {
 		}
 	}
 }
+
+// testSig0Keys finds keys by owner name only.
+type testSig0Keys []*KEY
+
+func (ks testSig0Keys) Sig0Key(signer string, algorithm uint8, keyTag uint16) (*KEY, error) {
+	for _, k := range ks {
+		if k.Hdr.Name == signer && k.Algorithm == algorithm && k.KeyTag() == keyTag {
+			return k, nil
+		}
+	}
+	return nil, ErrKey
+}
+
+func newSig0Key(t *testing.T, name string) (*KEY, crypto.Signer) {
+	k := &KEY{DNSKEY{Hdr: RR_Header{Name: name, Rrtype: TypeKEY, Class: ClassINET}, Algorithm: ECDSAP256SHA256, Protocol: 3}}
+	pk, err := k.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k, pk.(crypto.Signer)
+}
+
+func TestServingSIG0(t *testing.T) {
+	clientKey, clientSigner := newSig0Key(t, "client.example.org.")
+	serverKey, serverSigner := newSig0Key(t, "server.example.org.")
+
+	status := make(chan error, 1)
+	HandleFunc("example.org.", func(w ResponseWriter, r *Msg) {
+		status <- w.(Sig0Writer).Sig0Status()
+		m := new(Msg)
+		m.SetReply(r)
+		buf, err := sig0Sign(serverKey, serverSigner, m)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		w.Write(buf)
+	})
+	defer HandleRemove("example.org.")
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	s := &Server{PacketConn: pc, Sig0Provider: testSig0Keys{clientKey}}
+	waitLock := sync.Mutex{}
+	waitLock.Lock()
+	s.NotifyStartedFunc = waitLock.Unlock
+	go s.ActivateAndServe()
+	waitLock.Lock()
+	defer s.Shutdown()
+	addr := pc.LocalAddr().String()
+
+	c := &Client{Sig0Key: clientKey, Sig0Signer: clientSigner, Sig0Provider: testSig0Keys{serverKey}}
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeSOA)
+	r, _, err := c.Exchange(m, addr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if err := <-status; err != nil {
+		t.Errorf("server SIG(0) status: %v", err)
+	}
+	if r.IsSig0() == nil {
+		t.Error("response not signed")
+	}
+
+	// A key unknown to the server.
+	c.Sig0Key, c.Sig0Signer = newSig0Key(t, "unknown.example.org.")
+	if _, _, err := c.Exchange(m, addr); err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if err := <-status; err != ErrKey {
+		t.Errorf("server SIG(0) status for an unknown key: %v, want %v", err, ErrKey)
+	}
+
+	// A response signed by a key unknown to the client.
+	c.Sig0Provider = testSig0Keys{clientKey}
+	if _, _, err := c.Exchange(m, addr); err != ErrKey {
+		t.Errorf("client error for an unknown key: %v, want %v", err, ErrKey)
+	}
+	<-status
+}
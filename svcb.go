@@ -0,0 +1,799 @@
+package dns
+
+// Service binding records, SVCB and HTTPS, RFC 9460.
+
+import (
+	"encoding/binary"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SVCBKey is the type of the keys used in the SVCB RR.
+type SVCBKey uint16
+
+// Keys defined in RFC 9460 section 14.3.2.
+const (
+	SVCB_MANDATORY SVCBKey = iota
+	SVCB_ALPN
+	SVCB_NO_DEFAULT_ALPN
+	SVCB_PORT
+	SVCB_IPV4HINT
+	SVCB_ECHCONFIG
+	SVCB_IPV6HINT
+
+	svcb_RESERVED SVCBKey = 65535
+)
+
+var svcbKeyToStringMap = map[SVCBKey]string{
+	SVCB_MANDATORY:       "mandatory",
+	SVCB_ALPN:            "alpn",
+	SVCB_NO_DEFAULT_ALPN: "no-default-alpn",
+	SVCB_PORT:            "port",
+	SVCB_IPV4HINT:        "ipv4hint",
+	SVCB_ECHCONFIG:       "ech",
+	SVCB_IPV6HINT:        "ipv6hint",
+}
+
+var svcbStringToKeyMap = reverseSVCBKeyMap(svcbKeyToStringMap)
+
+func reverseSVCBKeyMap(m map[SVCBKey]string) map[string]SVCBKey {
+	n := make(map[string]SVCBKey, len(m))
+	for u, s := range m {
+		n[s] = u
+	}
+	return n
+}
+
+// String takes the numerical code of an SVCB key and returns its name.
+// Returns an empty string for reserved keys.
+// Accepts unassigned keys as well as experimental/private keys.
+func (key SVCBKey) String() string {
+	if x := svcbKeyToStringMap[key]; x != "" {
+		return x
+	}
+	if key == svcb_RESERVED {
+		return ""
+	}
+	return "key" + strconv.FormatUint(uint64(key), 10)
+}
+
+// svcbStringToKey returns the numerical code of an SVCB key, the name of a
+// key or keyNNNNN. Returns svcb_RESERVED for reserved or invalid keys.
+func svcbStringToKey(s string) SVCBKey {
+	if strings.HasPrefix(s, "key") {
+		a, err := strconv.ParseUint(s[3:], 10, 16)
+		// No leading zeros, RFC 9460 section 2.1.
+		if err != nil || a == 65535 || len(s) > 4 && s[3] == '0' {
+			return svcb_RESERVED
+		}
+		return SVCBKey(a)
+	}
+	if key, ok := svcbStringToKeyMap[s]; ok {
+		return key
+	}
+	return svcb_RESERVED
+}
+
+// SVCB RR. See RFC 9460.
+type SVCB struct {
+	Hdr      RR_Header
+	Priority uint16         // If zero, Value must be empty or discarded by the user of this library
+	Target   string         `dns:"domain-name"`
+	Value    []SVCBKeyValue `dns:"pairs"`
+}
+
+func (rr *SVCB) String() string {
+	s := rr.Hdr.String() +
+		strconv.Itoa(int(rr.Priority)) + " " +
+		sprintName(rr.Target)
+	for _, e := range rr.Value {
+		s += " " + e.Key().String()
+		v := e.String()
+		switch {
+		case v == "":
+		case strings.ContainsAny(v, " ;()"):
+			s += `="` + v + `"`
+		default:
+			s += "=" + v
+		}
+	}
+	return s
+}
+
+// HTTPS RR. See RFC 9460. Everything valid for SVCB applies to HTTPS as
+// well. Except that the HTTPS record is intended for use with the HTTP
+// and HTTPS protocols.
+type HTTPS struct {
+	SVCB
+}
+
+func (rr *HTTPS) String() string { return rr.SVCB.String() }
+
+// The generated copy of HTTPS would return the embedded SVCB.
+func (rr *HTTPS) copy() RR { return &HTTPS{*rr.SVCB.copy().(*SVCB)} }
+
+// SVCBKeyValue defines a key=value pair for the SVCB RR type.
+// An SVCB RR can have multiple SVCBKeyValues appended to it.
+type SVCBKeyValue interface {
+	// Key returns the numerical key code.
+	Key() SVCBKey
+	// pack returns the encoded value.
+	pack() ([]byte, error)
+	// unpack sets the data as found in the value. Is also responsible for
+	// validation.
+	unpack([]byte) error
+	// String returns the value in presentation format, with the escapes of
+	// a character-string but without quotes.
+	String() string
+	// parse sets the value from its presentation format.
+	parse(string) error
+	// copy returns a deep copy of the pair.
+	copy() SVCBKeyValue
+	// len returns the length of the value in wire format.
+	len() int
+}
+
+// makeSVCBKeyValue returns an empty pair for key, nil if key is reserved.
+func makeSVCBKeyValue(key SVCBKey) SVCBKeyValue {
+	switch key {
+	case SVCB_MANDATORY:
+		return new(SVCBMandatory)
+	case SVCB_ALPN:
+		return new(SVCBAlpn)
+	case SVCB_NO_DEFAULT_ALPN:
+		return new(SVCBNoDefaultAlpn)
+	case SVCB_PORT:
+		return new(SVCBPort)
+	case SVCB_IPV4HINT:
+		return new(SVCBIPv4Hint)
+	case SVCB_ECHCONFIG:
+		return new(SVCBECHConfig)
+	case SVCB_IPV6HINT:
+		return new(SVCBIPv6Hint)
+	case svcb_RESERVED:
+		return nil
+	}
+	e := new(SVCBLocal)
+	e.KeyCode = key
+	return e
+}
+
+// checkSVCB checks the pairs of an SVCB record, sorted by key: keys must not
+// repeat, and the keys listed by mandatory must be present, RFC 9460 section
+// 8.
+func checkSVCB(pairs []SVCBKeyValue) error {
+	for i := 1; i < len(pairs); i++ {
+		if pairs[i-1].Key() == pairs[i].Key() {
+			return &Error{err: "SVCB key " + pairs[i].Key().String() + " repeated"}
+		}
+	}
+	for _, e := range pairs {
+		m, ok := e.(*SVCBMandatory)
+		if !ok {
+			continue
+		}
+		seen := make(map[SVCBKey]bool)
+		for _, k := range m.Code {
+			if k == SVCB_MANDATORY {
+				return &Error{err: "SVCB mandatory key lists itself"}
+			}
+			if seen[k] {
+				return &Error{err: "SVCB mandatory key " + k.String() + " repeated"}
+			}
+			seen[k] = true
+			i := sort.Search(len(pairs), func(i int) bool { return pairs[i].Key() >= k })
+			if i == len(pairs) || pairs[i].Key() != k {
+				return &Error{err: "SVCB mandatory key " + k.String() + " missing"}
+			}
+		}
+	}
+	return nil
+}
+
+// sortSVCB returns a copy of pairs sorted by key.
+func sortSVCB(pairs []SVCBKeyValue) []SVCBKeyValue {
+	sorted := append([]SVCBKeyValue(nil), pairs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Key() < sorted[j].Key() })
+	return sorted
+}
+
+// packDataSVCB packs pairs in the order of their keys, RFC 9460 section 2.2.
+func packDataSVCB(pairs []SVCBKeyValue, msg []byte, off int) (int, error) {
+	pairs = sortSVCB(pairs)
+	if err := checkSVCB(pairs); err != nil {
+		return len(msg), err
+	}
+	for _, el := range pairs {
+		b, err := el.pack()
+		if err != nil {
+			return len(msg), err
+		}
+		if off+4+len(b) > len(msg) {
+			return len(msg), &Error{err: "overflow packing SVCB"}
+		}
+		binary.BigEndian.PutUint16(msg[off:], uint16(el.Key()))
+		binary.BigEndian.PutUint16(msg[off+2:], uint16(len(b)))
+		off += 4
+		off += copy(msg[off:], b)
+	}
+	return off, nil
+}
+
+// unpackDataSVCB unpacks the pairs in msg[off:end], which must be in strictly
+// increasing order of their keys.
+func unpackDataSVCB(msg []byte, off, end int) ([]SVCBKeyValue, int, error) {
+	if end > len(msg) {
+		return nil, len(msg), &Error{err: "overflow unpacking SVCB"}
+	}
+	var xs []SVCBKeyValue
+	for off < end {
+		if off+4 > end {
+			return nil, len(msg), &Error{err: "overflow unpacking SVCB"}
+		}
+		key := SVCBKey(binary.BigEndian.Uint16(msg[off:]))
+		length := int(binary.BigEndian.Uint16(msg[off+2:]))
+		off += 4
+		if off+length > end {
+			return nil, len(msg), &Error{err: "overflow unpacking SVCB"}
+		}
+		if len(xs) > 0 && key <= xs[len(xs)-1].Key() {
+			return nil, len(msg), &Error{err: "SVCB keys not in strictly increasing order"}
+		}
+		e := makeSVCBKeyValue(key)
+		if e == nil {
+			return nil, len(msg), &Error{err: "bad SVCB key"}
+		}
+		if err := e.unpack(msg[off : off+length]); err != nil {
+			return nil, len(msg), err
+		}
+		xs = append(xs, e)
+		off += length
+	}
+	if err := checkSVCB(xs); err != nil {
+		return nil, len(msg), err
+	}
+	return xs, off, nil
+}
+
+// svcbUnescape returns the octets of the character-string s, with its
+// escapes resolved.
+func svcbUnescape(s string) []byte {
+	src := []byte(s)
+	dst := make([]byte, 0, len(src))
+	for i := 0; i < len(src); {
+		b, n := nextByte(src, i)
+		if n == 0 {
+			break // dangling back slash
+		}
+		dst = append(dst, b)
+		i += n
+	}
+	return dst
+}
+
+// svcbEscape returns b as a character-string, with the octets that can't be
+// used as is escaped.
+func svcbEscape(b []byte) string {
+	dst := make([]byte, 0, len(b))
+	for _, c := range b {
+		dst = appendTXTStringByte(dst, c)
+	}
+	return string(dst)
+}
+
+// SVCBMandatory pair lists the keys that a client must understand to use the
+// record, RFC 9460 section 8. The keys must be present in the record, they
+// must not repeat and must not include mandatory itself; the record fails to
+// pack otherwise. "port" and "no-default-alpn" are automatically mandatory,
+// so they shouldn't be listed.
+//
+// Basic use pattern for creating a mandatory option:
+//
+//	s := &dns.SVCB{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeSVCB, Class: dns.ClassINET}}
+//	e := new(dns.SVCBMandatory)
+//	e.Code = []dns.SVCBKey{dns.SVCB_ALPN}
+//	s.Value = append(s.Value, e)
+//	t := new(dns.SVCBAlpn)
+//	t.Alpn = []string{"xmpp-client"}
+//	s.Value = append(s.Value, t)
+type SVCBMandatory struct {
+	Code []SVCBKey
+}
+
+func (*SVCBMandatory) Key() SVCBKey { return SVCB_MANDATORY }
+
+func (s *SVCBMandatory) String() string {
+	str := make([]string, len(s.Code))
+	for i, e := range s.Code {
+		str[i] = e.String()
+	}
+	return strings.Join(str, ",")
+}
+
+func (s *SVCBMandatory) pack() ([]byte, error) {
+	if len(s.Code) == 0 {
+		return nil, &Error{err: "SVCB mandatory without keys"}
+	}
+	codes := append([]SVCBKey(nil), s.Code...)
+	sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+	b := make([]byte, 2*len(codes))
+	for i, e := range codes {
+		binary.BigEndian.PutUint16(b[2*i:], uint16(e))
+	}
+	return b, nil
+}
+
+func (s *SVCBMandatory) unpack(b []byte) error {
+	if len(b) == 0 || len(b)%2 != 0 {
+		return &Error{err: "bad SVCB mandatory"}
+	}
+	codes := make([]SVCBKey, 0, len(b)/2)
+	for i := 0; i < len(b); i += 2 {
+		k := SVCBKey(binary.BigEndian.Uint16(b[i:]))
+		if len(codes) > 0 && k <= codes[len(codes)-1] {
+			return &Error{err: "SVCB mandatory keys not in strictly increasing order"}
+		}
+		codes = append(codes, k)
+	}
+	s.Code = codes
+	return nil
+}
+
+func (s *SVCBMandatory) parse(b string) error {
+	if b == "" {
+		return &Error{err: "SVCB mandatory without keys"}
+	}
+	str := strings.Split(string(svcbUnescape(b)), ",")
+	codes := make([]SVCBKey, 0, len(str))
+	for _, e := range str {
+		k := svcbStringToKey(e)
+		if k == svcb_RESERVED {
+			return &Error{err: "bad SVCB mandatory key " + e}
+		}
+		codes = append(codes, k)
+	}
+	s.Code = codes
+	return nil
+}
+
+func (s *SVCBMandatory) len() int { return 2 * len(s.Code) }
+
+func (s *SVCBMandatory) copy() SVCBKeyValue {
+	return &SVCBMandatory{append([]SVCBKey(nil), s.Code...)}
+}
+
+// SVCBAlpn pair is used to list supported connection protocols, at least
+// one. Protocol IDs can be found at:
+// https://www.iana.org/assignments/tls-extensiontype-values/tls-extensiontype-values.xhtml#alpn-protocol-ids
+// Basic use pattern for creating an alpn option:
+//
+//	h := new(dns.HTTPS)
+//	h.Hdr = dns.RR_Header{Name: ".", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET}
+//	e := new(dns.SVCBAlpn)
+//	e.Alpn = []string{"h2", "http/1.1"}
+//	h.Value = append(h.Value, e)
+type SVCBAlpn struct {
+	Alpn []string
+}
+
+func (*SVCBAlpn) Key() SVCBKey { return SVCB_ALPN }
+
+// String returns the protocols as a comma separated value-list, RFC 9460
+// appendix A.1, in which commas and backslashes are escaped.
+func (s *SVCBAlpn) String() string {
+	var b []byte
+	for i, e := range s.Alpn {
+		if i > 0 {
+			b = append(b, ',')
+		}
+		for j := 0; j < len(e); j++ {
+			if e[j] == ',' || e[j] == '\\' {
+				b = append(b, '\\')
+			}
+			b = append(b, e[j])
+		}
+	}
+	return svcbEscape(b)
+}
+
+func (s *SVCBAlpn) pack() ([]byte, error) {
+	if len(s.Alpn) == 0 {
+		return nil, &Error{err: "SVCB alpn without protocols"}
+	}
+	b := make([]byte, 0, s.len())
+	for _, e := range s.Alpn {
+		if e == "" || len(e) > 255 {
+			return nil, &Error{err: "bad SVCB alpn protocol"}
+		}
+		b = append(b, byte(len(e)))
+		b = append(b, e...)
+	}
+	return b, nil
+}
+
+func (s *SVCBAlpn) unpack(b []byte) error {
+	if len(b) == 0 {
+		return &Error{err: "SVCB alpn without protocols"}
+	}
+	var alpn []string
+	for i := 0; i < len(b); {
+		length := int(b[i])
+		i++
+		if length == 0 || i+length > len(b) {
+			return &Error{err: "bad SVCB alpn protocol"}
+		}
+		alpn = append(alpn, string(b[i:i+length]))
+		i += length
+	}
+	s.Alpn = alpn
+	return nil
+}
+
+// parse splits the value-list b, RFC 9460 appendix A.1, on the commas that are
+// not escaped.
+func (s *SVCBAlpn) parse(b string) error {
+	v := svcbUnescape(b)
+	if len(v) == 0 {
+		return &Error{err: "SVCB alpn without protocols"}
+	}
+	var (
+		alpn []string
+		e    []byte
+	)
+	for i := 0; i < len(v); i++ {
+		switch v[i] {
+		case ',':
+			if len(e) == 0 {
+				return &Error{err: "bad SVCB alpn protocol"}
+			}
+			alpn = append(alpn, string(e))
+			e = e[:0]
+			continue
+		case '\\':
+			if i+1 == len(v) {
+				return &Error{err: "bad SVCB alpn escape"}
+			}
+			i++
+		}
+		e = append(e, v[i])
+	}
+	if len(e) == 0 {
+		return &Error{err: "bad SVCB alpn protocol"}
+	}
+	s.Alpn = append(alpn, string(e))
+	return nil
+}
+
+func (s *SVCBAlpn) len() int {
+	var l int
+	for _, e := range s.Alpn {
+		l += 1 + len(e)
+	}
+	return l
+}
+
+func (s *SVCBAlpn) copy() SVCBKeyValue {
+	return &SVCBAlpn{append([]string(nil), s.Alpn...)}
+}
+
+// SVCBNoDefaultAlpn pair signifies no support for default connection
+// protocols. Should be used in conjunction with alpn.
+// Basic use pattern for creating a no-default-alpn option:
+//
+//	s := &dns.SVCB{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeSVCB, Class: dns.ClassINET}}
+//	t := new(dns.SVCBAlpn)
+//	t.Alpn = []string{"xmpp-client"}
+//	s.Value = append(s.Value, t)
+//	e := new(dns.SVCBNoDefaultAlpn)
+//	s.Value = append(s.Value, e)
+type SVCBNoDefaultAlpn struct{}
+
+func (*SVCBNoDefaultAlpn) Key() SVCBKey          { return SVCB_NO_DEFAULT_ALPN }
+func (*SVCBNoDefaultAlpn) copy() SVCBKeyValue    { return &SVCBNoDefaultAlpn{} }
+func (*SVCBNoDefaultAlpn) pack() ([]byte, error) { return []byte{}, nil }
+func (*SVCBNoDefaultAlpn) String() string        { return "" }
+func (*SVCBNoDefaultAlpn) len() int              { return 0 }
+
+func (*SVCBNoDefaultAlpn) unpack(b []byte) error {
+	if len(b) != 0 {
+		return &Error{err: "bad SVCB no-default-alpn: value provided"}
+	}
+	return nil
+}
+
+func (*SVCBNoDefaultAlpn) parse(b string) error {
+	if b != "" {
+		return &Error{err: "bad SVCB no-default-alpn: value provided"}
+	}
+	return nil
+}
+
+// SVCBPort pair defines the port for connection.
+// Basic use pattern for creating a port option:
+//
+//	s := &dns.SVCB{Hdr: dns.RR_Header{Name: ".", Rrtype: dns.TypeSVCB, Class: dns.ClassINET}}
+//	e := new(dns.SVCBPort)
+//	e.Port = 80
+//	s.Value = append(s.Value, e)
+type SVCBPort struct {
+	Port uint16
+}
+
+func (*SVCBPort) Key() SVCBKey         { return SVCB_PORT }
+func (*SVCBPort) len() int             { return 2 }
+func (s *SVCBPort) String() string     { return strconv.FormatUint(uint64(s.Port), 10) }
+func (s *SVCBPort) copy() SVCBKeyValue { return &SVCBPort{s.Port} }
+
+func (s *SVCBPort) unpack(b []byte) error {
+	if len(b) != 2 {
+		return &Error{err: "bad SVCB port"}
+	}
+	s.Port = binary.BigEndian.Uint16(b)
+	return nil
+}
+
+func (s *SVCBPort) pack() ([]byte, error) {
+	b := make([]byte, 2)
+	binary.BigEndian.PutUint16(b, s.Port)
+	return b, nil
+}
+
+func (s *SVCBPort) parse(b string) error {
+	port, err := strconv.ParseUint(string(svcbUnescape(b)), 10, 16)
+	if err != nil {
+		return &Error{err: "bad SVCB port"}
+	}
+	s.Port = uint16(port)
+	return nil
+}
+
+// SVCBIPv4Hint pair suggests an IPv4 address which may be used to open
+// connections if A and AAAA record responses for SVCB's Target domain haven't
+// been received. In that case, optionally, A and AAAA requests can be made,
+// after which the connection to the hinted IP address may be terminated and a
+// new connection may be opened.
+// Basic use pattern for creating an ipv4hint option:
+//
+//	h := new(dns.HTTPS)
+//	h.Hdr = dns.RR_Header{Name: ".", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET}
+//	e := new(dns.SVCBIPv4Hint)
+//	e.Hint = []net.IP{net.IPv4(1,1,1,1).To4()}
+//
+//	Or
+//
+//	e.Hint = []net.IP{net.ParseIP("1.1.1.1").To4()}
+//	h.Value = append(h.Value, e)
+type SVCBIPv4Hint struct {
+	Hint []net.IP
+}
+
+func (*SVCBIPv4Hint) Key() SVCBKey { return SVCB_IPV4HINT }
+func (s *SVCBIPv4Hint) len() int   { return 4 * len(s.Hint) }
+
+func (s *SVCBIPv4Hint) pack() ([]byte, error) {
+	if len(s.Hint) == 0 {
+		return nil, &Error{err: "SVCB ipv4hint without addresses"}
+	}
+	b := make([]byte, 0, 4*len(s.Hint))
+	for _, e := range s.Hint {
+		x := e.To4()
+		if x == nil {
+			return nil, &Error{err: "bad SVCB ipv4hint: expected ipv4, hint is ipv6"}
+		}
+		b = append(b, x...)
+	}
+	return b, nil
+}
+
+func (s *SVCBIPv4Hint) unpack(b []byte) error {
+	if len(b) == 0 || len(b)%4 != 0 {
+		return &Error{err: "bad SVCB ipv4hint"}
+	}
+	x := make([]net.IP, 0, len(b)/4)
+	for i := 0; i < len(b); i += 4 {
+		x = append(x, net.IP(append([]byte(nil), b[i:i+4]...)))
+	}
+	s.Hint = x
+	return nil
+}
+
+func (s *SVCBIPv4Hint) String() string {
+	str := make([]string, len(s.Hint))
+	for i, e := range s.Hint {
+		x := e.To4()
+		if x == nil {
+			return "<nil>"
+		}
+		str[i] = x.String()
+	}
+	return strings.Join(str, ",")
+}
+
+func (s *SVCBIPv4Hint) parse(b string) error {
+	v := string(svcbUnescape(b))
+	if v == "" {
+		return &Error{err: "SVCB ipv4hint without addresses"}
+	}
+	if strings.Contains(v, ":") {
+		return &Error{err: "bad SVCB ipv4hint: expected ipv4, got ipv6"}
+	}
+	str := strings.Split(v, ",")
+	dst := make([]net.IP, 0, len(str))
+	for _, e := range str {
+		ip := net.ParseIP(e).To4()
+		if ip == nil {
+			return &Error{err: "bad SVCB ipv4hint " + e}
+		}
+		dst = append(dst, ip)
+	}
+	s.Hint = dst
+	return nil
+}
+
+func (s *SVCBIPv4Hint) copy() SVCBKeyValue {
+	hint := make([]net.IP, len(s.Hint))
+	for i, ip := range s.Hint {
+		hint[i] = copyIP(ip)
+	}
+	return &SVCBIPv4Hint{Hint: hint}
+}
+
+// SVCBECHConfig pair contains the ECHConfigList of TLS Encrypted Client Hello.
+// Basic use pattern for creating an ech option:
+//
+//	h := new(dns.HTTPS)
+//	h.Hdr = dns.RR_Header{Name: ".", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET}
+//	e := new(dns.SVCBECHConfig)
+//	e.ECH = []byte{0xfe, 0x08, ...}
+//	h.Value = append(h.Value, e)
+type SVCBECHConfig struct {
+	ECH []byte // Specifically ECHConfigList including the redundant length prefix
+}
+
+func (*SVCBECHConfig) Key() SVCBKey     { return SVCB_ECHCONFIG }
+func (s *SVCBECHConfig) String() string { return toBase64(s.ECH) }
+func (s *SVCBECHConfig) len() int       { return len(s.ECH) }
+
+func (s *SVCBECHConfig) pack() ([]byte, error) {
+	return append([]byte(nil), s.ECH...), nil
+}
+
+func (s *SVCBECHConfig) copy() SVCBKeyValue {
+	return &SVCBECHConfig{append([]byte(nil), s.ECH...)}
+}
+
+func (s *SVCBECHConfig) unpack(b []byte) error {
+	s.ECH = append([]byte(nil), b...)
+	return nil
+}
+
+func (s *SVCBECHConfig) parse(b string) error {
+	x, err := fromBase64(svcbUnescape(b))
+	if err != nil {
+		return &Error{err: "bad SVCB ech"}
+	}
+	s.ECH = x
+	return nil
+}
+
+// SVCBIPv6Hint pair suggests an IPv6 address which may be used to open
+// connections if A and AAAA record responses for SVCB's Target domain haven't
+// been received. In that case, optionally, A and AAAA requests can be made,
+// after which the connection to the hinted IP address may be terminated and a
+// new connection may be opened.
+// Basic use pattern for creating an ipv6hint option:
+//
+//	h := new(dns.HTTPS)
+//	h.Hdr = dns.RR_Header{Name: ".", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET}
+//	e := new(dns.SVCBIPv6Hint)
+//	e.Hint = []net.IP{net.ParseIP("2001:db8::1")}
+//	h.Value = append(h.Value, e)
+type SVCBIPv6Hint struct {
+	Hint []net.IP
+}
+
+func (*SVCBIPv6Hint) Key() SVCBKey { return SVCB_IPV6HINT }
+func (s *SVCBIPv6Hint) len() int   { return 16 * len(s.Hint) }
+
+func (s *SVCBIPv6Hint) pack() ([]byte, error) {
+	if len(s.Hint) == 0 {
+		return nil, &Error{err: "SVCB ipv6hint without addresses"}
+	}
+	b := make([]byte, 0, 16*len(s.Hint))
+	for _, e := range s.Hint {
+		if len(e) != net.IPv6len || e.To4() != nil {
+			return nil, &Error{err: "bad SVCB ipv6hint: expected ipv6, hint is ipv4"}
+		}
+		b = append(b, e...)
+	}
+	return b, nil
+}
+
+func (s *SVCBIPv6Hint) unpack(b []byte) error {
+	if len(b) == 0 || len(b)%16 != 0 {
+		return &Error{err: "bad SVCB ipv6hint"}
+	}
+	x := make([]net.IP, 0, len(b)/16)
+	for i := 0; i < len(b); i += 16 {
+		ip := net.IP(append([]byte(nil), b[i:i+16]...))
+		if ip.To4() != nil {
+			return &Error{err: "bad SVCB ipv6hint: expected ipv6, got ipv4"}
+		}
+		x = append(x, ip)
+	}
+	s.Hint = x
+	return nil
+}
+
+func (s *SVCBIPv6Hint) String() string {
+	str := make([]string, len(s.Hint))
+	for i, e := range s.Hint {
+		if x := e.To4(); x != nil {
+			return "<nil>"
+		}
+		str[i] = e.String()
+	}
+	return strings.Join(str, ",")
+}
+
+func (s *SVCBIPv6Hint) parse(b string) error {
+	v := string(svcbUnescape(b))
+	if v == "" {
+		return &Error{err: "SVCB ipv6hint without addresses"}
+	}
+	str := strings.Split(v, ",")
+	dst := make([]net.IP, 0, len(str))
+	for _, e := range str {
+		ip := net.ParseIP(e)
+		if ip == nil || !strings.Contains(e, ":") || ip.To4() != nil {
+			return &Error{err: "bad SVCB ipv6hint " + e}
+		}
+		dst = append(dst, ip)
+	}
+	s.Hint = dst
+	return nil
+}
+
+func (s *SVCBIPv6Hint) copy() SVCBKeyValue {
+	hint := make([]net.IP, len(s.Hint))
+	for i, ip := range s.Hint {
+		hint[i] = copyIP(ip)
+	}
+	return &SVCBIPv6Hint{Hint: hint}
+}
+
+// SVCBLocal pair holds the value of a key this package doesn't know, in
+// presentation format keyNNNNN. Keys 65280 to 65534 are for private use.
+// Basic use pattern for creating a keyNNNNN option:
+//
+//	h := new(dns.HTTPS)
+//	h.Hdr = dns.RR_Header{Name: ".", Rrtype: dns.TypeHTTPS, Class: dns.ClassINET}
+//	e := new(dns.SVCBLocal)
+//	e.KeyCode = 65400
+//	e.Data = []byte("abc")
+//	h.Value = append(h.Value, e)
+type SVCBLocal struct {
+	KeyCode SVCBKey // Never 65535 or any assigned keys.
+	Data    []byte  // All byte sequences are allowed.
+}
+
+func (s *SVCBLocal) Key() SVCBKey          { return s.KeyCode }
+func (s *SVCBLocal) String() string        { return svcbEscape(s.Data) }
+func (s *SVCBLocal) pack() ([]byte, error) { return append([]byte(nil), s.Data...), nil }
+func (s *SVCBLocal) len() int              { return len(s.Data) }
+
+func (s *SVCBLocal) unpack(b []byte) error {
+	s.Data = append([]byte(nil), b...)
+	return nil
+}
+
+func (s *SVCBLocal) parse(b string) error {
+	s.Data = svcbUnescape(b)
+	return nil
+}
+
+func (s *SVCBLocal) copy() SVCBKeyValue {
+	return &SVCBLocal{s.KeyCode, append([]byte(nil), s.Data...)}
+}
@@ -0,0 +1,196 @@
+package dns
+
+import (
+	"encoding/hex"
+	"net"
+	"testing"
+)
+
+const (
+	svcbFooCom = "03666f6f076578616d706c6503636f6d00" // foo.example.com.
+	svcbFooOrg = "03666f6f076578616d706c65036f726700" // foo.example.org.
+)
+
+// The test vectors of RFC 9460 appendix D, with the rdata they pack to and
+// how they are printed.
+var svcbVectors = []struct {
+	in, rdata, out string
+}{
+	{`example.com. HTTPS 0 foo.example.com.`, "0000" + svcbFooCom,
+		"example.com.\t3600\tIN\tHTTPS\t0 foo.example.com."},
+	{`example.com. SVCB 1 .`, "000100",
+		"example.com.\t3600\tIN\tSVCB\t1 ."},
+	{`example.com. SVCB 16 foo.example.com. port=53`, "0010" + svcbFooCom + "000300020035",
+		"example.com.\t3600\tIN\tSVCB\t16 foo.example.com. port=53"},
+	{`example.com. SVCB 1 foo.example.com. key667=hello`, "0001" + svcbFooCom + "029b000568656c6c6f",
+		"example.com.\t3600\tIN\tSVCB\t1 foo.example.com. key667=hello"},
+	{`example.com. SVCB 1 foo.example.com. key667="hello\210qoo"`, "0001" + svcbFooCom + "029b000968656c6c6fd2716f6f",
+		"example.com.\t3600\tIN\tSVCB\t1 foo.example.com. key667=hello\\210qoo"},
+	{`example.com. SVCB 1 foo.example.com. ipv6hint="2001:db8::1,2001:db8::53:1"`,
+		"0001" + svcbFooCom + "00060020" + "20010db8000000000000000000000001" + "20010db8000000000000000000530001",
+		"example.com.\t3600\tIN\tSVCB\t1 foo.example.com. ipv6hint=2001:db8::1,2001:db8::53:1"},
+	{`example.com. SVCB 1 example.com. ipv6hint="2001:db8:122:344::192.0.2.33"`,
+		"0001" + "076578616d706c6503636f6d00" + "00060010" + "20010db80122034400000000c0000221",
+		"example.com.\t3600\tIN\tSVCB\t1 example.com. ipv6hint=2001:db8:122:344::c000:221"},
+	{`example.com. SVCB 16 foo.example.org. ( alpn=h2,h3-19 mandatory=ipv4hint,alpn
+		ipv4hint=192.0.2.1 )`,
+		"0010" + svcbFooOrg + "0000000400010004" + "00010009026832056833" + "2d3139" + "00040004c0000201",
+		"example.com.\t3600\tIN\tSVCB\t16 foo.example.org. alpn=h2,h3-19 mandatory=ipv4hint,alpn ipv4hint=192.0.2.1"},
+	{`example.com. SVCB 16 foo.example.org. alpn="f\\\\oo\\,bar,h2"`, "0010" + svcbFooOrg + "0001000c08665c6f6f2c626172026832",
+		"example.com.\t3600\tIN\tSVCB\t16 foo.example.org. alpn=f\\\\\\\\oo\\\\,bar,h2"},
+	{`example.com. SVCB 16 foo.example.org. alpn=f\\\092oo\092,bar,h2`, "0010" + svcbFooOrg + "0001000c08665c6f6f2c626172026832",
+		"example.com.\t3600\tIN\tSVCB\t16 foo.example.org. alpn=f\\\\\\\\oo\\\\,bar,h2"},
+}
+
+func TestSVCB(t *testing.T) {
+	for _, tc := range svcbVectors {
+		rr, err := NewRR(tc.in)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", tc.in, err)
+			continue
+		}
+		if rr.String() != tc.out {
+			t.Errorf("got %q, want %q", rr.String(), tc.out)
+		}
+
+		buf := make([]byte, 512)
+		off, err := PackRR(rr, buf, 0, nil, false)
+		if err != nil {
+			t.Errorf("failed to pack %q: %v", tc.in, err)
+			continue
+		}
+		if off > rr.len() {
+			t.Errorf("%q: packed %d octets, len is %d", tc.in, off, rr.len())
+		}
+		rdata := hex.EncodeToString(buf[off-int(rr.Header().Rdlength) : off])
+		if rdata != tc.rdata {
+			t.Errorf("%q: got rdata %s, want %s", tc.in, rdata, tc.rdata)
+		}
+
+		// Unpacked, the pairs are in the order of their keys.
+		rr1, _, err := UnpackRR(buf[:off], 0)
+		if err != nil {
+			t.Errorf("failed to unpack %q: %v", tc.in, err)
+			continue
+		}
+		rr2, err := NewRR(rr1.String())
+		if err != nil {
+			t.Errorf("failed to parse %q again: %v", rr1.String(), err)
+			continue
+		}
+		off, err = PackRR(rr2, buf, 0, nil, false)
+		if err != nil || hex.EncodeToString(buf[off-int(rr2.Header().Rdlength):off]) != tc.rdata || rr2.String() != rr1.String() {
+			t.Errorf("%q: unpacked %q", tc.in, rr2.String())
+		}
+	}
+}
+
+func TestSVCBParseErrors(t *testing.T) {
+	// The failure cases of RFC 9460 appendix D.3, and some more.
+	for _, s := range []string{
+		`example.com. SVCB 1 foo.example.com. ( key123=abc key123=def )`,
+		`example.com. SVCB 1 foo.example.com. mandatory`,
+		`example.com. SVCB 1 foo.example.com. alpn`,
+		`example.com. SVCB 1 foo.example.com. port`,
+		`example.com. SVCB 1 foo.example.com. ipv4hint`,
+		`example.com. SVCB 1 foo.example.com. ipv6hint`,
+		`example.com. SVCB 1 foo.example.com. no-default-alpn=abc`,
+		`example.com. SVCB 1 foo.example.com. mandatory=key123`,
+		`example.com. SVCB 1 foo.example.com. mandatory=mandatory`,
+		`example.com. SVCB 1 foo.example.com. ( mandatory=key123,key123 key123=abc )`,
+		`example.com. SVCB 1 foo.example.com. key65535=abc`,
+		`example.com. SVCB 1 foo.example.com. key0123=abc`,
+		`example.com. SVCB 1 foo.example.com. foo=abc`,
+		`example.com. SVCB 1 foo.example.com. port=123456`,
+		`example.com. SVCB 1 foo.example.com. ipv4hint=2001:db8::1`,
+		`example.com. SVCB 1 foo.example.com. ipv6hint=192.0.2.1`,
+		`example.com. SVCB 1 foo.example.com. alpn=h2,,h3`,
+		`example.com. SVCB 1 foo.example.com. alpn="h2"h3`,
+		`example.com. HTTPS 1 foo.example.com. port="53`,
+	} {
+		if rr, err := NewRR(s); err == nil {
+			t.Errorf("parsed %q as %q", s, rr)
+		}
+	}
+
+	for _, s := range []string{
+		`example.com. SVCB 1 foo.example.com. key1=h2 key0=key1 no-default-alpn`,
+		`example.com. HTTPS 1 foo.example.com. no-default-alpn="" key65534 key667=""`,
+		`example.com. HTTPS 1 foo.example.com. alpn="h2 with space" ech=AEj+DQBEAQAgACA=`,
+	} {
+		rr, err := NewRR(s)
+		if err != nil {
+			t.Errorf("failed to parse %q: %v", s, err)
+			continue
+		}
+		if rr2, err := NewRR(rr.String()); err != nil || rr2.String() != rr.String() {
+			t.Errorf("failed to parse %q again: %v", rr, err)
+		}
+	}
+}
+
+func TestSVCBWire(t *testing.T) {
+	rr := &SVCB{Hdr: RR_Header{Name: "example.com.", Rrtype: TypeSVCB, Class: ClassINET}, Priority: 1, Target: "."}
+	pack := func() ([]byte, error) {
+		buf := make([]byte, 512)
+		off, err := PackRR(rr, buf, 0, nil, false)
+		return buf[:off], err
+	}
+
+	// Pairs are packed in the order of their keys.
+	rr.Value = []SVCBKeyValue{&SVCBPort{Port: 853}, &SVCBMandatory{Code: []SVCBKey{SVCB_PORT, SVCB_ALPN}}, &SVCBAlpn{Alpn: []string{"dot"}}}
+	buf, err := pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x := hex.EncodeToString(buf[len(buf)-int(rr.Hdr.Rdlength):]); x != "000100"+"00000004"+"00010003"+"00010004"+"03646f74"+"00030002"+"0355" {
+		t.Errorf("got rdata %s", x)
+	}
+	if rr.Value[0].Key() != SVCB_PORT {
+		t.Errorf("packing reordered the pairs of the record")
+	}
+
+	// Keys out of order on the wire: port before alpn.
+	bad := append([]byte(nil), buf...)
+	copy(bad[len(bad)-14:], buf[len(buf)-6:])
+	copy(bad[len(bad)-8:], buf[len(buf)-14:len(buf)-6])
+	if _, _, err := UnpackRR(bad, 0); err == nil || err.Error() != "dns: SVCB keys not in strictly increasing order" {
+		t.Errorf("unpacked a record with keys out of order: %v", err)
+	}
+	// A mandatory key that is missing: port is cut off.
+	bad = append([]byte(nil), buf[:len(buf)-6]...)
+	bad[22] -= 6 // rdlength
+	if _, _, err := UnpackRR(bad, 0); err == nil || err.Error() != "dns: SVCB mandatory key port missing" {
+		t.Errorf("unpacked a record without a mandatory key: %v", err)
+	}
+
+	for _, pairs := range [][]SVCBKeyValue{
+		{&SVCBPort{Port: 53}, &SVCBPort{Port: 853}},
+		{&SVCBMandatory{Code: []SVCBKey{SVCB_ALPN}}},
+		{&SVCBMandatory{Code: []SVCBKey{SVCB_MANDATORY}}},
+		{&SVCBMandatory{}},
+		{&SVCBAlpn{}},
+		{&SVCBAlpn{Alpn: []string{""}}},
+		{&SVCBIPv4Hint{Hint: []net.IP{net.ParseIP("2001:db8::1")}}},
+		{&SVCBIPv6Hint{Hint: []net.IP{net.ParseIP("192.0.2.1")}}},
+	} {
+		rr.Value = pairs
+		if _, err := pack(); err == nil {
+			t.Errorf("packed %s", rr)
+		}
+	}
+}
+
+func TestCopyHTTPS(t *testing.T) {
+	rr := testRR(`example.com. HTTPS 1 . alpn=h2 ipv4hint=192.0.2.1 key65400=abc`).(*HTTPS)
+	c, ok := Copy(rr).(*HTTPS)
+	if !ok {
+		t.Fatalf("copy is a %T", Copy(rr))
+	}
+	c.Value[0].(*SVCBAlpn).Alpn[0] = "h3"
+	c.Value[1].(*SVCBIPv4Hint).Hint[0][3] = 2
+	c.Value[2].(*SVCBLocal).Data[0] = 'x'
+	if rr.String() != "example.com.\t3600\tIN\tHTTPS\t1 . alpn=h2 ipv4hint=192.0.2.1 key65400=abc" {
+		t.Errorf("changing the copy changed %s", rr)
+	}
+}
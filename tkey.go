@@ -0,0 +1,407 @@
+package dns
+
+// GSS-TSIG, RFC 3645, and the TKEY negotiation of its keys, RFC 2930.
+
+import (
+	crand "crypto/rand"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TKEY modes, see RFC 2930 section 2.5.
+const (
+	TkeyModeServer   = 1 // server assignment
+	TkeyModeDH       = 2 // Diffie-Hellman exchange
+	TkeyModeGSS      = 3 // GSS-API negotiation
+	TkeyModeResolver = 4 // resolver assignment
+	TkeyModeDelete   = 5 // key deletion
+)
+
+// GssTsig is the TSIG algorithm of the keys negotiated with GSS-API.
+const GssTsig = "gss-tsig."
+
+// A GSSContext is a GSS-API security context, RFC 2743, for instance one of
+// the Kerberos 5 mechanism. Once established, it must be safe for concurrent
+// use by multiple goroutines.
+type GSSContext interface {
+	// Step processes the token received from the peer, which is nil on the
+	// first call for an initiator, and returns the token to send to the peer,
+	// if any. Established is true once the context is complete.
+	Step(token []byte) (output []byte, established bool, err error)
+	// GetMIC returns the message integrity code of msg.
+	GetMIC(msg []byte) ([]byte, error)
+	// VerifyMIC returns an error if mic is not the message integrity code of msg.
+	VerifyMIC(msg, mic []byte) error
+}
+
+// A GSSMechanism creates GSS-API security contexts.
+type GSSMechanism interface {
+	// Initiator returns a new context to be established with the server
+	// named target, e.g. "DNS/ns1.example.org" for Kerberos.
+	Initiator(target string) (GSSContext, error)
+	// Acceptor returns a new context to accept one initiated by a client.
+	Acceptor() (GSSContext, error)
+}
+
+// GSSKeyring holds the GSS-TSIG keys negotiated with TKEY. It is a
+// TsigProvider, set it as the TsigProvider of a Client, Conn, Transfer or
+// Server to sign and verify messages with the gss-tsig algorithm.
+//
+// On the client side Negotiate establishes a key with a server. On the
+// server side the keyring is also a Handler that answers TKEY queries. Route
+// these to it for the zones the key names are in, or for the root zone if no
+// other zone has them, e.g.
+//
+//	k := &dns.GSSKeyring{Mechanism: mech}
+//	dns.HandleRoute(".", dns.Route{Qtype: dns.TypeTKEY}, k)
+//	srv := &dns.Server{Addr: ":53", Net: "tcp", TsigProvider: k}
+type GSSKeyring struct {
+	// Mechanism creates the contexts of the keys.
+	Mechanism GSSMechanism
+	// Fallback provides the secrets of the keys that do not use gss-tsig,
+	// it may be nil.
+	Fallback TsigProvider
+	// Lifetime is the lifetime of the keys accepted by ServeDNS, it
+	// defaults to one hour.
+	Lifetime time.Duration
+
+	m    sync.Mutex
+	keys map[string]*gssEntry // lower cased key name
+}
+
+// gssEntry is a context being negotiated or, once established, a key.
+type gssEntry struct {
+	ctx         GSSContext
+	established bool
+	expire      time.Time
+}
+
+// TsigSecret implements the TsigProvider interface, it returns the secrets
+// of Fallback.
+func (k *GSSKeyring) TsigSecret(name, algorithm string) (string, error) {
+	if k.Fallback == nil {
+		return "", ErrSecret
+	}
+	return k.Fallback.TsigSecret(name, algorithm)
+}
+
+// Remove removes the key name from the keyring.
+func (k *GSSKeyring) Remove(name string) {
+	k.m.Lock()
+	delete(k.keys, strings.ToLower(Fqdn(name)))
+	k.m.Unlock()
+}
+
+func (k *GSSKeyring) lifetime() time.Duration {
+	if k.Lifetime != 0 {
+		return k.Lifetime
+	}
+	return time.Hour
+}
+
+// key returns the established, unexpired, key name.
+func (k *GSSKeyring) key(name string) (tsigKey, error) {
+	k.m.Lock()
+	defer k.m.Unlock()
+	e, ok := k.keys[name]
+	if !ok || !e.established || time.Now().After(e.expire) {
+		return nil, ErrSecret
+	}
+	return gssKey{e.ctx}, nil
+}
+
+// add stores the context ctx as key name.
+func (k *GSSKeyring) add(name string, e *gssEntry) {
+	k.m.Lock()
+	if k.keys == nil {
+		k.keys = make(map[string]*gssEntry)
+	}
+	k.keys[name] = e
+	k.m.Unlock()
+}
+
+// Negotiate establishes a new key with the server at address, which is
+// named target in the GSS-API mechanism, with the TKEY exchange of RFC 3645.
+// The exchange is done over a single connection made with c, which should
+// use TCP. The key is added to the keyring and its name is returned, use it
+// with SetTsig and the GssTsig algorithm.
+func (k *GSSKeyring) Negotiate(c *Client, address, target string) (string, error) {
+	ctx, err := k.Mechanism.Initiator(target)
+	if err != nil {
+		return "", err
+	}
+	name, err := gssKeyName(target)
+	if err != nil {
+		return "", err
+	}
+	co, err := c.Dial(address)
+	if err != nil {
+		return "", err
+	}
+	defer co.Close()
+
+	var (
+		token []byte
+		p     []byte
+		r     *TKEY
+	)
+	for {
+		out, established, err := ctx.Step(token)
+		if err != nil {
+			return "", err
+		}
+		if len(out) == 0 {
+			if !established || p == nil {
+				return "", &Error{err: "GSS context not established"}
+			}
+			break
+		}
+		if p, r, err = tkeyExchange(c, co, name, out); err != nil {
+			return "", err
+		}
+		if token, err = hex.DecodeString(r.Key); err != nil {
+			return "", err
+		}
+		if established {
+			break
+		}
+	}
+
+	// The server signs the response once it has established its context.
+	m := new(Msg)
+	if err := m.Unpack(p); err != nil {
+		return "", err
+	}
+	if m.IsTsig() == nil {
+		return "", ErrNoSig
+	}
+	if err := (gssKey{ctx}).verify(p, "", false); err != nil {
+		return "", err
+	}
+
+	e := &gssEntry{ctx: ctx, established: true, expire: time.Now().Add(k.lifetime())}
+	if r.Expiration != 0 {
+		e.expire = time.Unix(int64(r.Expiration), 0)
+	}
+	k.add(name, e)
+	return name, nil
+}
+
+// tkeyExchange sends token to the server and returns the response, as
+// read from the wire, and its TKEY.
+func tkeyExchange(c *Client, co *Conn, name string, token []byte) ([]byte, *TKEY, error) {
+	now := uint32(time.Now().Unix())
+	m := new(Msg)
+	m.SetQuestion(name, TypeTKEY)
+	m.Question[0].Qclass = ClassANY
+	m.Extra = append(m.Extra, &TKEY{
+		Hdr:        RR_Header{Name: name, Rrtype: TypeTKEY, Class: ClassANY},
+		Algorithm:  GssTsig,
+		Inception:  now,
+		Expiration: now + 86400,
+		Mode:       TkeyModeGSS,
+		KeySize:    uint16(len(token)),
+		Key:        hex.EncodeToString(token),
+	})
+
+	co.SetWriteDeadline(time.Now().Add(c.getTimeoutForRequest(c.writeTimeout())))
+	if err := co.WriteMsg(m); err != nil {
+		return nil, nil, err
+	}
+	co.SetReadDeadline(time.Now().Add(c.getTimeoutForRequest(c.readTimeout())))
+	p, err := co.ReadMsgHeader(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := new(Msg)
+	if err := r.Unpack(p); err != nil {
+		return nil, nil, err
+	}
+	if r.Id != m.Id {
+		return nil, nil, ErrId
+	}
+	if r.Rcode != RcodeSuccess {
+		return nil, nil, &Error{err: "TKEY failed with rcode " + RcodeToString[r.Rcode]}
+	}
+	for _, rr := range r.Answer {
+		if t, ok := rr.(*TKEY); ok && strings.EqualFold(t.Hdr.Name, name) {
+			if t.Error != RcodeSuccess {
+				return nil, nil, &Error{err: "TKEY failed with error " + RcodeToString[int(t.Error)]}
+			}
+			return p, t, nil
+		}
+	}
+	return nil, nil, &Error{err: "no TKEY in response"}
+}
+
+// gssKeyName returns a new random key name for a key with target, as
+// suggested by RFC 3645 section 3.1.1.
+func gssKeyName(target string) (string, error) {
+	host := target
+	if i := strings.IndexByte(host, '/'); i >= 0 {
+		host = host[i+1:]
+	}
+	if i := strings.IndexByte(host, '@'); i >= 0 {
+		host = host[:i]
+	}
+	b := make([]byte, 8)
+	if _, err := crand.Read(b); err != nil {
+		return "", err
+	}
+	name := strings.ToLower(Fqdn(hex.EncodeToString(b) + ".sig-" + host))
+	if _, ok := IsDomainName(name); !ok {
+		return "", &Error{err: "bad GSS target " + target}
+	}
+	return name, nil
+}
+
+// ServeDNS implements the Handler interface, it answers the TKEY queries
+// that negotiate GSS-TSIG keys and delete them.
+func (k *GSSKeyring) ServeDNS(w ResponseWriter, r *Msg) {
+	m := new(Msg)
+	m.SetReply(r)
+
+	// The TKEY is in the additional section, but accept it in the answer too.
+	var req *TKEY
+	for _, section := range [][]RR{r.Extra, r.Answer} {
+		for _, rr := range section {
+			if t, ok := rr.(*TKEY); ok && req == nil {
+				req = t
+			}
+		}
+	}
+	if len(r.Question) != 1 || r.Question[0].Qtype != TypeTKEY || req == nil ||
+		!strings.EqualFold(req.Hdr.Name, r.Question[0].Name) {
+		m.SetRcode(r, RcodeFormatError)
+		w.WriteMsg(m)
+		return
+	}
+
+	name := strings.ToLower(req.Hdr.Name)
+	now := time.Now()
+	rep := &TKEY{
+		Hdr:        RR_Header{Name: req.Hdr.Name, Rrtype: TypeTKEY, Class: ClassANY},
+		Algorithm:  req.Algorithm,
+		Inception:  req.Inception,
+		Expiration: req.Expiration,
+		Mode:       req.Mode,
+	}
+	m.Answer = append(m.Answer, rep)
+
+	switch {
+	case req.Mode == TkeyModeDelete:
+		// Only the holder of the key may delete it, RFC 2930 section 4.2.
+		t := r.IsTsig()
+		if t == nil || w.TsigStatus() != nil || !strings.EqualFold(t.Hdr.Name, name) {
+			rep.Error = RcodeBadKey
+			break
+		}
+		// The response is still signed with the key.
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, now.Unix())
+		w.WriteMsg(m)
+		k.Remove(name)
+		return
+	case req.Mode != TkeyModeGSS:
+		rep.Error = RcodeBadMode
+	case !strings.EqualFold(req.Algorithm, GssTsig):
+		rep.Error = RcodeBadAlg
+	default:
+		token, err := hex.DecodeString(req.Key)
+		if err != nil {
+			rep.Error = RcodeBadKey
+			break
+		}
+		out, established, rcode := k.accept(name, token, now)
+		if rcode != RcodeSuccess {
+			rep.Error = uint16(rcode)
+			break
+		}
+		rep.Key = hex.EncodeToString(out)
+		rep.KeySize = uint16(len(out))
+		if established {
+			rep.Inception = uint32(now.Unix())
+			rep.Expiration = uint32(now.Add(k.lifetime()).Unix())
+			// Sign the response with the new key, RFC 3645 section 4.1.3.
+			m.SetTsig(req.Hdr.Name, GssTsig, 300, now.Unix())
+		}
+	}
+	w.WriteMsg(m)
+}
+
+// accept feeds token to the context being negotiated as name, creating it
+// if needed. It returns the token for the client, whether the context has
+// been established and the TKEY error.
+func (k *GSSKeyring) accept(name string, token []byte, now time.Time) ([]byte, bool, int) {
+	k.m.Lock()
+	defer k.m.Unlock()
+	if k.keys == nil {
+		k.keys = make(map[string]*gssEntry)
+	}
+	for n, e := range k.keys {
+		if now.After(e.expire) {
+			delete(k.keys, n)
+		}
+	}
+
+	e, ok := k.keys[name]
+	if ok && e.established {
+		return nil, false, RcodeBadName
+	}
+	if !ok {
+		ctx, err := k.Mechanism.Acceptor()
+		if err != nil {
+			return nil, false, RcodeBadKey
+		}
+		// A negotiation that is not completed within the lifetime is abandoned.
+		e = &gssEntry{ctx: ctx, expire: now.Add(k.lifetime())}
+		k.keys[name] = e
+	}
+	out, established, err := e.ctx.Step(token)
+	if err != nil {
+		delete(k.keys, name)
+		return nil, false, RcodeBadKey
+	}
+	if established {
+		e.established = true
+		e.expire = now.Add(k.lifetime())
+	}
+	return out, established, RcodeSuccess
+}
+
+// gssKey is a tsigKey that signs with an established GSS-API context.
+type gssKey struct {
+	ctx GSSContext
+}
+
+func (k gssKey) generate(m *Msg, requestMAC string, timersOnly bool) ([]byte, string, error) {
+	if m.IsTsig() == nil {
+		panic("dns: TSIG not last RR in additional")
+	}
+	return tsigGenerate(m, requestMAC, timersOnly, func(buf []byte, rr *TSIG) ([]byte, error) {
+		return k.ctx.GetMIC(buf)
+	})
+}
+
+func (k gssKey) verify(msg []byte, requestMAC string, timersOnly bool) error {
+	stripped, tsig, err := stripTsig(msg)
+	if err != nil {
+		return err
+	}
+	if tsig.Error != RcodeSuccess && tsig.MACSize == 0 {
+		return tsigRcodeError(tsig.Error)
+	}
+	mic, err := hex.DecodeString(tsig.MAC)
+	if err != nil {
+		return err
+	}
+	buf := tsigBuffer(stripped, tsig, requestMAC, timersOnly)
+	if err := k.ctx.VerifyMIC(buf, mic); err != nil {
+		return ErrSig
+	}
+	if tsig.Error != RcodeSuccess {
+		return tsigRcodeError(tsig.Error)
+	}
+	return tsigCheckTime(tsig, uint64(time.Now().Unix()))
+}
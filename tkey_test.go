@@ -0,0 +1,161 @@
+package dns
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGSS is a GSS-API mechanism without any security: the initiator sends a
+// nonce, the acceptor answers with its own and both use an HMAC keyed with
+// the two nonces as the MIC.
+type fakeGSS struct{}
+
+func (fakeGSS) Initiator(target string) (GSSContext, error) {
+	if target != "DNS/ns.example.org" {
+		return nil, errors.New("unknown target")
+	}
+	return &fakeGSSContext{initiator: true}, nil
+}
+
+func (fakeGSS) Acceptor() (GSSContext, error) { return &fakeGSSContext{}, nil }
+
+type fakeGSSContext struct {
+	initiator bool
+	nonce     []byte
+	key       []byte
+}
+
+func (c *fakeGSSContext) Step(token []byte) ([]byte, bool, error) {
+	switch {
+	case c.initiator && c.nonce == nil:
+		c.nonce = []byte("client-nonce")
+		return c.nonce, false, nil
+	case c.initiator:
+		c.key = append(append([]byte(nil), c.nonce...), token...)
+		return nil, true, nil
+	case !bytes.HasSuffix(token, []byte("nonce")):
+		return nil, false, errors.New("bad token")
+	}
+	c.nonce = []byte("server-nonce")
+	c.key = append(append([]byte(nil), token...), c.nonce...)
+	return c.nonce, true, nil
+}
+
+func (c *fakeGSSContext) GetMIC(msg []byte) ([]byte, error) {
+	h := hmac.New(sha256.New, c.key)
+	h.Write(msg)
+	return h.Sum(nil), nil
+}
+
+func (c *fakeGSSContext) VerifyMIC(msg, mic []byte) error {
+	m, _ := c.GetMIC(msg)
+	if !hmac.Equal(m, mic) {
+		return errors.New("bad MIC")
+	}
+	return nil
+}
+
+func TestGSSTsig(t *testing.T) {
+	server := &GSSKeyring{Mechanism: fakeGSS{}}
+	mux := NewServeMux()
+	mux.HandleRoute("example.org.", Route{Qtype: TypeTKEY}, server)
+	status := make(chan error, 1)
+	mux.HandleFunc("example.org.", func(w ResponseWriter, r *Msg) {
+		status <- w.TsigStatus()
+		m := new(Msg)
+		m.SetReply(r)
+		t := r.IsTsig()
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, time.Now().Unix())
+		w.WriteMsg(m)
+	})
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	s := &Server{Listener: l, Handler: mux, TsigProvider: server}
+	waitLock := sync.Mutex{}
+	waitLock.Lock()
+	s.NotifyStartedFunc = waitLock.Unlock
+	go s.ActivateAndServe()
+	waitLock.Lock()
+	defer s.Shutdown()
+	addr := l.Addr().String()
+
+	client := &GSSKeyring{Mechanism: fakeGSS{}}
+	c := &Client{Net: "tcp", TsigProvider: client}
+	name, err := client.Negotiate(c, addr, "DNS/ns.example.org")
+	if err != nil {
+		t.Fatalf("failed to negotiate: %v", err)
+	}
+
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeSOA)
+	m.SetTsig(name, GssTsig, 300, time.Now().Unix())
+	r, _, err := c.Exchange(m, addr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if err := <-status; err != nil {
+		t.Errorf("server TSIG status: %v", err)
+	}
+	if r.IsTsig() == nil || r.IsTsig().Algorithm != GssTsig {
+		t.Errorf("response not signed with %s", GssTsig)
+	}
+
+	// Negotiating the same name again is refused.
+	co, err := c.Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tkeyExchange(c, co, name, []byte("client-nonce")); err == nil {
+		t.Error("expected an error for a duplicate key name")
+	}
+	// So is a token the mechanism does not accept.
+	if _, _, err := tkeyExchange(c, co, "other."+name, []byte("garbage")); err == nil {
+		t.Error("expected an error for a bad token")
+	}
+	co.Close()
+
+	// Delete the key, after which it is unknown to the server.
+	m = new(Msg)
+	m.SetQuestion(name, TypeTKEY)
+	m.Question[0].Qclass = ClassANY
+	m.Extra = append(m.Extra, &TKEY{Hdr: RR_Header{Name: name, Rrtype: TypeTKEY, Class: ClassANY}, Algorithm: GssTsig, Mode: TkeyModeDelete})
+	m.SetTsig(name, GssTsig, 300, time.Now().Unix())
+	if r, _, err = c.Exchange(m, addr); err != nil {
+		t.Fatalf("failed to delete the key: %v", err)
+	}
+	if tkey, ok := r.Answer[0].(*TKEY); !ok || tkey.Error != RcodeSuccess {
+		t.Errorf("expected the key to be deleted, got %v", r.Answer[0])
+	}
+
+	m = new(Msg)
+	m.SetQuestion("example.org.", TypeSOA)
+	m.SetTsig(name, GssTsig, 300, time.Now().Unix())
+	if _, _, err := c.Exchange(m, addr); err != ErrKey {
+		t.Errorf("expected %v after deleting the key, got %v", ErrKey, err)
+	}
+	if err := <-status; err != ErrKey {
+		t.Errorf("server TSIG status after deleting the key: %v, want %v", err, ErrKey)
+	}
+}
+
+func TestGSSKeyName(t *testing.T) {
+	for _, target := range []string{"DNS/ns1.example.org", "DNS/ns1.example.org@EXAMPLE.ORG", "ns1.example.org."} {
+		name, err := gssKeyName(target)
+		if err != nil {
+			t.Errorf("%s: %v", target, err)
+			continue
+		}
+		if !IsSubDomain("example.org.", name) {
+			t.Errorf("%s: got key name %s", target, name)
+		}
+	}
+}
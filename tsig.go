@@ -8,6 +8,7 @@ import (
 	"crypto/sha512"
 	"encoding/binary"
 	"encoding/hex"
+	"fmt"
 	"hash"
 	"strconv"
 	"strings"
@@ -18,10 +19,134 @@ import (
 const (
 	HmacMD5    = "hmac-md5.sig-alg.reg.int."
 	HmacSHA1   = "hmac-sha1."
+	HmacSHA224 = "hmac-sha224."
 	HmacSHA256 = "hmac-sha256."
+	HmacSHA384 = "hmac-sha384."
 	HmacSHA512 = "hmac-sha512."
+
+	// Truncated HMACs, see RFC 8945 section 6. Other truncations can be
+	// used by appending the MAC length in bits to the name of the HMAC,
+	// e.g. "hmac-sha1-80.".
+	HmacSHA256_128 = "hmac-sha256-128."
+	HmacSHA384_192 = "hmac-sha384-192."
+	HmacSHA512_256 = "hmac-sha512-256."
 )
 
+var tsigHashes = map[string]func() hash.Hash{
+	HmacMD5:    md5.New,
+	HmacSHA1:   sha1.New,
+	HmacSHA224: sha256.New224,
+	HmacSHA256: sha256.New,
+	HmacSHA384: sha512.New384,
+	HmacSHA512: sha512.New,
+}
+
+// tsigHash returns the hash function of the HMAC algorithm and the length
+// of the MAC, in octets, it calls for. This length is also the minimum
+// length accepted, i.e. the local truncation policy.
+func tsigHash(algorithm string) (func() hash.Hash, int, error) {
+	algorithm = strings.ToLower(algorithm)
+	if h, ok := tsigHashes[algorithm]; ok {
+		return h, h().Size(), nil
+	}
+	i := strings.LastIndexByte(algorithm, '-')
+	if i < 0 {
+		return nil, 0, ErrKeyAlg
+	}
+	h, ok := tsigHashes[algorithm[:i]+"."]
+	if !ok {
+		return nil, 0, ErrKeyAlg
+	}
+	bits, err := strconv.Atoi(strings.TrimSuffix(algorithm[i+1:], "."))
+	if err != nil || bits%8 != 0 || bits/8 > h().Size() || bits/8 < tsigMinMACSize(h().Size()) {
+		return nil, 0, ErrKeyAlg
+	}
+	return h, bits / 8, nil
+}
+
+// tsigMinMACSize returns the length of the shortest MAC allowed for a hash
+// of the given size, RFC 8945 section 5.2.2.1.
+func tsigMinMACSize(size int) int {
+	if size/2 > 10 {
+		return size / 2
+	}
+	return 10
+}
+
+// A TsigProvider returns the secrets of TSIG keys. It can be used instead of a
+// static map of secrets, for instance to rotate keys or to look them up in a
+// key management service.
+type TsigProvider interface {
+	// TsigSecret returns the base64 encoded secret of the key with the given
+	// name and algorithm, both in canonical form (lowercase, fqdn). If the
+	// key is not known it must return an error, ErrSecret by convention.
+	TsigSecret(name, algorithm string) (string, error)
+}
+
+// tsigSecretProvider adapts the static TsigSecret maps to a TsigProvider.
+type tsigSecretProvider map[string]string
+
+func (s tsigSecretProvider) TsigSecret(name, algorithm string) (string, error) {
+	secret, ok := s[name]
+	if !ok {
+		return "", ErrSecret
+	}
+	return secret, nil
+}
+
+// tsigProvider returns p if set, otherwise a TsigProvider for secrets. It
+// returns nil if neither is set.
+func tsigProvider(p TsigProvider, secrets map[string]string) TsigProvider {
+	if p != nil {
+		return p
+	}
+	if secrets != nil {
+		return tsigSecretProvider(secrets)
+	}
+	return nil
+}
+
+// tsigSecret looks up the secret for t in p.
+func tsigSecret(p TsigProvider, t *TSIG) (string, error) {
+	if p == nil {
+		return "", ErrSecret
+	}
+	return p.TsigSecret(strings.ToLower(t.Hdr.Name), strings.ToLower(t.Algorithm))
+}
+
+// A tsigKey signs and verifies messages with a single TSIG key.
+type tsigKey interface {
+	generate(m *Msg, requestMAC string, timersOnly bool) ([]byte, string, error)
+	verify(msg []byte, requestMAC string, timersOnly bool) error
+}
+
+// hmacKey is a tsigKey for the HMAC algorithms, it holds the base64 encoded secret.
+type hmacKey string
+
+func (k hmacKey) generate(m *Msg, requestMAC string, timersOnly bool) ([]byte, string, error) {
+	return TsigGenerate(m, string(k), requestMAC, timersOnly)
+}
+
+func (k hmacKey) verify(msg []byte, requestMAC string, timersOnly bool) error {
+	return TsigVerify(msg, string(k), requestMAC, timersOnly)
+}
+
+// tsigLookup returns the key for t from p. GSS-TSIG keys are only found if p
+// is a *GSSKeyring.
+func tsigLookup(p TsigProvider, t *TSIG) (tsigKey, error) {
+	if strings.EqualFold(t.Algorithm, GssTsig) {
+		if k, ok := p.(*GSSKeyring); ok {
+			return k.key(strings.ToLower(t.Hdr.Name))
+		}
+		return nil, ErrKeyAlg
+	}
+	secret, err := tsigSecret(p, t)
+	if err != nil {
+		return nil, err
+	}
+	return hmacKey(secret), nil
+}
+
 // TSIG is the RR the holds the transaction signature of a message.
 // See RFC 2845 and RFC 4635.
 type TSIG struct {
@@ -100,7 +225,20 @@ func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, s
 	if err != nil {
 		return nil, "", err
 	}
+	return tsigGenerate(m, requestMAC, timersOnly, func(buf []byte, rr *TSIG) ([]byte, error) {
+		hf, size, err := tsigHash(rr.Algorithm)
+		if err != nil {
+			return nil, err
+		}
+		h := hmac.New(hf, []byte(rawsecret))
+		h.Write(buf)
+		return h.Sum(nil)[:size], nil
+	})
+}
 
+// tsigGenerate signs m, which must end in a TSIG, with the MAC returned by
+// mac for the wiredata buf and the stub TSIG rr.
+func tsigGenerate(m *Msg, requestMAC string, timersOnly bool, mac func(buf []byte, rr *TSIG) ([]byte, error)) ([]byte, string, error) {
 	rr := m.Extra[len(m.Extra)-1].(*TSIG)
 	m.Extra = m.Extra[0 : len(m.Extra)-1] // kill the TSIG from the msg
 	mbuf, err := m.Pack()
@@ -110,21 +248,11 @@ func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, s
 	buf := tsigBuffer(mbuf, rr, requestMAC, timersOnly)
 
 	t := new(TSIG)
-	var h hash.Hash
-	switch strings.ToLower(rr.Algorithm) {
-	case HmacMD5:
-		h = hmac.New(md5.New, []byte(rawsecret))
-	case HmacSHA1:
-		h = hmac.New(sha1.New, []byte(rawsecret))
-	case HmacSHA256:
-		h = hmac.New(sha256.New, []byte(rawsecret))
-	case HmacSHA512:
-		h = hmac.New(sha512.New, []byte(rawsecret))
-	default:
-		return nil, "", ErrKeyAlg
+	sum, err := mac(buf, rr)
+	if err != nil {
+		return nil, "", err
 	}
-	h.Write(buf)
-	t.MAC = hex.EncodeToString(h.Sum(nil))
+	t.MAC = hex.EncodeToString(sum)
 	t.MACSize = uint16(len(t.MAC) / 2) // Size is half!
 
 	t.Hdr = RR_Header{Name: rr.Hdr.Name, Rrtype: TypeTSIG, Class: ClassANY, Ttl: 0}
@@ -132,6 +260,9 @@ func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, s
 	t.TimeSigned = rr.TimeSigned
 	t.Algorithm = rr.Algorithm
 	t.OrigId = m.Id
+	t.Error = rr.Error
+	t.OtherLen = rr.OtherLen
+	t.OtherData = rr.OtherData
 
 	tbuf := make([]byte, t.len())
 	if off, err := PackRR(t, tbuf, 0, nil, false); err == nil {
@@ -149,7 +280,20 @@ func TsigGenerate(m *Msg, secret, requestMAC string, timersOnly bool) ([]byte, s
 // TsigVerify verifies the TSIG on a message.
 // If the signature does not validate err contains the
 // error, otherwise it is nil.
+//
+// The checks of RFC 8945 section 5.2 are done in order and the first one to
+// fail determines the error: ErrKeyAlg if the algorithm is not known, ErrMACSize
+// if the MAC is longer than the hash or truncated below the allowed minimum,
+// ErrSig if the MAC is wrong, ErrTime if the message was signed outside of
+// the fudge window and ErrTrunc if the MAC is shorter than called for by the
+// algorithm. A message carrying a TSIG error, such as a response from a
+// server that failed to verify the request, returns the matching error.
 func TsigVerify(msg []byte, secret, requestMAC string, timersOnly bool) error {
+	return tsigVerify(msg, secret, requestMAC, timersOnly, uint64(time.Now().Unix()))
+}
+
+// actual implementation of TsigVerify, taking the current time ('now') as a parameter for the convenience of tests.
+func tsigVerify(msg []byte, secret, requestMAC string, timersOnly bool, now uint64) error {
 	rawsecret, err := fromBase64([]byte(secret))
 	if err != nil {
 		return err
@@ -160,42 +304,122 @@ func TsigVerify(msg []byte, secret, requestMAC string, timersOnly bool) error {
 		return err
 	}
 
+	// Error responses for a bad key or MAC are not signed, RFC 8945 section 5.3.2.
+	if tsig.Error != RcodeSuccess && tsig.MACSize == 0 {
+		return tsigRcodeError(tsig.Error)
+	}
+	if tsig.Error == RcodeSuccess && int(binary.BigEndian.Uint16(msg[2:])&0xF) == RcodeNotAuth {
+		return ErrAuth
+	}
+
 	msgMAC, err := hex.DecodeString(tsig.MAC)
 	if err != nil {
 		return err
 	}
 
+	hf, size, err := tsigHash(tsig.Algorithm)
+	if err != nil {
+		return err
+	}
+	h := hmac.New(hf, rawsecret)
+	if len(msgMAC) > h.Size() || len(msgMAC) < tsigMinMACSize(h.Size()) {
+		return ErrMACSize
+	}
+
 	buf := tsigBuffer(stripped, tsig, requestMAC, timersOnly)
+	h.Write(buf)
+	if !hmac.Equal(h.Sum(nil)[:len(msgMAC)], msgMAC) {
+		return ErrSig
+	}
+
+	if tsig.Error != RcodeSuccess {
+		return tsigRcodeError(tsig.Error)
+	}
+
+	if err := tsigCheckTime(tsig, now); err != nil {
+		return err
+	}
+
+	if len(msgMAC) < size {
+		return ErrTrunc
+	}
+	return nil
+}
 
+// tsigCheckTime returns ErrTime if t was not signed within its fudge of now.
+func tsigCheckTime(t *TSIG, now uint64) error {
 	// Fudge factor works both ways. A message can arrive before it was signed because
 	// of clock skew.
-	now := uint64(time.Now().Unix())
-	ti := now - tsig.TimeSigned
-	if now < tsig.TimeSigned {
-		ti = tsig.TimeSigned - now
+	ti := now - t.TimeSigned
+	if now < t.TimeSigned {
+		ti = t.TimeSigned - now
+	}
+	if uint64(t.Fudge) < ti {
+		return ErrTime
 	}
-	if uint64(tsig.Fudge) < ti {
+	return nil
+}
+
+// tsigRcodeError returns the error for the TSIG error code rcode.
+func tsigRcodeError(rcode uint16) error {
+	switch rcode {
+	case RcodeBadSig:
+		return ErrSig
+	case RcodeBadKey:
+		return ErrKey
+	case RcodeBadTime:
 		return ErrTime
+	case RcodeBadTrunc:
+		return ErrTrunc
 	}
+	return ErrAuth
+}
 
-	var h hash.Hash
-	switch strings.ToLower(tsig.Algorithm) {
-	case HmacMD5:
-		h = hmac.New(md5.New, rawsecret)
-	case HmacSHA1:
-		h = hmac.New(sha1.New, rawsecret)
-	case HmacSHA256:
-		h = hmac.New(sha256.New, rawsecret)
-	case HmacSHA512:
-		h = hmac.New(sha512.New, rawsecret)
+// tsigErrorResponse turns m into the error response for a request whose
+// TSIG, req, failed to verify with status. As RFC 8945 section 5.3.2
+// requires, BADKEY and BADSIG responses are not signed, while BADTIME and
+// BADTRUNC responses are signed with k and the MAC of the request. A MAC of
+// the wrong size results in a FORMERR response without a TSIG.
+func tsigErrorResponse(m *Msg, req *TSIG, status error, k tsigKey, now uint64) ([]byte, error) {
+	// Keep nothing but the OPT RR.
+	var opt RR
+	if o := m.IsEdns0(); o != nil {
+		opt = o
+	}
+	m.Answer, m.Ns, m.Extra = nil, nil, nil
+	if opt != nil {
+		m.Extra = []RR{opt}
+	}
+
+	m.Rcode = RcodeNotAuth
+	t := &TSIG{
+		Hdr:        RR_Header{Name: req.Hdr.Name, Rrtype: TypeTSIG, Class: ClassANY},
+		Algorithm:  req.Algorithm,
+		TimeSigned: req.TimeSigned,
+		Fudge:      req.Fudge,
+		OrigId:     m.Id,
+	}
+	switch status {
+	case ErrMACSize:
+		m.Rcode = RcodeFormatError
+		return m.Pack()
+	case ErrKey, ErrKeyAlg, ErrSecret:
+		t.Error = RcodeBadKey
+	case ErrTime:
+		t.Error = RcodeBadTime
+		t.OtherLen = 6
+		t.OtherData = fmt.Sprintf("%012x", now)
+	case ErrTrunc:
+		t.Error = RcodeBadTrunc
 	default:
-		return ErrKeyAlg
+		t.Error = RcodeBadSig
 	}
-	h.Write(buf)
-	if !hmac.Equal(h.Sum(nil), msgMAC) {
-		return ErrSig
+	m.Extra = append(m.Extra, t)
+	if k != nil && (t.Error == RcodeBadTime || t.Error == RcodeBadTrunc) {
+		b, _, err := k.generate(m, req.MAC, false)
+		return b, err
 	}
-	return nil
+	return m.Pack()
 }
 
 // Create a wiredata buffer for the MAC calculation.
@@ -267,11 +491,6 @@ func stripTsig(msg []byte) ([]byte, *TSIG, error) {
 		return nil, nil, ErrNoSig
 	}
 
-	// Rcode, see msg.go Unpack()
-	if int(dh.Bits&0xF) == RcodeNotAuth {
-		return nil, nil, ErrAuth
-	}
-
 	for i := 0; i < int(dh.Qdcount); i++ {
 		_, off, err = unpackQuestion(msg, off)
 		if err != nil {
@@ -298,16 +517,16 @@ func stripTsig(msg []byte) ([]byte, *TSIG, error) {
 		}
 		if extra.Header().Rrtype == TypeTSIG {
 			rr = extra.(*TSIG)
-			// Adjust Arcount.
-			arcount := binary.BigEndian.Uint16(msg[10:])
-			binary.BigEndian.PutUint16(msg[10:], arcount-1)
 			break
 		}
 	}
 	if rr == nil {
 		return nil, nil, ErrNoSig
 	}
-	return msg[:tsigoff], rr, nil
+	// Adjust Arcount in a copy, so msg can be verified again.
+	stripped := append([]byte(nil), msg[:tsigoff]...)
+	binary.BigEndian.PutUint16(stripped[10:], dh.Arcount-1)
+	return stripped, rr, nil
 }
 
 // Translate the TSIG time signed into a date. There is no
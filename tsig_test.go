@@ -2,6 +2,8 @@ package dns
 
 import (
 	"encoding/binary"
+	"net"
+	"sync"
 	"testing"
 	"time"
 )
@@ -50,3 +52,168 @@ func TestTsigCase(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+// testTsigProvider only knows the keys for a single algorithm.
+type testTsigProvider struct {
+	algorithm string
+	secrets   map[string]string
+}
+
+func (p testTsigProvider) TsigSecret(name, algorithm string) (string, error) {
+	if algorithm != p.algorithm {
+		return "", ErrKeyAlg
+	}
+	if s, ok := p.secrets[name]; ok {
+		return s, nil
+	}
+	return "", ErrSecret
+}
+
+func TestTsigProvider(t *testing.T) {
+	provider := testTsigProvider{algorithm: HmacSHA256, secrets: map[string]string{"example.": "pRZgBrBvI4NAHZYhxmhs/Q=="}}
+
+	status := make(chan error, 1)
+	HandleFunc("example.org.", func(w ResponseWriter, r *Msg) {
+		status <- w.TsigStatus()
+		m := new(Msg)
+		m.SetReply(r)
+		t := r.IsTsig()
+		m.SetTsig(t.Hdr.Name, t.Algorithm, 300, time.Now().Unix())
+		w.WriteMsg(m)
+	})
+	defer HandleRemove("example.org.")
+
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to run test server: %v", err)
+	}
+	s := &Server{PacketConn: pc, TsigProvider: provider}
+	waitLock := sync.Mutex{}
+	waitLock.Lock()
+	s.NotifyStartedFunc = waitLock.Unlock
+	go s.ActivateAndServe()
+	waitLock.Lock()
+	defer s.Shutdown()
+	addrstr := pc.LocalAddr().String()
+
+	c := &Client{TsigProvider: provider}
+	m := new(Msg)
+	m.SetQuestion("example.org.", TypeA)
+	m.SetTsig("EXAMPLE.", HmacSHA256, 300, time.Now().Unix())
+	r, _, err := c.Exchange(m, addrstr)
+	if err != nil {
+		t.Fatalf("failed to exchange: %v", err)
+	}
+	if err := <-status; err != nil {
+		t.Errorf("server TSIG status: %v", err)
+	}
+	if r.IsTsig() == nil {
+		t.Error("response not signed")
+	}
+
+	// An unknown key is reported as BADKEY, to both the handler and the client.
+	c.TsigSecret = map[string]string{"unknown.": "pRZgBrBvI4NAHZYhxmhs/Q=="}
+	c.TsigProvider = nil
+	m.SetTsig("unknown.", HmacSHA256, 300, time.Now().Unix())
+	r, _, err = c.Exchange(m, addrstr)
+	if err != ErrKey {
+		t.Errorf("client error for unknown key: %v, want %v", err, ErrKey)
+	}
+	if err := <-status; err != ErrKey {
+		t.Errorf("server TSIG status for unknown key: %v, want %v", err, ErrKey)
+	}
+	if r == nil || r.Rcode != RcodeNotAuth || r.IsTsig() == nil || r.IsTsig().Error != RcodeBadKey || r.IsTsig().MACSize != 0 {
+		t.Errorf("expected an unsigned BADKEY response, got %v", r)
+	}
+
+	// A request signed outside of the fudge window gets a signed BADTIME.
+	c.TsigSecret = nil
+	c.TsigProvider = provider
+	m.SetTsig("example.", HmacSHA256, 300, time.Now().Unix()-3600)
+	r, _, err = c.Exchange(m, addrstr)
+	if err != ErrTime {
+		t.Errorf("client error for a stale request: %v, want %v", err, ErrTime)
+	}
+	if err := <-status; err != ErrTime {
+		t.Errorf("server TSIG status for a stale request: %v, want %v", err, ErrTime)
+	}
+	if r == nil || r.IsTsig() == nil || r.IsTsig().Error != RcodeBadTime || r.IsTsig().OtherLen != 6 || r.IsTsig().MACSize == 0 {
+		t.Errorf("expected a signed BADTIME response, got %v", r)
+	}
+}
+
+func TestTsigAlgorithms(t *testing.T) {
+	for _, algo := range []string{HmacMD5, HmacSHA1, HmacSHA224, HmacSHA256, HmacSHA384, HmacSHA512,
+		HmacSHA256_128, HmacSHA384_192, HmacSHA512_256, "hmac-sha1-80.", "HMAC-SHA256-160."} {
+		buf, mac, err := TsigGenerate(newTsig(algo), "pRZgBrBvI4NAHZYhxmhs/Q==", "", false)
+		if err != nil {
+			t.Errorf("%s: %v", algo, err)
+			continue
+		}
+		_, size, _ := tsigHash(algo)
+		if len(mac) != 2*size {
+			t.Errorf("%s: MAC of %d octets, want %d", algo, len(mac)/2, size)
+		}
+		if err := TsigVerify(buf, "pRZgBrBvI4NAHZYhxmhs/Q==", "", false); err != nil {
+			t.Errorf("%s: %v", algo, err)
+		}
+	}
+
+	// Truncations that are not a whole number of octets, too short or
+	// longer than the hash are not allowed.
+	for _, algo := range []string{"hmac-sha1-84.", "hmac-sha1-72.", "hmac-sha256-120.", "hmac-sha256-264.", "hmac-sha3-256.", "hmac-sha256-x."} {
+		if _, _, err := TsigGenerate(newTsig(algo), "pRZgBrBvI4NAHZYhxmhs/Q==", "", false); err != ErrKeyAlg {
+			t.Errorf("%s: got %v, want %v", algo, err, ErrKeyAlg)
+		}
+	}
+}
+
+// truncateTsig returns msg with the MAC of its TSIG truncated to size octets.
+func truncateTsig(t *testing.T, msg []byte, size int) []byte {
+	m := new(Msg)
+	if err := m.Unpack(msg); err != nil {
+		t.Fatal(err)
+	}
+	tsig := m.IsTsig()
+	tsig.MAC = tsig.MAC[:2*size]
+	tsig.MACSize = uint16(size)
+	buf, err := m.Pack()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestTsigVerifyErrors(t *testing.T) {
+	const secret = "pRZgBrBvI4NAHZYhxmhs/Q=="
+	m := newTsig(HmacSHA256)
+	now := m.IsTsig().TimeSigned
+	buf, _, err := TsigGenerate(m, secret, "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := tsigVerify(buf, secret, "", false, now+301); err != ErrTime {
+		t.Errorf("late: got %v, want %v", err, ErrTime)
+	}
+	if err := tsigVerify(buf, secret, "", false, now-301); err != ErrTime {
+		t.Errorf("early: got %v, want %v", err, ErrTime)
+	}
+	if err := tsigVerify(buf, "cFJaZ0JyQnZJNE5BSFpZaHhtaHMvUT09", "", false, now); err != ErrSig {
+		t.Errorf("wrong secret: got %v, want %v", err, ErrSig)
+	}
+	// A MAC shorter than hmac-sha256 calls for is valid, but not acceptable.
+	if err := tsigVerify(truncateTsig(t, buf, 16), secret, "", false, now); err != ErrTrunc {
+		t.Errorf("truncated: got %v, want %v", err, ErrTrunc)
+	}
+	if err := tsigVerify(truncateTsig(t, buf, 15), secret, "", false, now); err != ErrMACSize {
+		t.Errorf("truncated below the minimum: got %v, want %v", err, ErrMACSize)
+	}
+
+	// The MAC check comes before the time check.
+	bad := truncateTsig(t, buf, 32)
+	bad[len(bad)-20] ^= 0xFF
+	if err := tsigVerify(bad, secret, "", false, now+301); err != ErrSig {
+		t.Errorf("bad MAC and time: got %v, want %v", err, ErrSig)
+	}
+}
@@ -61,8 +61,10 @@ const (
 	TypeCERT       uint16 = 37
 	TypeDNAME      uint16 = 39
 	TypeOPT        uint16 = 41 // EDNS
+	TypeAPL        uint16 = 42
 	TypeDS         uint16 = 43
 	TypeSSHFP      uint16 = 44
+	TypeIPSECKEY   uint16 = 45
 	TypeRRSIG      uint16 = 46
 	TypeNSEC       uint16 = 47
 	TypeDNSKEY     uint16 = 48
@@ -79,6 +81,10 @@ const (
 	TypeCDNSKEY    uint16 = 60
 	TypeOPENPGPKEY uint16 = 61
 	TypeCSYNC      uint16 = 62
+	TypeZONEMD     uint16 = 63
+	TypeSVCB       uint16 = 64
+	TypeHTTPS      uint16 = 65
+	TypeDSYNC      uint16 = 66
 	TypeSPF        uint16 = 99
 	TypeUINFO      uint16 = 100
 	TypeUID        uint16 = 101
@@ -90,9 +96,13 @@ const (
 	TypeLP         uint16 = 107
 	TypeEUI48      uint16 = 108
 	TypeEUI64      uint16 = 109
+	TypeNXNAME     uint16 = 128
 	TypeURI        uint16 = 256
 	TypeCAA        uint16 = 257
 	TypeAVC        uint16 = 258
+	TypeAMTRELAY   uint16 = 260
+	TypeRESINFO    uint16 = 261
+	TypeWALLET     uint16 = 262
 
 	TypeTKEY uint16 = 249
 	TypeTSIG uint16 = 250
@@ -559,6 +569,22 @@ type AVC struct {
 
 func (rr *AVC) String() string { return rr.Hdr.String() + sprintTxt(rr.Txt) }
 
+// RESINFO RR. See RFC 9606.
+type RESINFO struct {
+	Hdr RR_Header
+	Txt []string `dns:"txt"`
+}
+
+func (rr *RESINFO) String() string { return rr.Hdr.String() + sprintTxt(rr.Txt) }
+
+// WALLET RR. See https://www.iana.org/assignments/dns-parameters/WALLET/wallet-completed-template.
+type WALLET struct {
+	Hdr RR_Header
+	Txt []string `dns:"txt"`
+}
+
+func (rr *WALLET) String() string { return rr.Hdr.String() + sprintTxt(rr.Txt) }
+
 // SRV RR. See RFC 2782.
 type SRV struct {
 	Hdr      RR_Header
@@ -906,6 +932,14 @@ type CDNSKEY struct {
 	DNSKEY
 }
 
+// The types embedding another type are not generated, without these copy
+// would return the embedded type.
+func (rr *SIG) copy() RR     { return &SIG{*rr.RRSIG.copy().(*RRSIG)} }
+func (rr *DLV) copy() RR     { return &DLV{*rr.DS.copy().(*DS)} }
+func (rr *CDS) copy() RR     { return &CDS{*rr.DS.copy().(*DS)} }
+func (rr *KEY) copy() RR     { return &KEY{*rr.DNSKEY.copy().(*DNSKEY)} }
+func (rr *CDNSKEY) copy() RR { return &CDNSKEY{*rr.DNSKEY.copy().(*DNSKEY)} }
+
 // DNSKEY RR. See RFC 4034 and RFC 3755.
 type DNSKEY struct {
 	Hdr       RR_Header
@@ -1302,6 +1336,172 @@ func (rr *CSYNC) len() int {
 	return l
 }
 
+// ZONEMD RR. See RFC 8976.
+type ZONEMD struct {
+	Hdr    RR_Header
+	Serial uint32
+	Scheme uint8
+	Hash   uint8
+	Digest string `dns:"hex"`
+}
+
+func (rr *ZONEMD) String() string {
+	return rr.Hdr.String() +
+		strconv.FormatInt(int64(rr.Serial), 10) +
+		" " + strconv.Itoa(int(rr.Scheme)) +
+		" " + strconv.Itoa(int(rr.Hash)) +
+		" " + rr.Digest
+}
+
+// APL RR. See RFC 3123.
+type APL struct {
+	Hdr      RR_Header
+	Prefixes []APLPrefix `dns:"apl"`
+}
+
+// APLPrefix is an address prefix of an APL RR.
+type APLPrefix struct {
+	Negation bool
+	Network  net.IPNet
+}
+
+func (rr *APL) String() string {
+	s := rr.Hdr.String()
+	for i, p := range rr.Prefixes {
+		if i > 0 {
+			s += " "
+		}
+		s += p.str()
+	}
+	return s
+}
+
+// str returns p in the presentation format of RFC 3123 section 5.
+func (p *APLPrefix) str() string {
+	s := ""
+	if p.Negation {
+		s = "!"
+	}
+	ip := p.ip()
+	switch len(ip) {
+	case net.IPv4len:
+		s += "1:" + ip.String()
+	case net.IPv6len:
+		s += "2:"
+		// add prefix for IPv4-mapped IPv6
+		if v4 := ip.To4(); v4 != nil {
+			s += "::ffff:"
+		}
+		s += ip.String()
+	default:
+		s += ":"
+	}
+	prefix, _ := p.Network.Mask.Size()
+	return s + "/" + strconv.Itoa(prefix)
+}
+
+// ip returns the address of p, in its 4 octet form if the mask is an IPv4
+// one, as net.ParseIP returns IPv4 addresses in their 16 octet form.
+func (p *APLPrefix) ip() net.IP {
+	if len(p.Network.Mask) == net.IPv4len {
+		if v4 := p.Network.IP.To4(); v4 != nil {
+			return v4
+		}
+	}
+	return p.Network.IP
+}
+
+// copy returns a deep copy of p.
+func (p *APLPrefix) copy() APLPrefix {
+	return APLPrefix{
+		Negation: p.Negation,
+		Network:  copyNet(p.Network),
+	}
+}
+
+// len returns the length of p in wire format.
+func (p *APLPrefix) len() int {
+	// 4-byte header and the network address prefix (see Section 4 of RFC 3123)
+	prefix, _ := p.Network.Mask.Size()
+	return 4 + (prefix+7)/8
+}
+
+// The gateway types of the IPSECKEY and AMTRELAY records, RFC 4025 section
+// 2.3 and RFC 8777 section 4.2.3.
+const (
+	IPSECGatewayNone uint8 = iota
+	IPSECGatewayIPv4
+	IPSECGatewayIPv6
+	IPSECGatewayHost
+)
+
+// IPSECKEY RR. See RFC 4025.
+type IPSECKEY struct {
+	Hdr         RR_Header
+	Precedence  uint8
+	GatewayType uint8
+	Algorithm   uint8
+	GatewayAddr net.IP `dns:"-"` // packed and parsed together with GatewayHost
+	GatewayHost string `dns:"ipsechost"`
+	PublicKey   string `dns:"base64"`
+}
+
+func (rr *IPSECKEY) String() string {
+	return rr.Hdr.String() + strconv.Itoa(int(rr.Precedence)) +
+		" " + strconv.Itoa(int(rr.GatewayType)) +
+		" " + strconv.Itoa(int(rr.Algorithm)) +
+		" " + gatewayString(rr.GatewayType, rr.GatewayAddr, rr.GatewayHost) +
+		" " + rr.PublicKey
+}
+
+// AMTRELAY RR. See RFC 8777.
+type AMTRELAY struct {
+	Hdr         RR_Header
+	Precedence  uint8
+	GatewayType uint8  // the discovery optional flag is the top bit, 0x80
+	GatewayAddr net.IP `dns:"-"` // packed and parsed together with GatewayHost
+	GatewayHost string `dns:"amtrelayhost"`
+}
+
+func (rr *AMTRELAY) String() string {
+	d := "0"
+	if rr.GatewayType&0x80 == 0x80 {
+		d = "1"
+	}
+	return rr.Hdr.String() + strconv.Itoa(int(rr.Precedence)) +
+		" " + d +
+		" " + strconv.Itoa(int(rr.GatewayType&0x7f)) +
+		" " + gatewayString(rr.GatewayType&0x7f, rr.GatewayAddr, rr.GatewayHost)
+}
+
+// gatewayString returns the gateway of an IPSECKEY or AMTRELAY RR of
+// gateway type typ in presentation format.
+func gatewayString(typ uint8, addr net.IP, host string) string {
+	switch typ {
+	case IPSECGatewayIPv4, IPSECGatewayIPv6:
+		return addr.String()
+	case IPSECGatewayHost:
+		return sprintName(host)
+	}
+	return "."
+}
+
+// DSYNC RR. See RFC 9859.
+type DSYNC struct {
+	Hdr    RR_Header
+	Type   uint16 // the type of the record changes are notified for
+	Scheme uint8
+	Port   uint16
+	Target string `dns:"domain-name"`
+}
+
+func (rr *DSYNC) String() string {
+	return rr.Hdr.String() + Type(rr.Type).String() +
+		" " + strconv.Itoa(int(rr.Scheme)) +
+		" " + strconv.Itoa(int(rr.Port)) +
+		" " + sprintName(rr.Target)
+}
+
 // TimeToString translates the RRSIG's incep. and expir. times to the
 // string representation used when printing the record.
 // It takes serial arithmetic (RFC 1982) into account.
@@ -1358,6 +1558,12 @@ func copyIP(ip net.IP) net.IP {
 	return p
 }
 
+func copyNet(n net.IPNet) net.IPNet {
+	m := make(net.IPMask, len(n.Mask))
+	copy(m, n.Mask)
+	return net.IPNet{IP: copyIP(n.IP), Mask: m}
+}
+
 // SplitN splits a string into N sized string chunks.
 // This might become an exported function once.
 func splitN(s string, n int) []string {
@@ -68,7 +68,7 @@ var headerFunc = template.Must(template.New("headerFunc").Parse(`
 // resolved.
 func getTypeStruct(t types.Type, scope *types.Scope) (*types.Struct, bool) {
 	st, ok := t.Underlying().(*types.Struct)
-	if !ok {
+	if !ok || st.NumFields() == 0 {
 		return nil, false
 	}
 	if st.Field(0).Type() == scope.Lookup("RR_Header").Type() {
@@ -164,6 +164,10 @@ func main() {
 					// ignored
 				case `dns:"cdomain-name"`, `dns:"domain-name"`, `dns:"txt"`:
 					o("for _, x := range rr.%s { l += len(x) + 1 }\n")
+				case `dns:"pairs"`:
+					o("for _, x := range rr.%s { l += 4 + x.len() }\n")
+				case `dns:"apl"`:
+					o("for _, x := range rr.%s { l += x.len() }\n")
 				default:
 					log.Fatalln(name, st.Field(i).Name(), st.Tag(i))
 				}
@@ -195,6 +199,16 @@ func main() {
 				o("for _, t := range rr.%s { l += len(t) + 1 }\n")
 			case st.Tag(i) == `dns:"uint48"`:
 				o("l += 6 // %s\n")
+			case st.Tag(i) == `dns:"ipsechost"`, st.Tag(i) == `dns:"amtrelayhost"`:
+				o(`switch rr.GatewayType & 0x7f {
+case IPSECGatewayIPv4:
+	l += net.IPv4len
+case IPSECGatewayIPv6:
+	l += net.IPv6len
+case IPSECGatewayHost:
+	l += len(rr.%s) + 1
+}
+`)
 			case st.Tag(i) == "":
 				switch st.Field(i).Type().(*types.Basic).Kind() {
 				case types.Uint8:
@@ -229,6 +243,18 @@ func main() {
 		fields := []string{"*rr.Hdr.copyHeader()"}
 		for i := 1; i < st.NumFields(); i++ {
 			f := st.Field(i).Name()
+			if st.Tag(i) == `dns:"apl"` {
+				fmt.Fprintf(b, "%s := make([]APLPrefix, len(rr.%s));\n for i, e := range rr.%s {\n %s[i] = e.copy()\n}\n",
+					f, f, f, f)
+				fields = append(fields, f)
+				continue
+			}
+			if st.Tag(i) == `dns:"pairs"` {
+				fmt.Fprintf(b, "%s := make([]SVCBKeyValue, len(rr.%s));\n for i, e := range rr.%s {\n %s[i] = e.copy()\n}\n",
+					f, f, f, f)
+				fields = append(fields, f)
+				continue
+			}
 			if sl, ok := st.Field(i).Type().(*types.Slice); ok {
 				t := sl.Underlying().String()
 				t = strings.TrimPrefix(t, "[]")
@@ -0,0 +1,218 @@
+// +build !windows
+
+package dns
+
+import (
+	"io"
+	"net"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const udpBatchSupported = true
+
+// batchConn is implemented by both ipv4.PacketConn and ipv6.PacketConn. On
+// Linux ReadBatch and WriteBatch use recvmmsg(2) and sendmmsg(2), elsewhere
+// they read and write a single message.
+type batchConn interface {
+	ReadBatch(ms []ipv4.Message, flags int) (int, error)
+	WriteBatch(ms []ipv4.Message, flags int) (int, error)
+}
+
+func newBatchConn(conn *net.UDPConn) batchConn {
+	if a, ok := conn.LocalAddr().(*net.UDPAddr); ok && a.IP.To4() != nil {
+		return ipv4.NewPacketConn(conn)
+	}
+	return ipv6.NewPacketConn(conn)
+}
+
+// udpPacket is a request read by serveUDPBatch, it is handed to one of the
+// workers and then returned to the pool.
+type udpPacket struct {
+	buf     []byte
+	n       int
+	session *SessionUDP
+}
+
+// serveUDPBatch serves l by reading up to srv.UDPBatchSize messages at a time
+// and dispatching them to a fixed number of workers.
+func (srv *Server) serveUDPBatch(l *net.UDPConn, h Handler) error {
+	bc := newBatchConn(l)
+
+	pool := sync.Pool{New: func() interface{} {
+		return &udpPacket{buf: make([]byte, srv.UDPSize)}
+	}}
+
+	w := &udpBatchWriter{conn: bc, size: srv.UDPBatchSize, queue: make(chan *udpWrite, srv.UDPBatchSize)}
+	go w.run()
+
+	workers := srv.UDPWorkers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	reqs := make(chan *udpPacket, srv.UDPBatchSize)
+	var wg sync.WaitGroup
+	wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer wg.Done()
+			for p := range reqs {
+				m, a := p.buf[:p.n], p.session.RemoteAddr()
+				if srv.ProxyProtocol != nil {
+					var err error
+					if m, a, err = srv.ProxyProtocol.parseUDP(m, a); err != nil || len(m) < headerSize {
+						p.session = nil
+						pool.Put(p)
+						continue
+					}
+				}
+				srv.serve(a, h, m, l, p.session, nil, w)
+				p.session = nil
+				pool.Put(p)
+			}
+		}()
+	}
+	defer func() {
+		close(reqs)
+		go func() {
+			wg.Wait()
+			close(w.queue)
+		}()
+	}()
+
+	ms := make([]ipv4.Message, srv.UDPBatchSize)
+	ps := make([]*udpPacket, srv.UDPBatchSize)
+	for i := range ms {
+		ps[i] = pool.Get().(*udpPacket)
+		ms[i].Buffers = [][]byte{ps[i].buf}
+		ms[i].OOB = make([]byte, 40)
+	}
+
+	rtimeout := srv.getReadTimeout()
+	// deadline is not used here
+	for {
+		l.SetReadDeadline(time.Now().Add(rtimeout))
+		n, err := bc.ReadBatch(ms, 0)
+		srv.lock.RLock()
+		if !srv.started {
+			srv.lock.RUnlock()
+			return nil
+		}
+		srv.lock.RUnlock()
+		if err != nil {
+			if netErr, ok := err.(net.Error); ok && netErr.Temporary() {
+				continue
+			}
+			return err
+		}
+		for i := 0; i < n; i++ {
+			m := &ms[i]
+			raddr, ok := m.Addr.(*net.UDPAddr)
+			if !ok || m.N < headerSize {
+				continue
+			}
+			p := ps[i]
+			p.n = m.N
+			// The OOB buffer is reused for the next batch, so copy it.
+			p.session = &SessionUDP{raddr, append([]byte(nil), m.OOB[:m.NN]...)}
+			reqs <- p
+
+			ps[i] = pool.Get().(*udpPacket)
+			m.Buffers[0] = ps[i].buf
+		}
+	}
+}
+
+// udpWrite is a single response queued on a udpBatchWriter.
+type udpWrite struct {
+	b       []byte
+	session *SessionUDP
+	n       int
+	done    chan error
+}
+
+var udpWritePool = sync.Pool{New: func() interface{} {
+	return &udpWrite{done: make(chan error, 1)}
+}}
+
+// udpBatchWriter collects the responses written by concurrent handlers and
+// sends them with as few WriteBatch calls as possible.
+type udpBatchWriter struct {
+	conn  batchConn
+	size  int
+	queue chan *udpWrite
+}
+
+// WriteToSessionUDP queues b to be sent to session and waits until it has
+// been written.
+func (bw *udpBatchWriter) WriteToSessionUDP(b []byte, session *SessionUDP) (int, error) {
+	w := udpWritePool.Get().(*udpWrite)
+	w.b, w.session = b, session
+	bw.queue <- w
+	err := <-w.done
+	n := w.n
+	w.b, w.session = nil, nil
+	udpWritePool.Put(w)
+	return n, err
+}
+
+func (bw *udpBatchWriter) run() {
+	ms := make([]ipv4.Message, bw.size)
+	bufs := make([][]byte, bw.size)
+	ws := make([]*udpWrite, 0, bw.size)
+	for w := range bw.queue {
+		ws = append(ws[:0], w)
+	Drain:
+		for len(ws) < bw.size {
+			select {
+			case w, ok := <-bw.queue:
+				if !ok {
+					break Drain
+				}
+				ws = append(ws, w)
+			default:
+				break Drain
+			}
+		}
+		for i, w := range ws {
+			bufs[i] = w.b
+			ms[i].Buffers = bufs[i : i+1]
+			ms[i].OOB = correctSource(w.session.context)
+			ms[i].Addr = w.session.raddr
+		}
+		bw.write(ms[:len(ws)], ws)
+		for i := range ws {
+			bufs[i] = nil
+			ms[i] = ipv4.Message{}
+		}
+	}
+}
+
+// write sends ms and reports the result of each message to the matching
+// entry in ws. A message that fails to be sent is skipped so that it
+// does not hold up the remainder of the batch.
+func (bw *udpBatchWriter) write(ms []ipv4.Message, ws []*udpWrite) {
+	for len(ms) > 0 {
+		n, err := bw.conn.WriteBatch(ms, 0)
+		if err != nil {
+			n = 0
+		}
+		for i := 0; i < n; i++ {
+			ws[i].n = ms[i].N
+			ws[i].done <- nil
+		}
+		ms, ws = ms[n:], ws[n:]
+		if err != nil || n == 0 {
+			if err == nil {
+				err = io.ErrShortWrite
+			}
+			ws[0].n = 0
+			ws[0].done <- err
+			ms, ws = ms[1:], ws[1:]
+		}
+	}
+}
@@ -35,3 +35,8 @@ func WriteToSessionUDP(conn *net.UDPConn, b []byte, session *SessionUDP) (int, e
 // use the standard method in udp.go for these.
 func setUDPSocketOptions(*net.UDPConn) error { return nil }
 func parseDstFromOOB([]byte, net.IP) net.IP  { return nil }
+
+// Batched UDP reads and writes are not implemented on Windows.
+const udpBatchSupported = false
+
+func (srv *Server) serveUDPBatch(*net.UDPConn, Handler) error { panic("not reached") }
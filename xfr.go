@@ -18,6 +18,7 @@ type Transfer struct {
 	ReadTimeout    time.Duration     // net.Conn.SetReadTimeout value for connections, defaults to 2 seconds
 	WriteTimeout   time.Duration     // net.Conn.SetWriteTimeout value for connections, defaults to 2 seconds
 	TsigSecret     map[string]string // Secret(s) for Tsig map[<zonename>]<base64 secret>, zonename must be in canonical form (lowercase, fqdn, see RFC 4034 Section 6.2)
+	TsigProvider   TsigProvider      // Looks up Tsig secrets at runtime, takes precedence over TsigSecret
 	tsigTimersOnly bool
 }
 
@@ -212,25 +213,30 @@ func (t *Transfer) ReadMsg() (*Msg, error) {
 	if err := m.Unpack(p); err != nil {
 		return nil, err
 	}
-	if ts := m.IsTsig(); ts != nil && t.TsigSecret != nil {
-		if _, ok := t.TsigSecret[ts.Hdr.Name]; !ok {
-			return m, ErrSecret
+	if ts, tp := m.IsTsig(), t.tsigProvider(); ts != nil && tp != nil {
+		k, err := tsigLookup(tp, ts)
+		if err != nil {
+			return m, err
 		}
 		// Need to work on the original message p, as that was used to calculate the tsig.
-		err = TsigVerify(p, t.TsigSecret[ts.Hdr.Name], t.tsigRequestMAC, t.tsigTimersOnly)
+		err = k.verify(p, t.tsigRequestMAC, t.tsigTimersOnly)
 		t.tsigRequestMAC = ts.MAC
+		return m, err
 	}
 	return m, err
 }
 
+func (t *Transfer) tsigProvider() TsigProvider { return tsigProvider(t.TsigProvider, t.TsigSecret) }
+
 // WriteMsg writes a message through the transfer connection t.
 func (t *Transfer) WriteMsg(m *Msg) (err error) {
 	var out []byte
-	if ts := m.IsTsig(); ts != nil && t.TsigSecret != nil {
-		if _, ok := t.TsigSecret[ts.Hdr.Name]; !ok {
-			return ErrSecret
+	if ts, tp := m.IsTsig(), t.tsigProvider(); ts != nil && tp != nil {
+		var k tsigKey
+		if k, err = tsigLookup(tp, ts); err != nil {
+			return err
 		}
-		out, t.tsigRequestMAC, err = TsigGenerate(m, t.TsigSecret[ts.Hdr.Name], t.tsigRequestMAC, t.tsigTimersOnly)
+		out, t.tsigRequestMAC, err = k.generate(m, t.tsigRequestMAC, t.tsigTimersOnly)
 	} else {
 		out, err = m.Pack()
 	}
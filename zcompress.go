@@ -2,7 +2,7 @@
 
 package dns
 
-func compressionLenHelperType(c map[string]int, r RR) {
+func compressionLenHelperType(c *compressionTable, r RR) {
 	switch x := r.(type) {
 	case *AFSDB:
 		compressionLenHelper(c, x.Hostname)
@@ -10,10 +10,14 @@ func compressionLenHelperType(c map[string]int, r RR) {
 		compressionLenHelper(c, x.Target)
 	case *DNAME:
 		compressionLenHelper(c, x.Target)
+	case *DSYNC:
+		compressionLenHelper(c, x.Target)
 	case *HIP:
 		for i := range x.RendezvousServers {
 			compressionLenHelper(c, x.RendezvousServers[i])
 		}
+	case *HTTPS:
+		compressionLenHelper(c, x.Target)
 	case *KX:
 		compressionLenHelper(c, x.Exchanger)
 	case *LP:
@@ -60,6 +64,8 @@ func compressionLenHelperType(c map[string]int, r RR) {
 		compressionLenHelper(c, x.Mbox)
 	case *SRV:
 		compressionLenHelper(c, x.Target)
+	case *SVCB:
+		compressionLenHelper(c, x.Target)
 	case *TALINK:
 		compressionLenHelper(c, x.PreviousName)
 		compressionLenHelper(c, x.NextName)
@@ -70,7 +76,7 @@ func compressionLenHelperType(c map[string]int, r RR) {
 	}
 }
 
-func compressionLenSearchType(c map[string]int, r RR) (int, bool) {
+func compressionLenSearchType(c *compressionTable, r RR) (int, bool) {
 	switch x := r.(type) {
 	case *AFSDB:
 		k1, ok1 := compressionLenSearch(c, x.Hostname)
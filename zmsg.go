@@ -4,7 +4,7 @@ package dns
 
 // pack*() functions
 
-func (rr *A) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *A) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -18,7 +18,7 @@ func (rr *A) pack(msg []byte, off int, compression map[string]int, compress bool
 	return off, nil
 }
 
-func (rr *AAAA) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *AAAA) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -32,7 +32,7 @@ func (rr *AAAA) pack(msg []byte, off int, compression map[string]int, compress b
 	return off, nil
 }
 
-func (rr *AFSDB) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *AFSDB) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -42,7 +42,7 @@ func (rr *AFSDB) pack(msg []byte, off int, compression map[string]int, compress
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Hostname, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Hostname, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -50,7 +50,29 @@ func (rr *AFSDB) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *ANY) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *AMTRELAY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packUint8(rr.Precedence, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packUint8(rr.GatewayType, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packIPSECGateway(rr.GatewayAddr, rr.GatewayHost, msg, off, rr.GatewayType&0x7f, compression, false)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *ANY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -60,7 +82,21 @@ func (rr *ANY) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *AVC) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *APL) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packDataApl(rr.Prefixes, msg, off)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *AVC) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -74,7 +110,7 @@ func (rr *AVC) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *CAA) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *CAA) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -96,7 +132,7 @@ func (rr *CAA) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *CDNSKEY) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *CDNSKEY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -122,7 +158,7 @@ func (rr *CDNSKEY) pack(msg []byte, off int, compression map[string]int, compres
 	return off, nil
 }
 
-func (rr *CDS) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *CDS) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -148,7 +184,7 @@ func (rr *CDS) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *CERT) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *CERT) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -174,13 +210,13 @@ func (rr *CERT) pack(msg []byte, off int, compression map[string]int, compress b
 	return off, nil
 }
 
-func (rr *CNAME) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *CNAME) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Target, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Target, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -188,7 +224,7 @@ func (rr *CNAME) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *CSYNC) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *CSYNC) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -210,7 +246,7 @@ func (rr *CSYNC) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *DHCID) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *DHCID) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -224,7 +260,7 @@ func (rr *DHCID) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *DLV) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *DLV) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -250,13 +286,13 @@ func (rr *DLV) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *DNAME) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *DNAME) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Target, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Target, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -264,7 +300,7 @@ func (rr *DNAME) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *DNSKEY) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *DNSKEY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -290,7 +326,7 @@ func (rr *DNSKEY) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *DS) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *DS) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -316,7 +352,33 @@ func (rr *DS) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *EID) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *DSYNC) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packUint16(rr.Type, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packUint8(rr.Scheme, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packUint16(rr.Port, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, _, err = packDomainName(rr.Target, msg, off, compression, false)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *EID) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -330,7 +392,7 @@ func (rr *EID) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *EUI48) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *EUI48) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -344,7 +406,7 @@ func (rr *EUI48) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *EUI64) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *EUI64) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -358,7 +420,7 @@ func (rr *EUI64) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *GID) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *GID) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -372,7 +434,7 @@ func (rr *GID) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *GPOS) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *GPOS) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -394,7 +456,7 @@ func (rr *GPOS) pack(msg []byte, off int, compression map[string]int, compress b
 	return off, nil
 }
 
-func (rr *HINFO) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *HINFO) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -412,7 +474,7 @@ func (rr *HINFO) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *HIP) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *HIP) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -446,7 +508,59 @@ func (rr *HIP) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *KEY) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *HTTPS) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packUint16(rr.Priority, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, _, err = packDomainName(rr.Target, msg, off, compression, false)
+	if err != nil {
+		return off, err
+	}
+	off, err = packDataSVCB(rr.Value, msg, off)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *IPSECKEY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packUint8(rr.Precedence, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packUint8(rr.GatewayType, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packUint8(rr.Algorithm, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packIPSECGateway(rr.GatewayAddr, rr.GatewayHost, msg, off, rr.GatewayType, compression, false)
+	if err != nil {
+		return off, err
+	}
+	off, err = packStringBase64(rr.PublicKey, msg, off)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *KEY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -472,7 +586,7 @@ func (rr *KEY) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *KX) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *KX) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -482,7 +596,7 @@ func (rr *KX) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Exchanger, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Exchanger, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -490,7 +604,7 @@ func (rr *KX) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *L32) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *L32) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -508,7 +622,7 @@ func (rr *L32) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *L64) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *L64) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -526,7 +640,7 @@ func (rr *L64) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *LOC) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *LOC) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -564,7 +678,7 @@ func (rr *LOC) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *LP) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *LP) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -574,7 +688,7 @@ func (rr *LP) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Fqdn, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Fqdn, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -582,13 +696,13 @@ func (rr *LP) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *MB) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *MB) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Mb, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Mb, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -596,13 +710,13 @@ func (rr *MB) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *MD) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *MD) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Md, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Md, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -610,13 +724,13 @@ func (rr *MD) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *MF) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *MF) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Mf, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Mf, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -624,13 +738,13 @@ func (rr *MF) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *MG) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *MG) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Mg, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Mg, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -638,17 +752,17 @@ func (rr *MG) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *MINFO) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *MINFO) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Rmail, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Rmail, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Email, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Email, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -656,13 +770,13 @@ func (rr *MINFO) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *MR) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *MR) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Mr, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Mr, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -670,7 +784,7 @@ func (rr *MR) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *MX) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *MX) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -680,7 +794,7 @@ func (rr *MX) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Mx, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Mx, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -688,7 +802,7 @@ func (rr *MX) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *NAPTR) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NAPTR) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -714,7 +828,7 @@ func (rr *NAPTR) pack(msg []byte, off int, compression map[string]int, compress
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Replacement, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Replacement, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -722,7 +836,7 @@ func (rr *NAPTR) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *NID) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NID) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -740,7 +854,7 @@ func (rr *NID) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *NIMLOC) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NIMLOC) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -754,7 +868,7 @@ func (rr *NIMLOC) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *NINFO) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NINFO) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -768,13 +882,13 @@ func (rr *NINFO) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *NS) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NS) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Ns, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Ns, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -782,13 +896,13 @@ func (rr *NS) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *NSAPPTR) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NSAPPTR) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Ptr, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Ptr, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -796,13 +910,13 @@ func (rr *NSAPPTR) pack(msg []byte, off int, compression map[string]int, compres
 	return off, nil
 }
 
-func (rr *NSEC) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NSEC) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.NextDomain, msg, off, compression, false)
+	off, _, err = packDomainName(rr.NextDomain, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -814,7 +928,7 @@ func (rr *NSEC) pack(msg []byte, off int, compression map[string]int, compress b
 	return off, nil
 }
 
-func (rr *NSEC3) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NSEC3) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -859,7 +973,7 @@ func (rr *NSEC3) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *NSEC3PARAM) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *NSEC3PARAM) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -892,7 +1006,7 @@ func (rr *NSEC3PARAM) pack(msg []byte, off int, compression map[string]int, comp
 	return off, nil
 }
 
-func (rr *OPENPGPKEY) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *OPENPGPKEY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -906,7 +1020,7 @@ func (rr *OPENPGPKEY) pack(msg []byte, off int, compression map[string]int, comp
 	return off, nil
 }
 
-func (rr *OPT) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *OPT) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -920,13 +1034,13 @@ func (rr *OPT) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *PTR) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *PTR) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Ptr, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Ptr, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -934,7 +1048,7 @@ func (rr *PTR) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *PX) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *PX) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -944,11 +1058,25 @@ func (rr *PX) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Map822, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Map822, msg, off, compression, false)
+	if err != nil {
+		return off, err
+	}
+	off, _, err = packDomainName(rr.Mapx400, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Mapx400, msg, off, compression, false)
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *RESINFO) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packStringTxt(rr.Txt, msg, off)
 	if err != nil {
 		return off, err
 	}
@@ -956,7 +1084,7 @@ func (rr *PX) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *RFC3597) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *RFC3597) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -970,7 +1098,7 @@ func (rr *RFC3597) pack(msg []byte, off int, compression map[string]int, compres
 	return off, nil
 }
 
-func (rr *RKEY) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *RKEY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -996,17 +1124,17 @@ func (rr *RKEY) pack(msg []byte, off int, compression map[string]int, compress b
 	return off, nil
 }
 
-func (rr *RP) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *RP) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Mbox, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Mbox, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Txt, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Txt, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1014,7 +1142,7 @@ func (rr *RP) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *RRSIG) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *RRSIG) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1048,7 +1176,7 @@ func (rr *RRSIG) pack(msg []byte, off int, compression map[string]int, compress
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.SignerName, msg, off, compression, false)
+	off, _, err = packDomainName(rr.SignerName, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1060,7 +1188,7 @@ func (rr *RRSIG) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *RT) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *RT) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1070,7 +1198,7 @@ func (rr *RT) pack(msg []byte, off int, compression map[string]int, compress boo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Host, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Host, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -1078,7 +1206,7 @@ func (rr *RT) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *SIG) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *SIG) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1112,7 +1240,7 @@ func (rr *SIG) pack(msg []byte, off int, compression map[string]int, compress bo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.SignerName, msg, off, compression, false)
+	off, _, err = packDomainName(rr.SignerName, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1124,7 +1252,7 @@ func (rr *SIG) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *SMIMEA) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *SMIMEA) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1150,17 +1278,17 @@ func (rr *SMIMEA) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *SOA) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *SOA) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Ns, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Ns, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Mbox, msg, off, compression, compress)
+	off, _, err = packDomainName(rr.Mbox, msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
@@ -1188,7 +1316,7 @@ func (rr *SOA) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *SPF) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *SPF) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1202,7 +1330,7 @@ func (rr *SPF) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *SRV) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *SRV) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1220,7 +1348,7 @@ func (rr *SRV) pack(msg []byte, off int, compression map[string]int, compress bo
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.Target, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Target, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1228,7 +1356,7 @@ func (rr *SRV) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *SSHFP) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *SSHFP) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1250,7 +1378,29 @@ func (rr *SSHFP) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *TA) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *SVCB) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packUint16(rr.Priority, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, _, err = packDomainName(rr.Target, msg, off, compression, false)
+	if err != nil {
+		return off, err
+	}
+	off, err = packDataSVCB(rr.Value, msg, off)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *TA) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1276,17 +1426,17 @@ func (rr *TA) pack(msg []byte, off int, compression map[string]int, compress boo
 	return off, nil
 }
 
-func (rr *TALINK) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *TALINK) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.PreviousName, msg, off, compression, false)
+	off, _, err = packDomainName(rr.PreviousName, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
-	off, err = PackDomainName(rr.NextName, msg, off, compression, false)
+	off, _, err = packDomainName(rr.NextName, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1294,13 +1444,13 @@ func (rr *TALINK) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *TKEY) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *TKEY) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Algorithm, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Algorithm, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1340,7 +1490,7 @@ func (rr *TKEY) pack(msg []byte, off int, compression map[string]int, compress b
 	return off, nil
 }
 
-func (rr *TLSA) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *TLSA) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1366,13 +1516,13 @@ func (rr *TLSA) pack(msg []byte, off int, compression map[string]int, compress b
 	return off, nil
 }
 
-func (rr *TSIG) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *TSIG) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
 	}
 	headerEnd := off
-	off, err = PackDomainName(rr.Algorithm, msg, off, compression, false)
+	off, _, err = packDomainName(rr.Algorithm, msg, off, compression, false)
 	if err != nil {
 		return off, err
 	}
@@ -1412,7 +1562,7 @@ func (rr *TSIG) pack(msg []byte, off int, compression map[string]int, compress b
 	return off, nil
 }
 
-func (rr *TXT) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *TXT) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1426,7 +1576,7 @@ func (rr *TXT) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *UID) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *UID) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1440,7 +1590,7 @@ func (rr *UID) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *UINFO) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *UINFO) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1454,7 +1604,7 @@ func (rr *UINFO) pack(msg []byte, off int, compression map[string]int, compress
 	return off, nil
 }
 
-func (rr *URI) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *URI) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1476,7 +1626,21 @@ func (rr *URI) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
-func (rr *X25) pack(msg []byte, off int, compression map[string]int, compress bool) (int, error) {
+func (rr *WALLET) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packStringTxt(rr.Txt, msg, off)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
+func (rr *X25) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
 	off, err := rr.Hdr.pack(msg, off, compression, compress)
 	if err != nil {
 		return off, err
@@ -1490,6 +1654,32 @@ func (rr *X25) pack(msg []byte, off int, compression map[string]int, compress bo
 	return off, nil
 }
 
+func (rr *ZONEMD) pack(msg []byte, off int, compression compressionMap, compress bool) (int, error) {
+	off, err := rr.Hdr.pack(msg, off, compression, compress)
+	if err != nil {
+		return off, err
+	}
+	headerEnd := off
+	off, err = packUint32(rr.Serial, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packUint8(rr.Scheme, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packUint8(rr.Hash, msg, off)
+	if err != nil {
+		return off, err
+	}
+	off, err = packStringHex(rr.Digest, msg, off)
+	if err != nil {
+		return off, err
+	}
+	rr.Header().Rdlength = uint16(off - headerEnd)
+	return off, nil
+}
+
 // unpack*() functions
 
 func unpackA(h RR_Header, msg []byte, off int) (RR, int, error) {
@@ -1550,6 +1740,37 @@ func unpackAFSDB(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackAMTRELAY(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(AMTRELAY)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Precedence, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.GatewayType, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.GatewayAddr, rr.GatewayHost, off, err = unpackIPSECGateway(msg, off, rr.GatewayType&0x7f)
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackANY(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(ANY)
 	rr.Hdr = h
@@ -1563,6 +1784,23 @@ func unpackANY(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackAPL(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(APL)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Prefixes, off, err = unpackDataApl(msg, off, rdStart+int(rr.Hdr.Rdlength))
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackAVC(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(AVC)
 	rr.Hdr = h
@@ -1921,6 +2159,44 @@ func unpackDS(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackDSYNC(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(DSYNC)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Type, off, err = unpackUint16(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Scheme, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Port, off, err = unpackUint16(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Target, off, err = UnpackDomainName(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackEID(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(EID)
 	rr.Hdr = h
@@ -2090,6 +2366,82 @@ func unpackHIP(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackHTTPS(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(HTTPS)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Priority, off, err = unpackUint16(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Target, off, err = UnpackDomainName(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Value, off, err = unpackDataSVCB(msg, off, rdStart+int(rr.Hdr.Rdlength))
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
+func unpackIPSECKEY(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(IPSECKEY)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Precedence, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.GatewayType, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Algorithm, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.GatewayAddr, rr.GatewayHost, off, err = unpackIPSECGateway(msg, off, rr.GatewayType)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.PublicKey, off, err = unpackStringBase64(msg, off, rdStart+int(rr.Hdr.Rdlength))
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackKEY(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(KEY)
 	rr.Hdr = h
@@ -2771,6 +3123,23 @@ func unpackPX(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackRESINFO(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(RESINFO)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Txt, off, err = unpackStringTxt(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackRFC3597(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(RFC3597)
 	rr.Hdr = h
@@ -3203,6 +3572,37 @@ func unpackSSHFP(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackSVCB(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(SVCB)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Priority, off, err = unpackUint16(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Target, off, err = UnpackDomainName(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Value, off, err = unpackDataSVCB(msg, off, rdStart+int(rr.Hdr.Rdlength))
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackTA(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(TA)
 	rr.Hdr = h
@@ -3525,6 +3925,23 @@ func unpackURI(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackWALLET(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(WALLET)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Txt, off, err = unpackStringTxt(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 func unpackX25(h RR_Header, msg []byte, off int) (RR, int, error) {
 	rr := new(X25)
 	rr.Hdr = h
@@ -3542,11 +3959,51 @@ func unpackX25(h RR_Header, msg []byte, off int) (RR, int, error) {
 	return rr, off, err
 }
 
+func unpackZONEMD(h RR_Header, msg []byte, off int) (RR, int, error) {
+	rr := new(ZONEMD)
+	rr.Hdr = h
+	if noRdata(h) {
+		return rr, off, nil
+	}
+	var err error
+	rdStart := off
+	_ = rdStart
+
+	rr.Serial, off, err = unpackUint32(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Scheme, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Hash, off, err = unpackUint8(msg, off)
+	if err != nil {
+		return rr, off, err
+	}
+	if off == len(msg) {
+		return rr, off, nil
+	}
+	rr.Digest, off, err = unpackStringHex(msg, off, rdStart+int(rr.Hdr.Rdlength))
+	if err != nil {
+		return rr, off, err
+	}
+	return rr, off, err
+}
+
 var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeA:          unpackA,
 	TypeAAAA:       unpackAAAA,
 	TypeAFSDB:      unpackAFSDB,
+	TypeAMTRELAY:   unpackAMTRELAY,
 	TypeANY:        unpackANY,
+	TypeAPL:        unpackAPL,
 	TypeAVC:        unpackAVC,
 	TypeCAA:        unpackCAA,
 	TypeCDNSKEY:    unpackCDNSKEY,
@@ -3559,6 +4016,7 @@ var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeDNAME:      unpackDNAME,
 	TypeDNSKEY:     unpackDNSKEY,
 	TypeDS:         unpackDS,
+	TypeDSYNC:      unpackDSYNC,
 	TypeEID:        unpackEID,
 	TypeEUI48:      unpackEUI48,
 	TypeEUI64:      unpackEUI64,
@@ -3566,6 +4024,8 @@ var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeGPOS:       unpackGPOS,
 	TypeHINFO:      unpackHINFO,
 	TypeHIP:        unpackHIP,
+	TypeHTTPS:      unpackHTTPS,
+	TypeIPSECKEY:   unpackIPSECKEY,
 	TypeKEY:        unpackKEY,
 	TypeKX:         unpackKX,
 	TypeL32:        unpackL32,
@@ -3592,6 +4052,7 @@ var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeOPT:        unpackOPT,
 	TypePTR:        unpackPTR,
 	TypePX:         unpackPX,
+	TypeRESINFO:    unpackRESINFO,
 	TypeRKEY:       unpackRKEY,
 	TypeRP:         unpackRP,
 	TypeRRSIG:      unpackRRSIG,
@@ -3602,6 +4063,7 @@ var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeSPF:        unpackSPF,
 	TypeSRV:        unpackSRV,
 	TypeSSHFP:      unpackSSHFP,
+	TypeSVCB:       unpackSVCB,
 	TypeTA:         unpackTA,
 	TypeTALINK:     unpackTALINK,
 	TypeTKEY:       unpackTKEY,
@@ -3611,5 +4073,7 @@ var typeToUnpack = map[uint16]func(RR_Header, []byte, int) (RR, int, error){
 	TypeUID:        unpackUID,
 	TypeUINFO:      unpackUINFO,
 	TypeURI:        unpackURI,
+	TypeWALLET:     unpackWALLET,
 	TypeX25:        unpackX25,
+	TypeZONEMD:     unpackZONEMD,
 }
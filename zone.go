@@ -0,0 +1,382 @@
+package dns
+
+// An in-memory authoritative zone, answering with the algorithm of RFC 1034
+// section 4.3.2, including the updates for DNAME (RFC 6672), wildcards (RFC
+// 4592) and negative caching (RFC 2308).
+
+import (
+	"io"
+	"strings"
+	"sync"
+)
+
+// maxZoneChain is the maximum number of CNAME and DNAME records followed when
+// answering a query.
+const maxZoneChain = 8
+
+// Zone is an in-memory authoritative zone. It is a Handler, the zero value is
+// not usable, use NewZone or ReadZone.
+//
+// A Zone is safe for concurrent use by multiple goroutines, records can be
+// inserted while it is serving.
+type Zone struct {
+	Origin string // the lower cased, fully qualified, name of the apex
+
+	m     sync.RWMutex
+	nodes map[string]*zoneNode // lower cased owner name
+
+	im    sync.Mutex // protects index
+	index *zoneIndex // the denial of existence records, nil if not yet indexed
+}
+
+// zoneNode holds the RRsets of a name. A name without RRsets is an empty
+// non-terminal, it exists because there are names below it.
+type zoneNode struct {
+	rrsets map[uint16][]RR
+}
+
+// NewZone returns a new empty zone for origin.
+func NewZone(origin string) *Zone {
+	origin = strings.ToLower(Fqdn(origin))
+	return &Zone{Origin: origin, nodes: map[string]*zoneNode{origin: {}}}
+}
+
+// ReadZone returns a zone for origin with the records read from r, see
+// ParseZone for the arguments. The first error is returned.
+func ReadZone(r io.Reader, origin, file string) (*Zone, error) {
+	z := NewZone(origin)
+	tokens := ParseZone(r, origin, file)
+	defer func() {
+		// Drain the channel, so the parser does not leak.
+		for range tokens {
+		}
+	}()
+	for t := range tokens {
+		if t.Error != nil {
+			return nil, t.Error
+		}
+		if err := z.Insert(t.RR); err != nil {
+			return nil, err
+		}
+	}
+	return z, nil
+}
+
+// Insert adds rr to the zone, rr is ignored if the zone already holds it.
+// It returns an error if rr is not in the zone.
+func (z *Zone) Insert(rr RR) error {
+	name := strings.ToLower(rr.Header().Name)
+	if !IsSubDomain(z.Origin, name) {
+		return &Error{err: "out of zone record: " + rr.Header().Name}
+	}
+
+	z.m.Lock()
+	defer z.m.Unlock()
+	z.insert(name, rr)
+	return nil
+}
+
+// insert adds rr, with the lower cased owner name, to the zone. z.m must be
+// held.
+func (z *Zone) insert(name string, rr RR) {
+	n, ok := z.nodes[name]
+	if !ok {
+		n = new(zoneNode)
+		z.nodes[name] = n
+		// Create the empty non-terminals between the apex and the new name.
+		for off, end := NextLabel(name, 0); !end && len(name)-off > len(z.Origin); off, end = NextLabel(name, off) {
+			if _, ok := z.nodes[name[off:]]; !ok {
+				z.nodes[name[off:]] = new(zoneNode)
+			}
+		}
+	}
+	if n.rrsets == nil {
+		n.rrsets = make(map[uint16][]RR)
+	}
+	t := rr.Header().Rrtype
+	for _, r := range n.rrsets[t] {
+		if sameRdata(r, rr) {
+			return
+		}
+	}
+	n.rrsets[t] = append(n.rrsets[t], rr)
+	z.index = nil
+}
+
+// sameRdata returns true if a and b, of the same type, have the same rdata.
+func sameRdata(a, b RR) bool {
+	as, bs := a.String(), b.String()
+	return as[len(a.Header().String()):] == bs[len(b.Header().String()):]
+}
+
+// Sig0Key implements the Sig0KeyProvider interface, it returns the matching
+// KEY record of the zone.
+func (z *Zone) Sig0Key(signer string, algorithm uint8, keyTag uint16) (*KEY, error) {
+	z.m.RLock()
+	defer z.m.RUnlock()
+	if n, ok := z.nodes[strings.ToLower(signer)]; ok {
+		for _, rr := range n.rrsets[TypeKEY] {
+			if k := rr.(*KEY); k.Algorithm == algorithm && k.KeyTag() == keyTag {
+				return k, nil
+			}
+		}
+	}
+	return nil, ErrKey
+}
+
+// ServeDNS implements the Handler interface, it writes the response returned
+// by Answer.
+func (z *Zone) ServeDNS(w ResponseWriter, r *Msg) {
+	w.WriteMsg(z.Answer(r))
+}
+
+// Answer returns the response to the query req. The response is
+// authoritative, unless it is a referral, and carries the SOA in the
+// authority section when the name or data does not exist. A query for a name
+// outside of the zone is refused.
+//
+// If req has the DO bit set, the RRSIGs of the RRsets in the response are
+// added, as are the NSEC or NSEC3 records that prove the non-existence of
+// names, types and DS records, as described in RFC 4035 section 3.1 and RFC
+// 5155 section 7.2. These records must be in the zone, i.e. it must have been
+// signed.
+func (z *Zone) Answer(req *Msg) *Msg {
+	m := new(Msg)
+	m.SetReply(req)
+	if len(req.Question) != 1 {
+		m.Rcode = RcodeFormatError
+		return m
+	}
+	q := req.Question[0]
+	if !IsSubDomain(z.Origin, q.Name) {
+		m.Rcode = RcodeRefused
+		return m
+	}
+	m.Authoritative = true
+	do := false
+	if opt := req.IsEdns0(); opt != nil {
+		do = opt.Do()
+		defer m.SetEdns0(opt.UDPSize(), do)
+	}
+
+	z.m.RLock()
+	defer z.m.RUnlock()
+
+	qname := q.Name
+	seen := make(map[string]bool)
+	for chain := 0; chain <= maxZoneChain; chain++ {
+		seen[strings.ToLower(qname)] = true
+		next, done := z.answer(m, qname, q.Qtype, do)
+		// Stop if the chain leaves the zone or loops, the client continues
+		// from here.
+		if done || !IsSubDomain(z.Origin, next) || seen[strings.ToLower(next)] {
+			return m
+		}
+		qname = next
+	}
+	return m
+}
+
+// answer adds the answer for qname to m. If it hits a CNAME or DNAME it
+// returns the name to continue with and false.
+func (z *Zone) answer(m *Msg, qname string, qtype uint16, do bool) (string, bool) {
+	lname := strings.ToLower(qname)
+	idx := Split(lname)
+	at := func(i int) string {
+		if i == len(idx) {
+			return "."
+		}
+		return lname[idx[i]:]
+	}
+
+	// Walk down from the apex to qname, stopping at a zone cut or DNAME.
+	var (
+		node     *zoneNode
+		wildcard bool
+		ce       string // the closest encloser, if qname does not exist
+	)
+	for i := len(idx) - CountLabel(z.Origin); i >= 0; i-- {
+		name := at(i)
+		n, ok := z.nodes[name]
+		if !ok {
+			// name does not exist, but a wildcard at the closest encloser may.
+			ce = at(i + 1)
+			if n, ok = z.nodes[wildcardName(ce)]; !ok {
+				m.Rcode = RcodeNameError
+				z.addSOA(m, do)
+				if do {
+					z.denyName(m, lname, ce)
+				}
+				return "", true
+			}
+			node, wildcard = n, true
+			break
+		}
+		if name != z.Origin && n.rrsets[TypeNS] != nil && (i > 0 || qtype != TypeDS) {
+			z.referral(m, name, n, do)
+			return "", true
+		}
+		if i > 0 && n.rrsets[TypeDNAME] != nil {
+			return z.dname(m, qname, name, n, do)
+		}
+		node = n
+	}
+
+	// The name, or a wildcard for it, exists.
+	if cname, ok := node.rrsets[TypeCNAME]; ok && qtype != TypeCNAME && qtype != TypeANY {
+		m.Answer = append(m.Answer, node.rrset(TypeCNAME, qname, wildcard, do)...)
+		if do && wildcard {
+			z.proveWildcard(m, lname, ce)
+		}
+		return cname[0].(*CNAME).Target, false
+	}
+	var rrs []RR
+	if qtype == TypeANY {
+		for t := range node.rrsets {
+			if t != TypeRRSIG {
+				rrs = append(rrs, node.rrset(t, qname, wildcard, do)...)
+			}
+		}
+	} else {
+		rrs = node.rrset(qtype, qname, wildcard, do)
+	}
+	if len(rrs) == 0 {
+		z.addSOA(m, do)
+		switch {
+		case do && wildcard:
+			z.denyWildcardType(m, lname, ce)
+		case do:
+			z.denyType(m, lname)
+		}
+		return "", true
+	}
+	m.Answer = append(m.Answer, rrs...)
+	if do && wildcard {
+		z.proveWildcard(m, lname, ce)
+	}
+	z.additional(m, rrs, do)
+	return "", true
+}
+
+// dname adds the DNAME of n and the CNAME it synthesizes for qname, which is
+// below owner, to m and returns the target of the CNAME.
+func (z *Zone) dname(m *Msg, qname, owner string, n *zoneNode, do bool) (string, bool) {
+	d := n.rrsets[TypeDNAME][0].(*DNAME)
+	target := qname[:len(qname)-len(owner)] + d.Target
+	if d.Target == "." {
+		target = qname[:len(qname)-len(owner)]
+	}
+	if len(target) > 255 {
+		m.Rcode = RcodeYXDomain
+		return "", true
+	}
+	m.Answer = append(m.Answer, n.rrset(TypeDNAME, "", false, do)...)
+	m.Answer = append(m.Answer, &CNAME{
+		Hdr:    RR_Header{Name: qname, Rrtype: TypeCNAME, Class: d.Hdr.Class, Ttl: d.Hdr.Ttl},
+		Target: target,
+	})
+	return target, false
+}
+
+// referral turns m into a referral to the zone cut name, with its name
+// servers and their glue. With DNSSEC the DS records are added, or the proof
+// that there are none.
+func (z *Zone) referral(m *Msg, name string, n *zoneNode, do bool) {
+	// The answer is only authoritative for the aliases already added.
+	if len(m.Answer) == 0 {
+		m.Authoritative = false
+	}
+	ns := n.rrset(TypeNS, "", false, do)
+	m.Ns = append(m.Ns, ns...)
+	if do {
+		if ds := n.rrset(TypeDS, "", false, do); len(ds) > 0 {
+			m.Ns = append(m.Ns, ds...)
+		} else {
+			z.denyType(m, name)
+		}
+	}
+	z.additional(m, ns, do)
+}
+
+// addSOA adds the SOA to the authority section of m. Its TTL is the TTL to
+// cache the negative answer, see RFC 2308 section 5.
+func (z *Zone) addSOA(m *Msg, do bool) {
+	n := z.nodes[z.Origin]
+	if len(n.rrsets[TypeSOA]) == 0 {
+		return
+	}
+	soa := n.rrset(TypeSOA, "", false, do)
+	ttl := soa[0].(*SOA).Minttl
+	for _, rr := range soa {
+		if ttl < rr.Header().Ttl {
+			rr.Header().Ttl = ttl
+		}
+	}
+	m.Ns = append(m.Ns, soa...)
+}
+
+// additional adds the addresses of the names in rrs to the additional section
+// of m, including the glue below zone cuts.
+func (z *Zone) additional(m *Msg, rrs []RR, do bool) {
+	for _, rr := range rrs {
+		var name string
+		switch rr := rr.(type) {
+		case *NS:
+			name = rr.Ns
+		case *MX:
+			name = rr.Mx
+		case *SRV:
+			name = rr.Target
+		default:
+			continue
+		}
+		n, ok := z.nodes[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+	Addrs:
+		for _, t := range []uint16{TypeA, TypeAAAA} {
+			addrs := n.rrset(t, "", false, do)
+			if len(addrs) == 0 {
+				continue
+			}
+			for _, e := range m.Extra {
+				if e.Header().Rrtype == t && strings.EqualFold(e.Header().Name, name) {
+					continue Addrs
+				}
+			}
+			m.Extra = append(m.Extra, addrs...)
+		}
+	}
+}
+
+// rrset returns a copy of the RRset of type t, with its RRSIGs if do is
+// true. If wildcard is true the owner name is set to qname.
+func (n *zoneNode) rrset(t uint16, qname string, wildcard, do bool) []RR {
+	rrs := zoneCopy(n.rrsets[t], qname, wildcard)
+	if !do || len(rrs) == 0 {
+		return rrs
+	}
+	for _, rr := range n.rrsets[TypeRRSIG] {
+		if rr.(*RRSIG).TypeCovered == t {
+			rrs = append(rrs, zoneCopy([]RR{rr}, qname, wildcard)...)
+		}
+	}
+	return rrs
+}
+
+// zoneCopy returns copies of rrs, if wildcard is true their owner name is
+// set to qname.
+func zoneCopy(rrs []RR, qname string, wildcard bool) []RR {
+	if len(rrs) == 0 {
+		return nil
+	}
+	c := make([]RR, len(rrs))
+	for i, rr := range rrs {
+		c[i] = Copy(rr)
+		if wildcard {
+			c[i].Header().Name = qname
+		}
+	}
+	return c
+}
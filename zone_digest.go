@@ -0,0 +1,180 @@
+package dns
+
+// Message digests of a Zone, RFC 8976.
+
+import (
+	"crypto/sha512"
+	"encoding/hex"
+	"hash"
+	"sort"
+	"strings"
+)
+
+// Schemes and hash algorithms of ZONEMD records, RFC 8976 section 5.
+const (
+	ZoneMDSchemeSimple = 1
+
+	ZoneMDHashAlgSHA384 = 1
+	ZoneMDHashAlgSHA512 = 2
+)
+
+// Digest returns the ZONEMD record with the digest of the zone for scheme,
+// which must be ZoneMDSchemeSimple, and hash. It has the serial and TTL of
+// the SOA record.
+//
+// The digest is calculated over all records of the zone, including glue and
+// occluded data, in canonical order, RFC 8976 section 3.3. The apex ZONEMD
+// RRset and the RRSIGs covering it are left out.
+//
+// A signed zone needs a ZONEMD record at the apex before signing, so the
+// type is in the bitmap of its NSEC or NSEC3 record, RFC 8976 section 3.1.
+// A placeholder can be added with Insert, then replaced with SetDigest after
+// signing, after which the zone must be signed again.
+func (z *Zone) Digest(scheme, hash uint8) (*ZONEMD, error) {
+	z.m.RLock()
+	defer z.m.RUnlock()
+	return z.digest(scheme, hash)
+}
+
+// SetDigest replaces the apex ZONEMD record of the zone for scheme and hash,
+// if any, with the one returned by Digest, and returns it. The ZONEMD
+// records for other schemes and hashes are kept.
+func (z *Zone) SetDigest(scheme, hash uint8) (*ZONEMD, error) {
+	z.m.Lock()
+	defer z.m.Unlock()
+	rr, err := z.digest(scheme, hash)
+	if err != nil {
+		return nil, err
+	}
+	apex := z.nodes[z.Origin]
+	var rrset []RR
+	for _, r := range apex.rrsets[TypeZONEMD] {
+		if md := r.(*ZONEMD); md.Scheme != scheme || md.Hash != hash {
+			rrset = append(rrset, r)
+		}
+	}
+	apex.rrsets[TypeZONEMD] = append(rrset, rr)
+	z.index = nil
+	return rr, nil
+}
+
+// VerifyDigest verifies the zone with its apex ZONEMD records, RFC 8976
+// section 4. The zone is verified if one of the records with a supported
+// scheme and hash, and the serial of the SOA record, has the digest of the
+// zone. It returns ErrDigest if there is no such record, and ErrZoneMD if
+// the digests do not match.
+//
+// If the zone is signed the ZONEMD RRset should be validated first, see
+// Validator.
+func (z *Zone) VerifyDigest() error {
+	z.m.RLock()
+	defer z.m.RUnlock()
+	apex := z.nodes[z.Origin]
+	soa := apex.rrsets[TypeSOA]
+	if len(soa) == 0 {
+		return &Error{err: "no SOA record in zone: " + z.Origin}
+	}
+	serial := soa[0].(*SOA).Serial
+
+	// Only one record for each scheme and hash is allowed, RFC 8976 section
+	// 2.4.
+	seen := make(map[[2]uint8]bool)
+	for _, r := range apex.rrsets[TypeZONEMD] {
+		md := r.(*ZONEMD)
+		if seen[[2]uint8{md.Scheme, md.Hash}] {
+			return &Error{err: "multiple ZONEMD records with the same scheme and hash: " + z.Origin}
+		}
+		seen[[2]uint8{md.Scheme, md.Hash}] = true
+	}
+
+	err := ErrDigest
+	for _, r := range apex.rrsets[TypeZONEMD] {
+		md := r.(*ZONEMD)
+		if newZoneMDHash(md.Scheme, md.Hash) == nil {
+			continue
+		}
+		if md.Serial != serial {
+			err = &Error{err: "ZONEMD serial does not match the SOA serial: " + z.Origin}
+			continue
+		}
+		digest, err1 := z.digest(md.Scheme, md.Hash)
+		if err1 != nil {
+			return err1
+		}
+		if strings.EqualFold(digest.Digest, md.Digest) {
+			return nil
+		}
+		err = ErrZoneMD
+	}
+	return err
+}
+
+// digest returns the ZONEMD record of the zone for scheme and hash. z.m must
+// be held.
+func (z *Zone) digest(scheme, hash uint8) (*ZONEMD, error) {
+	h := newZoneMDHash(scheme, hash)
+	if h == nil {
+		return nil, ErrDigest
+	}
+	soa := z.nodes[z.Origin].rrsets[TypeSOA]
+	if len(soa) == 0 {
+		return nil, &Error{err: "no SOA record in zone: " + z.Origin}
+	}
+
+	names := make([]string, 0, len(z.nodes))
+	for name := range z.nodes {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return canonicalNameCompare(names[i], names[j]) < 0 })
+
+	var buf []byte
+	for _, name := range names {
+		n := z.nodes[name]
+		types := make([]uint16, 0, len(n.rrsets))
+		for t := range n.rrsets {
+			types = append(types, t)
+		}
+		sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+		for _, t := range types {
+			if name == z.Origin && t == TypeZONEMD {
+				continue
+			}
+			var wires wireSlice
+			for _, rr := range n.rrsets[t] {
+				if sig, ok := rr.(*RRSIG); ok && name == z.Origin && sig.TypeCovered == TypeZONEMD {
+					continue
+				}
+				wire, err := canonicalWire(rr.copy())
+				if err != nil {
+					return nil, err
+				}
+				wires = append(wires, wire)
+			}
+			buf = wires.appendCanonical(buf[:0])
+			h.Write(buf)
+		}
+	}
+
+	return &ZONEMD{
+		Hdr:    RR_Header{Name: soa[0].Header().Name, Rrtype: TypeZONEMD, Class: soa[0].Header().Class, Ttl: soa[0].Header().Ttl},
+		Serial: soa[0].(*SOA).Serial,
+		Scheme: scheme,
+		Hash:   hash,
+		Digest: strings.ToUpper(hex.EncodeToString(h.Sum(nil))),
+	}, nil
+}
+
+// newZoneMDHash returns the hash for the scheme and hash of a ZONEMD record,
+// nil if they are not supported.
+func newZoneMDHash(scheme, alg uint8) hash.Hash {
+	if scheme != ZoneMDSchemeSimple {
+		return nil
+	}
+	switch alg {
+	case ZoneMDHashAlgSHA384:
+		return sha512.New384()
+	case ZoneMDHashAlgSHA512:
+		return sha512.New()
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// The simple example zone of RFC 8976 appendix A.1.
+const zonemdExample = `example.      86400  IN  SOA     ns1 admin 2018031900 (
+                                 1800 900 604800 86400 )
+              86400  IN  NS      ns1
+              86400  IN  NS      ns2
+              86400  IN  ZONEMD  2018031900 1 1 (
+                                 c68090d90a7aed71
+                                 6bc459f9340e3d7c
+                                 1370d4d24b7e2fc3
+                                 a1ddc0b9a87153b9
+                                 a9713b3c9ae5cc27
+                                 777f98b8e730044c )
+ns1           3600   IN  A       203.0.113.63
+ns2           3600   IN  AAAA    2001:db8::63
+`
+
+func TestZoneDigest(t *testing.T) {
+	tests := []struct {
+		name  string
+		extra string
+		err   bool
+	}{
+		{"example", "", false},
+		{"unsupported hash too", "@ 86400 IN ZONEMD 2018031900 1 240 e2d523f654b9422a96c5a8f44607bbee\n", false},
+		{"changed", "ns3 3600 IN A 203.0.113.64\n", true},
+		{"other serial", "@ 86400 IN ZONEMD 2018031901 1 2 08cfa1115c7b948c4163a901270395ea226a930cd2cbcf2fa9a5e6eb85f37c8a4e114d884e66f176eab121cb02db7d652e0cc4827e7a3204f166b47e5613fd27\n", false},
+		{"duplicate", "@ 86400 IN ZONEMD 2018031900 1 1 00\n", true},
+	}
+	for _, tc := range tests {
+		z, err := ReadZone(strings.NewReader(zonemdExample+tc.extra), "example.", "zonemdExample")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := z.VerifyDigest(); (err != nil) != tc.err {
+			t.Errorf("%s: got error %v", tc.name, err)
+		}
+	}
+
+	z, err := ReadZone(strings.NewReader(zonemdExample), "example.", "zonemdExample")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md, err := z.Digest(ZoneMDSchemeSimple, ZoneMDHashAlgSHA384)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "example.\t86400\tIN\tZONEMD\t2018031900 1 1 C68090D90A7AED716BC459F9340E3D7C1370D4D24B7E2FC3A1DDC0B9A87153B9A9713B3C9AE5CC27777F98B8E730044C"
+	if md.String() != want {
+		t.Errorf("got %s, want %s", md, want)
+	}
+	if _, err := z.Digest(ZoneMDSchemeSimple, 240); err != ErrDigest {
+		t.Errorf("got error %v for an unsupported hash, want %v", err, ErrDigest)
+	}
+
+	// Only records with a supported scheme and hash, and the serial of the SOA, verify.
+	for _, extra := range []string{"", "@ 86400 IN ZONEMD 2018031900 2 1 00\n", "@ 86400 IN ZONEMD 2018031901 1 1 00\n"} {
+		z, err := ReadZone(strings.NewReader(strings.Replace(zonemdExample, "ZONEMD  2018031900 1 1", "ZONEMD  2018031900 1 2", 1)+extra), "example.", "zonemdExample")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := z.VerifyDigest(); err == nil {
+			t.Errorf("verified a zone with the SHA-384 digest in a SHA-512 record, with %q", extra)
+		}
+	}
+}
+
+func TestZoneSetDigest(t *testing.T) {
+	now := time.Now()
+	z, err := ReadZone(strings.NewReader(testZone+"@ IN ZONEMD 0 1 1 000000000000000000000000\n"), "example.org.", "testZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []SigningKey{newSigningKey(t, "example.org.", ZONE|SEP), newSigningKey(t, "example.org.", ZONE)}
+	if err := z.Sign(keys, &SignPolicy{}, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := z.VerifyDigest(); err == nil {
+		t.Fatal("verified the placeholder digest")
+	}
+
+	for _, hash := range []uint8{ZoneMDHashAlgSHA384, ZoneMDHashAlgSHA512} {
+		if _, err := z.SetDigest(ZoneMDSchemeSimple, hash); err != nil {
+			t.Fatal(err)
+		}
+	}
+	before, err := z.Digest(ZoneMDSchemeSimple, ZoneMDHashAlgSHA512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Signing the new ZONEMD RRset does not change the digest.
+	if err := z.Sign(keys, &SignPolicy{}, now); err != nil {
+		t.Fatal(err)
+	}
+	after, err := z.Digest(ZoneMDSchemeSimple, ZoneMDHashAlgSHA512)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Digest != after.Digest {
+		t.Errorf("digest changed after signing: %s, was %s", after.Digest, before.Digest)
+	}
+	if err := z.VerifyDigest(); err != nil {
+		t.Errorf("failed to verify the signed zone: %v", err)
+	}
+	if n := len(z.nodes[z.Origin].rrsets[TypeZONEMD]); n != 2 {
+		t.Errorf("got %d ZONEMD records, want 2", n)
+	}
+	verifySigs(t, "zonemd", zoneRecords(z), keys)
+
+	z.Insert(testRR("www.example.org. IN A 192.0.2.99"))
+	if err := z.VerifyDigest(); err != ErrZoneMD {
+		t.Errorf("got error %v after changing the zone, want %v", err, ErrZoneMD)
+	}
+}
@@ -0,0 +1,262 @@
+package dns
+
+// Authenticated denial of existence for the answers of a signed Zone, RFC
+// 4035 section 3.1.3 for NSEC and RFC 5155 section 7.2 for NSEC3.
+
+import (
+	"sort"
+	"strings"
+)
+
+// zoneIndex holds the NSEC and NSEC3 records of a zone in the order needed
+// to find the ones matching or covering a name.
+type zoneIndex struct {
+	nsec  []*NSEC  // in canonical order
+	nsec3 []*NSEC3 // ordered on the hash, all with the parameters of the first
+	hash  []string // the upper cased hashes of nsec3
+}
+
+// denial returns the index of the zone, building it if needed. z.m must be
+// held.
+func (z *Zone) denial() *zoneIndex {
+	z.im.Lock()
+	defer z.im.Unlock()
+	if z.index != nil {
+		return z.index
+	}
+
+	x := new(zoneIndex)
+	var param *NSEC3PARAM
+	if p := z.nodes[z.Origin].rrsets[TypeNSEC3PARAM]; len(p) > 0 {
+		param = p[0].(*NSEC3PARAM)
+	}
+	for _, n := range z.nodes {
+		for _, rr := range n.rrsets[TypeNSEC] {
+			x.nsec = append(x.nsec, rr.(*NSEC))
+		}
+		for _, rr := range n.rrsets[TypeNSEC3] {
+			rr := rr.(*NSEC3)
+			if param == nil {
+				param = &NSEC3PARAM{Hash: rr.Hash, Iterations: rr.Iterations, Salt: rr.Salt}
+			}
+			if rr.Hash == param.Hash && rr.Iterations == param.Iterations && strings.EqualFold(rr.Salt, param.Salt) {
+				x.nsec3 = append(x.nsec3, rr)
+			}
+		}
+	}
+	sort.Slice(x.nsec, func(i, j int) bool {
+		return canonicalNameCompare(x.nsec[i].Hdr.Name, x.nsec[j].Hdr.Name) < 0
+	})
+	sort.Slice(x.nsec3, func(i, j int) bool {
+		return nsec3Hash(x.nsec3[i]) < nsec3Hash(x.nsec3[j])
+	})
+	x.hash = make([]string, len(x.nsec3))
+	for i, rr := range x.nsec3 {
+		x.hash[i] = nsec3Hash(rr)
+	}
+	z.index = x
+	return x
+}
+
+// nsec3Hash returns the upper cased hash in the owner name of rr.
+func nsec3Hash(rr *NSEC3) string {
+	name := rr.Hdr.Name
+	if i, end := NextLabel(name, 0); !end {
+		name = name[:i-1]
+	}
+	return strings.ToUpper(name)
+}
+
+// nsecFor returns the NSEC that matches or covers name, nil if the zone has
+// no NSEC records.
+func (x *zoneIndex) nsecFor(name string) RR {
+	if len(x.nsec) == 0 {
+		return nil
+	}
+	// The first NSEC after name, the one before it matches or covers name.
+	i := sort.Search(len(x.nsec), func(i int) bool {
+		return canonicalNameCompare(x.nsec[i].Hdr.Name, name) > 0
+	})
+	if i == 0 {
+		// Before the apex, can only happen for names outside of the zone.
+		i = len(x.nsec)
+	}
+	return x.nsec[i-1]
+}
+
+// nsec3For returns the NSEC3 that matches or covers name and whether it
+// matches. It returns nil if the zone has no NSEC3 records.
+func (x *zoneIndex) nsec3For(name string) (*NSEC3, bool) {
+	if len(x.nsec3) == 0 {
+		return nil, false
+	}
+	first := x.nsec3[0]
+	h := HashName(name, first.Hash, first.Iterations, first.Salt)
+	i := sort.SearchStrings(x.hash, h)
+	if i < len(x.hash) && x.hash[i] == h {
+		return x.nsec3[i], true
+	}
+	if i == 0 {
+		// Before the first hash, covered by the last NSEC3 that wraps around.
+		i = len(x.nsec3)
+	}
+	return x.nsec3[i-1], false
+}
+
+// addDenial adds rr, an NSEC or NSEC3, and its RRSIGs to the authority
+// section of m, unless it is already there.
+func (z *Zone) addDenial(m *Msg, rr RR) {
+	if rr == nil {
+		return
+	}
+	h := rr.Header()
+	for _, e := range m.Ns {
+		if e.Header().Rrtype == h.Rrtype && strings.EqualFold(e.Header().Name, h.Name) {
+			return
+		}
+	}
+	if n, ok := z.nodes[strings.ToLower(h.Name)]; ok {
+		m.Ns = append(m.Ns, n.rrset(h.Rrtype, "", false, true)...)
+	}
+}
+
+// closestEncloserProof adds the NSEC3 records that match ce and cover the
+// next closer name of qname, RFC 5155 section 7.2.1.
+func (z *Zone) closestEncloserProof(m *Msg, x *zoneIndex, qname, ce string) {
+	if rr, ok := x.nsec3For(ce); ok {
+		z.addDenial(m, rr)
+	}
+	rr, _ := x.nsec3For(nextCloser(qname, ce))
+	z.addDenial(m, rr)
+}
+
+// denyName adds the proof that qname, below the closest encloser ce, and the
+// wildcard at ce do not exist.
+func (z *Zone) denyName(m *Msg, qname, ce string) {
+	x := z.denial()
+	if len(x.nsec3) > 0 {
+		z.closestEncloserProof(m, x, qname, ce)
+		rr, _ := x.nsec3For(wildcardName(ce))
+		z.addDenial(m, rr)
+		return
+	}
+	z.addDenial(m, x.nsecFor(qname))
+	z.addDenial(m, x.nsecFor(wildcardName(ce)))
+}
+
+// denyType adds the proof that the existing name has no data of the
+// queried type. For a delegation this proves there is no DS.
+func (z *Zone) denyType(m *Msg, name string) {
+	x := z.denial()
+	if len(x.nsec3) == 0 {
+		z.addDenial(m, x.nsecFor(name))
+		return
+	}
+	if rr, ok := x.nsec3For(name); ok {
+		z.addDenial(m, rr)
+		return
+	}
+	// No NSEC3 for name, as it is an insecure delegation or an empty
+	// non-terminal in an opt-out span. Prove the closest provable encloser
+	// and that the next closer name is covered by an opt-out NSEC3, RFC 5155
+	// section 7.2.4.
+	for ce := name; ce != z.Origin; {
+		off, _ := NextLabel(ce, 0)
+		ce = ce[off:]
+		if _, ok := x.nsec3For(ce); ok {
+			z.closestEncloserProof(m, x, name, ce)
+			return
+		}
+	}
+}
+
+// proveWildcard adds the proof that qname, below the closest encloser ce, does
+// not exist, so it could be answered from the wildcard.
+func (z *Zone) proveWildcard(m *Msg, qname, ce string) {
+	x := z.denial()
+	if len(x.nsec3) > 0 {
+		rr, _ := x.nsec3For(nextCloser(qname, ce))
+		z.addDenial(m, rr)
+		return
+	}
+	z.addDenial(m, x.nsecFor(qname))
+}
+
+// denyWildcardType adds the proof that qname does not exist and the wildcard
+// at the closest encloser ce has no data of the queried type.
+func (z *Zone) denyWildcardType(m *Msg, qname, ce string) {
+	x := z.denial()
+	if len(x.nsec3) > 0 {
+		z.closestEncloserProof(m, x, qname, ce)
+		if rr, ok := x.nsec3For(wildcardName(ce)); ok {
+			z.addDenial(m, rr)
+		}
+		return
+	}
+	z.addDenial(m, x.nsecFor(qname))
+	z.addDenial(m, x.nsecFor(wildcardName(ce)))
+}
+
+// wildcardName returns the wildcard name at ce.
+func wildcardName(ce string) string {
+	if ce == "." {
+		return "*."
+	}
+	return "*." + ce
+}
+
+// nextCloser returns the name one label longer than ce of which qname is, or
+// is below, the closest encloser.
+func nextCloser(qname, ce string) string {
+	idx := Split(qname)
+	i := len(idx) - CountLabel(ce) - 1
+	if i < 0 {
+		return qname
+	}
+	return qname[idx[i]:]
+}
+
+// canonicalNameCompare compares the names a and b in the canonical order of
+// RFC 4034 section 6.1. It returns an integer less than, equal to or greater
+// than zero if a sorts before, equal to or after b.
+func canonicalNameCompare(a, b string) int {
+	la, lb := SplitDomainName(a), SplitDomainName(b)
+	i, j := len(la)-1, len(lb)-1
+	for ; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(canonicalLabel(la[i]), canonicalLabel(lb[j])); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case i < 0 && j < 0:
+		return 0
+	case i < 0:
+		return -1
+	}
+	return 1
+}
+
+// canonicalLabel returns label, in presentation format, as lower cased
+// octets.
+func canonicalLabel(label string) string {
+	if strings.IndexByte(label, '\\') < 0 {
+		return strings.ToLower(label)
+	}
+	b := make([]byte, 0, len(label))
+	for i := 0; i < len(label); i++ {
+		c := label[i]
+		if c == '\\' && i+1 < len(label) {
+			i++
+			c = label[i]
+			if isDigit(c) && i+2 < len(label) && isDigit(label[i+1]) && isDigit(label[i+2]) {
+				c = dddToByte([]byte(label[i:]))
+				i += 2
+			}
+		}
+		if c >= 'A' && c <= 'Z' {
+			c |= 'a' - 'A'
+		}
+		b = append(b, c)
+	}
+	return string(b)
+}
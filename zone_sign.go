@@ -0,0 +1,287 @@
+package dns
+
+// Signing of a Zone, RFC 4035 section 2, with NSEC or the NSEC3 chain of RFC
+// 5155 section 7.1.
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// signValidity is the validity of the signatures if the policy has none.
+const signValidity = 30 * 24 * time.Hour
+
+// SignPolicy describes how a Zone is signed.
+type SignPolicy struct {
+	NSEC3      bool          // deny existence with NSEC3 instead of NSEC records
+	Iterations uint16        // the additional NSEC3 hash iterations, RFC 9276 recommends 0
+	Salt       string        // the NSEC3 salt in hex, RFC 9276 recommends none
+	OptOut     bool          // do not cover unsigned delegations with NSEC3 records
+	Validity   time.Duration // the validity of signatures, 30 days if zero
+	Jitter     time.Duration // at most this much is randomly taken off the validity, to spread re-signing
+	Refresh    time.Duration // signatures expiring within this are renewed, a quarter of the validity if zero
+}
+
+// Sign signs the zone with keys according to the policy p, at time t. If t is
+// the zero time, the current time is used.
+//
+// The DNSKEYs of keys are added to the apex, the NSEC or NSEC3 chain is built,
+// replacing any existing one, and every authoritative RRset is signed. The NS
+// RRsets at delegations and the glue below them are not signed. The DNSKEY,
+// CDS and CDNSKEY RRsets are signed with the key signing keys, all other
+// RRsets with the zone signing keys, see SigningKey.
+//
+// Signing a signed zone again is incremental: signatures that still validate
+// with one of the keys and do not expire within the refresh window are kept,
+// only the RRsets lacking those are signed again.
+func (z *Zone) Sign(keys []SigningKey, p *SignPolicy, t time.Time) error {
+	if len(keys) == 0 {
+		return ErrKey
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	validity := p.Validity
+	if validity == 0 {
+		validity = signValidity
+	}
+	refresh := p.Refresh
+	if refresh == 0 {
+		refresh = validity / 4
+	}
+
+	z.m.Lock()
+	defer z.m.Unlock()
+	soa := z.nodes[z.Origin].rrsets[TypeSOA]
+	if len(soa) == 0 {
+		return &Error{err: "no SOA record in zone: " + z.Origin}
+	}
+	// The TTL of the denial of existence records, RFC 9077.
+	ttl := soa[0].Header().Ttl
+	if m := soa[0].(*SOA).Minttl; m < ttl {
+		ttl = m
+	}
+
+	for _, k := range keys {
+		if !strings.EqualFold(k.DNSKEY.Hdr.Name, z.Origin) {
+			return &Error{err: "key not at the apex: " + k.DNSKEY.Hdr.Name}
+		}
+		key := Copy(k.DNSKEY)
+		if key.Header().Ttl == 0 {
+			key.Header().Ttl = soa[0].Header().Ttl
+		}
+		z.insert(z.Origin, key)
+	}
+
+	// Remove the old chain, but keep the signatures of the NSEC3 records to
+	// reuse them if the new chain has the same records.
+	nsec3Sigs := make(map[string][]RR)
+	for name, n := range z.nodes {
+		delete(n.rrsets, TypeNSEC)
+		delete(n.rrsets, TypeNSEC3)
+		delete(n.rrsets, TypeNSEC3PARAM)
+		if n.rrsets == nil || name == z.Origin {
+			continue
+		}
+		if _, ok := n.rrsets[TypeRRSIG]; len(n.rrsets) == 0 || ok && len(n.rrsets) == 1 {
+			nsec3Sigs[name] = n.rrsets[TypeRRSIG]
+			delete(z.nodes, name)
+		}
+	}
+
+	if p.NSEC3 {
+		z.nsec3Chain(p, ttl, nsec3Sigs)
+	} else {
+		z.nsecChain(ttl)
+	}
+
+	for name, n := range z.nodes {
+		if err := z.signNode(name, n, keys, t, validity-p.jitter(), refresh); err != nil {
+			return err
+		}
+	}
+	z.index = nil
+	return nil
+}
+
+// jitter returns a random duration of at most p.Jitter.
+func (p *SignPolicy) jitter() time.Duration {
+	if p.Jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(p.Jitter)))
+}
+
+// occluded returns whether name is a delegation and whether it is below one,
+// or below a DNAME, so it is not authoritative. z.m must be held.
+func (z *Zone) occluded(name string) (cut, below bool) {
+	if n := z.nodes[name]; name != z.Origin && n != nil && n.rrsets[TypeNS] != nil {
+		cut = true
+	}
+	for off, end := NextLabel(name, 0); !end && len(name)-off >= len(z.Origin); off, end = NextLabel(name, off) {
+		n := z.nodes[name[off:]]
+		if n == nil {
+			continue
+		}
+		if n.rrsets[TypeDNAME] != nil || name[off:] != z.Origin && n.rrsets[TypeNS] != nil {
+			return cut, true
+		}
+	}
+	return cut, false
+}
+
+// types returns the types at the authoritative name for the bitmap of its NSEC
+// or NSEC3 record, without RRSIG. At a delegation these are only NS and DS.
+func (n *zoneNode) types(cut bool) []uint16 {
+	var types []uint16
+	for t := range n.rrsets {
+		if t != TypeRRSIG && (!cut || t == TypeNS || t == TypeDS) {
+			types = append(types, t)
+		}
+	}
+	return types
+}
+
+// nsecChain adds the NSEC records to the zone. z.m must be held.
+func (z *Zone) nsecChain(ttl uint32) {
+	var names []string
+	for name, n := range z.nodes {
+		if _, below := z.occluded(name); !below && len(n.rrsets) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool { return canonicalNameCompare(names[i], names[j]) < 0 })
+
+	for i, name := range names {
+		n := z.nodes[name]
+		cut, _ := z.occluded(name)
+		types := append(n.types(cut), TypeNSEC, TypeRRSIG)
+		sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+		n.rrsets[TypeNSEC] = []RR{&NSEC{
+			Hdr:        RR_Header{Name: name, Rrtype: TypeNSEC, Class: ClassINET, Ttl: ttl},
+			NextDomain: names[(i+1)%len(names)],
+			TypeBitMap: types,
+		}}
+	}
+}
+
+// nsec3Chain adds the NSEC3PARAM and NSEC3 records to the zone, reusing the
+// signatures in sigs for the NSEC3 records. z.m must be held.
+func (z *Zone) nsec3Chain(p *SignPolicy, ttl uint32, sigs map[string][]RR) {
+	salt := strings.ToUpper(p.Salt)
+	if salt == "-" {
+		salt = ""
+	}
+	var flags uint8
+	if p.OptOut {
+		flags = 1
+	}
+	z.nodes[z.Origin].rrsets[TypeNSEC3PARAM] = []RR{&NSEC3PARAM{
+		Hdr:        RR_Header{Name: z.Origin, Rrtype: TypeNSEC3PARAM, Class: ClassINET},
+		Hash:       SHA1,
+		Iterations: p.Iterations,
+		SaltLength: uint8(len(salt) / 2),
+		Salt:       salt,
+	}}
+
+	// The names that get an NSEC3 record, with the empty non-terminals
+	// above them. With opt-out, unsigned delegations are left out.
+	include := make(map[string]bool)
+	for name, n := range z.nodes {
+		cut, below := z.occluded(name)
+		if below || len(n.rrsets) == 0 || p.OptOut && cut && n.rrsets[TypeDS] == nil {
+			continue
+		}
+		include[name] = true
+		for off, end := NextLabel(name, 0); !end && len(name)-off >= len(z.Origin); off, end = NextLabel(name, off) {
+			include[name[off:]] = true
+		}
+	}
+
+	hashes := make([]string, 0, len(include))
+	names := make(map[string]string, len(include))
+	for name := range include {
+		h := HashName(name, SHA1, p.Iterations, salt)
+		hashes = append(hashes, h)
+		names[h] = name
+	}
+	sort.Strings(hashes)
+
+	for i, h := range hashes {
+		n := z.nodes[names[h]]
+		cut, _ := z.occluded(names[h])
+		types := n.types(cut)
+		if len(types) > 0 && (!cut || n.rrsets[TypeDS] != nil) {
+			types = append(types, TypeRRSIG)
+		}
+		sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+		owner := strings.ToLower(h) + "." + z.Origin
+		rr := &NSEC3{
+			Hdr:        RR_Header{Name: owner, Rrtype: TypeNSEC3, Class: ClassINET, Ttl: ttl},
+			Hash:       SHA1,
+			Flags:      flags,
+			Iterations: p.Iterations,
+			SaltLength: uint8(len(salt) / 2),
+			Salt:       salt,
+			HashLength: 20,
+			NextDomain: hashes[(i+1)%len(hashes)],
+			TypeBitMap: types,
+		}
+		z.nodes[owner] = &zoneNode{rrsets: map[uint16][]RR{TypeNSEC3: {rr}}}
+		if s, ok := sigs[owner]; ok {
+			z.nodes[owner].rrsets[TypeRRSIG] = s
+		}
+	}
+}
+
+// signNode signs the RRsets of the node n with keys. The signatures of a
+// RRset are kept if there is one for every key that signs it, which is valid
+// until after refresh. z.m must be held.
+func (z *Zone) signNode(name string, n *zoneNode, keys []SigningKey, t time.Time, validity, refresh time.Duration) error {
+	cut, below := z.occluded(name)
+	if below {
+		delete(n.rrsets, TypeRRSIG)
+		return nil
+	}
+
+	now := uint32(t.Unix())
+	old := n.rrsets[TypeRRSIG]
+	var sigs []RR
+RRsets:
+	for typ, rrset := range n.rrsets {
+		if typ == TypeRRSIG || cut && typ != TypeDS && typ != TypeNSEC {
+			continue
+		}
+		var keep []RR
+	Keys:
+		for _, k := range signingKeys(keys, typ) {
+			for _, rr := range old {
+				sig := rr.(*RRSIG)
+				if sig.TypeCovered != typ || sig.OrigTtl != rrset[0].Header().Ttl ||
+					time.Duration(int32(sig.Expiration-now))*time.Second <= refresh {
+					continue
+				}
+				if sig.Verify(k.DNSKEY, rrset) == nil {
+					keep = append(keep, sig)
+					continue Keys
+				}
+			}
+			// No usable signature with this key, sign the RRset again.
+			s, err := signRRset(keys, rrset, z.Origin, uint32(t.Add(-signatureSkew).Unix()), uint32(t.Add(validity).Unix()))
+			if err != nil {
+				return err
+			}
+			sigs = append(sigs, s...)
+			continue RRsets
+		}
+		sigs = append(sigs, keep...)
+	}
+	if len(sigs) == 0 {
+		delete(n.rrsets, TypeRRSIG)
+		return nil
+	}
+	n.rrsets[TypeRRSIG] = sigs
+	return nil
+}
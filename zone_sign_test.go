@@ -0,0 +1,206 @@
+package dns
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// zoneRecords returns all records in z.
+func zoneRecords(z *Zone) []RR {
+	var rrs []RR
+	for _, n := range z.nodes {
+		for _, rrset := range n.rrsets {
+			rrs = append(rrs, rrset...)
+		}
+	}
+	return rrs
+}
+
+// zoneSignatures returns the signatures in z, by the name and type covered.
+func zoneSignatures(z *Zone) map[string]string {
+	sigs := make(map[string]string)
+	for _, rr := range zoneRecords(z) {
+		if sig, ok := rr.(*RRSIG); ok {
+			sigs[sig.Hdr.Name+"/"+TypeToString[sig.TypeCovered]] += sig.Signature
+		}
+	}
+	return sigs
+}
+
+func signedTestZone(t *testing.T, p *SignPolicy, now time.Time) (*Zone, []SigningKey) {
+	z, err := ReadZone(strings.NewReader(testZone+"insecure\tIN\tNS\tns.example.net.\n"), "example.org.", "testZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := []SigningKey{newSigningKey(t, "example.org.", ZONE|SEP), newSigningKey(t, "example.org.", ZONE)}
+	if err := z.Sign(keys, p, now); err != nil {
+		t.Fatal(err)
+	}
+	return z, keys
+}
+
+func TestZoneSign(t *testing.T) {
+	z, keys := signedTestZone(t, &SignPolicy{}, time.Time{})
+
+	rrs := zoneRecords(z)
+	if n := verifySigs(t, "zone", rrs, keys); n == 0 {
+		t.Fatal("no signatures in the zone")
+	}
+	sigs := zoneSignatures(z)
+	for _, rr := range rrs {
+		h := rr.Header()
+		_, signed := sigs[h.Name+"/"+TypeToString[h.Rrtype]]
+		switch {
+		case h.Rrtype == TypeRRSIG:
+		case IsSubDomain("sub.example.org.", h.Name) && h.Rrtype != TypeDS && h.Rrtype != TypeNSEC || h.Rrtype == TypeNS && h.Name != "example.org.":
+			if signed {
+				t.Errorf("delegation or glue is signed: %s", rr)
+			}
+		case !signed:
+			t.Errorf("not signed: %s", rr)
+		}
+	}
+	if len(z.nodes["example.org."].rrsets[TypeDNSKEY]) != 2 {
+		t.Errorf("expected the DNSKEYs at the apex")
+	}
+
+	// The NSEC chain visits every authoritative name once.
+	names := 0
+	for _, n := range z.nodes {
+		if n.rrsets[TypeNSEC] != nil {
+			names++
+		}
+	}
+	name, visited := "example.org.", 0
+	for {
+		nsec := z.nodes[name].rrsets[TypeNSEC][0].(*NSEC)
+		visited++
+		if canonicalNameCompare(name, nsec.NextDomain) >= 0 && nsec.NextDomain != "example.org." {
+			t.Fatalf("NSEC chain out of order at %s", nsec)
+		}
+		if name = nsec.NextDomain; name == "example.org." || visited > names {
+			break
+		}
+	}
+	if visited != names || z.nodes["ns.sub.example.org."].rrsets[TypeNSEC] != nil || z.nodes["ent.example.org."].rrsets[TypeNSEC] != nil {
+		t.Errorf("NSEC chain visited %d of %d names", visited, names)
+	}
+	if s := z.nodes["sub.example.org."].rrsets[TypeNSEC][0].String(); !strings.HasSuffix(s, " NS DS RRSIG NSEC") {
+		t.Errorf("got delegation NSEC %s", s)
+	}
+
+	// The signed zone proves the non-existence of names.
+	req := new(Msg)
+	req.SetQuestion("nope.example.org.", TypeA)
+	req.SetEdns0(4096, true)
+	m := z.Answer(req)
+	if n := verifySigs(t, "nope", m.Ns, keys); n != 3 || len(m.Ns) != 6 {
+		t.Errorf("expected a signed SOA and two signed NSEC records, got %v", m.Ns)
+	}
+}
+
+func TestZoneSignNSEC3(t *testing.T) {
+	for _, optOut := range []bool{false, true} {
+		z, keys := signedTestZone(t, &SignPolicy{NSEC3: true, Salt: "AABB", Iterations: 1, OptOut: optOut}, time.Time{})
+		verifySigs(t, "zone", zoneRecords(z), keys)
+
+		var nsec3 []*NSEC3
+		for _, n := range z.nodes {
+			for _, rr := range n.rrsets[TypeNSEC3] {
+				nsec3 = append(nsec3, rr.(*NSEC3))
+			}
+		}
+		match := func(name string) *NSEC3 {
+			for _, rr := range nsec3 {
+				if rr.Match(name) {
+					return rr
+				}
+			}
+			return nil
+		}
+		for _, name := range []string{"example.org.", "ent.example.org.", "b.ent.example.org.", "a.b.ent.example.org.", "sub.example.org."} {
+			if match(name) == nil {
+				t.Errorf("opt-out %t: no NSEC3 for %s", optOut, name)
+			}
+		}
+		for _, name := range []string{"ns.sub.example.org.", "deep.sub.example.org."} {
+			if match(name) != nil {
+				t.Errorf("opt-out %t: NSEC3 for occluded %s", optOut, name)
+			}
+		}
+		if rr := match("insecure.example.org."); optOut == (rr != nil) {
+			t.Errorf("opt-out %t: got NSEC3 %v for the unsigned delegation", optOut, rr)
+		}
+		if rr := match("ent.example.org."); len(rr.TypeBitMap) != 0 || rr.Flags != btoi8(optOut) {
+			t.Errorf("opt-out %t: got NSEC3 %s for the empty non-terminal", optOut, rr)
+		}
+		if param := z.nodes["example.org."].rrsets[TypeNSEC3PARAM]; len(param) != 1 || param[0].(*NSEC3PARAM).Salt != "AABB" {
+			t.Errorf("opt-out %t: got NSEC3PARAM %v", optOut, param)
+		}
+
+		// Every NSEC3 points to the next hash, the last to the first.
+		next := make(map[string]bool)
+		for _, rr := range nsec3 {
+			next[rr.NextDomain] = true
+		}
+		for _, rr := range nsec3 {
+			if !next[nsec3Hash(rr)] {
+				t.Errorf("opt-out %t: NSEC3 chain is broken at %s", optOut, rr)
+			}
+		}
+	}
+}
+
+func btoi8(b bool) uint8 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestZoneResign(t *testing.T) {
+	now := time.Unix(1500000000, 0)
+	p := &SignPolicy{NSEC3: true, Validity: 20 * 24 * time.Hour, Jitter: 24 * time.Hour}
+	z, keys := signedTestZone(t, p, now)
+	first := zoneSignatures(z)
+	for _, rr := range zoneRecords(z) {
+		if sig, ok := rr.(*RRSIG); ok {
+			if left := time.Duration(sig.Expiration-uint32(now.Unix())) * time.Second; left > p.Validity || left < p.Validity-p.Jitter {
+				t.Errorf("got a validity of %s for %s", left, sig)
+			}
+		}
+	}
+
+	// A day later only the changed RRset is signed again, its NSEC3 record
+	// stays the same.
+	a, _ := NewRR("web.example.org. 3600 IN A 192.0.2.30")
+	z.Insert(a)
+	if err := z.Sign(keys, p, now.Add(24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	second := zoneSignatures(z)
+	changed := 0
+	for k, s := range second {
+		if first[k] != s {
+			changed++
+			if k != "web.example.org./A" {
+				t.Errorf("%s signed again", k)
+			}
+		}
+	}
+	if changed != 1 || len(first) != len(second) {
+		t.Errorf("got %d new signatures, want 1", changed)
+	}
+	verifySigs(t, "zone", zoneRecords(z), keys)
+
+	// Within the refresh window all signatures are renewed.
+	if err := z.Sign(keys, p, now.Add(16*24*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	for k, s := range zoneSignatures(z) {
+		if second[k] == s {
+			t.Errorf("%s not signed again", k)
+		}
+	}
+}
@@ -0,0 +1,355 @@
+package dns
+
+import (
+	"crypto"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const testZone = `$ORIGIN example.org.
+$TTL 3600
+@		IN	SOA	ns1 hostmaster 2018010101 7200 3600 1209600 300
+		IN	NS	ns1
+		IN	NS	ns2.example.net.
+		IN	MX	10 mail
+ns1		IN	A	192.0.2.1
+mail		IN	A	192.0.2.2
+		IN	AAAA	2001:db8::2
+www		IN	CNAME	web
+web		IN	A	192.0.2.3
+loop1		IN	CNAME	loop2
+loop2		IN	CNAME	loop1
+out		IN	CNAME	www.example.net.
+*.wild		IN	TXT	"wildcard"
+*.wild		IN	MX	10 mail
+a.b.ent		IN	A	192.0.2.4
+*.cname		IN	CNAME	web
+sub		IN	NS	ns.sub
+		IN	DS	12345 8 2 0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF
+ns.sub		IN	A	192.0.2.5
+deep.sub	IN	A	192.0.2.6
+alias		IN	DNAME	example.net.
+self		IN	DNAME	web.example.org.
+`
+
+func TestZoneAnswer(t *testing.T) {
+	z, err := ReadZone(strings.NewReader(testZone), "example.org.", "testZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const soa = "example.org.\t300\tIN\tSOA\tns1.example.org. hostmaster.example.org. 2018010101 7200 3600 1209600 300"
+
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		rcode  int
+		aa     bool
+		answer []string
+		ns     []string
+		extra  []string
+	}{
+		{"web.example.org.", TypeA, RcodeSuccess, true,
+			[]string{"web.example.org.\t3600\tIN\tA\t192.0.2.3"}, nil, nil},
+		{"WEB.Example.ORG.", TypeA, RcodeSuccess, true,
+			[]string{"web.example.org.\t3600\tIN\tA\t192.0.2.3"}, nil, nil},
+		{"example.org.", TypeMX, RcodeSuccess, true,
+			[]string{"example.org.\t3600\tIN\tMX\t10 mail.example.org."}, nil,
+			[]string{"mail.example.org.\t3600\tIN\tA\t192.0.2.2", "mail.example.org.\t3600\tIN\tAAAA\t2001:db8::2"}},
+		// NODATA and NXDOMAIN.
+		{"web.example.org.", TypeAAAA, RcodeSuccess, true, nil, []string{soa}, nil},
+		{"nope.example.org.", TypeA, RcodeNameError, true, nil, []string{soa}, nil},
+		{"ent.example.org.", TypeA, RcodeSuccess, true, nil, []string{soa}, nil},
+		{"b.ent.example.org.", TypeA, RcodeSuccess, true, nil, []string{soa}, nil},
+		{"c.ent.example.org.", TypeA, RcodeNameError, true, nil, []string{soa}, nil},
+		// CNAME chains.
+		{"www.example.org.", TypeA, RcodeSuccess, true,
+			[]string{"www.example.org.\t3600\tIN\tCNAME\tweb.example.org.", "web.example.org.\t3600\tIN\tA\t192.0.2.3"}, nil, nil},
+		{"www.example.org.", TypeCNAME, RcodeSuccess, true,
+			[]string{"www.example.org.\t3600\tIN\tCNAME\tweb.example.org."}, nil, nil},
+		{"out.example.org.", TypeA, RcodeSuccess, true,
+			[]string{"out.example.org.\t3600\tIN\tCNAME\twww.example.net."}, nil, nil},
+		{"loop1.example.org.", TypeA, RcodeSuccess, true,
+			[]string{"loop1.example.org.\t3600\tIN\tCNAME\tloop2.example.org.", "loop2.example.org.\t3600\tIN\tCNAME\tloop1.example.org."}, nil, nil},
+		// Wildcards.
+		{"x.wild.example.org.", TypeTXT, RcodeSuccess, true,
+			[]string{"x.wild.example.org.\t3600\tIN\tTXT\t\"wildcard\""}, nil, nil},
+		{"y.x.wild.example.org.", TypeMX, RcodeSuccess, true,
+			[]string{"y.x.wild.example.org.\t3600\tIN\tMX\t10 mail.example.org."}, nil,
+			[]string{"mail.example.org.\t3600\tIN\tA\t192.0.2.2", "mail.example.org.\t3600\tIN\tAAAA\t2001:db8::2"}},
+		{"x.wild.example.org.", TypeA, RcodeSuccess, true, nil, []string{soa}, nil},
+		{"wild.example.org.", TypeTXT, RcodeSuccess, true, nil, []string{soa}, nil},
+		{"x.cname.example.org.", TypeA, RcodeSuccess, true,
+			[]string{"x.cname.example.org.\t3600\tIN\tCNAME\tweb.example.org.", "web.example.org.\t3600\tIN\tA\t192.0.2.3"}, nil, nil},
+		// Referrals, the DS is answered by the parent.
+		{"sub.example.org.", TypeA, RcodeSuccess, false, nil,
+			[]string{"sub.example.org.\t3600\tIN\tNS\tns.sub.example.org."},
+			[]string{"ns.sub.example.org.\t3600\tIN\tA\t192.0.2.5"}},
+		{"deep.sub.example.org.", TypeA, RcodeSuccess, false, nil,
+			[]string{"sub.example.org.\t3600\tIN\tNS\tns.sub.example.org."},
+			[]string{"ns.sub.example.org.\t3600\tIN\tA\t192.0.2.5"}},
+		{"sub.example.org.", TypeDS, RcodeSuccess, true,
+			[]string{"sub.example.org.\t3600\tIN\tDS\t12345 8 2 0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789ABCDEF"}, nil, nil},
+		// DNAME.
+		{"a.alias.example.org.", TypeA, RcodeSuccess, true,
+			[]string{"alias.example.org.\t3600\tIN\tDNAME\texample.net.", "a.alias.example.org.\t3600\tIN\tCNAME\ta.example.net."}, nil, nil},
+		{"alias.example.org.", TypeDNAME, RcodeSuccess, true,
+			[]string{"alias.example.org.\t3600\tIN\tDNAME\texample.net."}, nil, nil},
+		{"alias.example.org.", TypeA, RcodeSuccess, true, nil, []string{soa}, nil},
+		{"x.self.example.org.", TypeA, RcodeNameError, true,
+			[]string{"self.example.org.\t3600\tIN\tDNAME\tweb.example.org.", "x.self.example.org.\t3600\tIN\tCNAME\tx.web.example.org."}, []string{soa}, nil},
+		// Out of zone.
+		{"example.net.", TypeA, RcodeRefused, false, nil, nil, nil},
+	}
+
+	for _, tc := range tests {
+		req := new(Msg)
+		req.SetQuestion(tc.qname, tc.qtype)
+		m := z.Answer(req)
+		name := tc.qname + "/" + TypeToString[tc.qtype]
+		if m.Rcode != tc.rcode {
+			t.Errorf("%s: got rcode %s, want %s", name, RcodeToString[m.Rcode], RcodeToString[tc.rcode])
+		}
+		if m.Authoritative != tc.aa {
+			t.Errorf("%s: got aa %t, want %t", name, m.Authoritative, tc.aa)
+		}
+		checkSection(t, name+" answer", m.Answer, tc.answer)
+		checkSection(t, name+" authority", m.Ns, tc.ns)
+		checkSection(t, name+" additional", m.Extra, tc.extra)
+	}
+
+	// The zone is not signed, the DO bit only adds the OPT record.
+	req := new(Msg)
+	req.SetQuestion("nope.example.org.", TypeA)
+	req.SetEdns0(4096, true)
+	m := z.Answer(req)
+	checkSection(t, "nope.example.org./A with DO authority", m.Ns, []string{soa})
+}
+
+func checkSection(t *testing.T, name string, rrs []RR, want []string) {
+	if len(rrs) != len(want) {
+		t.Errorf("%s: got %v, want %v", name, rrs, want)
+		return
+	}
+	for i := range rrs {
+		if rrs[i].String() != want[i] {
+			t.Errorf("%s: got %q, want %q", name, rrs[i].String(), want[i])
+		}
+	}
+}
+
+func TestZoneInsert(t *testing.T) {
+	z := NewZone("Example.ORG")
+	if z.Origin != "example.org." {
+		t.Errorf("got origin %s", z.Origin)
+	}
+	a, _ := NewRR("www.example.org. 3600 IN A 192.0.2.1")
+	if err := z.Insert(a); err != nil {
+		t.Fatal(err)
+	}
+	// Duplicates are ignored, even with another TTL.
+	dup, _ := NewRR("WWW.example.org. 60 IN A 192.0.2.1")
+	if err := z.Insert(dup); err != nil {
+		t.Fatal(err)
+	}
+	req := new(Msg)
+	req.SetQuestion("www.example.org.", TypeA)
+	if m := z.Answer(req); len(m.Answer) != 1 {
+		t.Errorf("expected a single A record, got %v", m.Answer)
+	}
+
+	out, _ := NewRR("www.example.net. 3600 IN A 192.0.2.1")
+	if err := z.Insert(out); err == nil {
+		t.Error("expected an error for an out of zone record")
+	}
+}
+
+func TestZoneSig0Key(t *testing.T) {
+	z := NewZone("example.org.")
+	k := &KEY{DNSKEY{Hdr: RR_Header{Name: "client.example.org.", Rrtype: TypeKEY, Class: ClassINET}, Algorithm: ECDSAP256SHA256, Protocol: 3}}
+	if _, err := k.Generate(256); err != nil {
+		t.Fatal(err)
+	}
+	z.Insert(k)
+	if got, err := z.Sig0Key("client.example.org.", ECDSAP256SHA256, k.KeyTag()); err != nil || got != k {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if _, err := z.Sig0Key("client.example.org.", ECDSAP256SHA256, k.KeyTag()+1); err != ErrKey {
+		t.Errorf("got %v for another key tag, want %v", err, ErrKey)
+	}
+}
+
+const testSignedZone = `$ORIGIN example.org.
+$TTL 3600
+@		IN	SOA	ns1 hostmaster 2018010101 7200 3600 1209600 300
+		IN	NS	ns1
+		IN	NSEC	ns1 NS SOA RRSIG NSEC
+ns1		IN	A	192.0.2.1
+		IN	NSEC	sub A RRSIG NSEC
+sub		IN	NS	ns.sub
+		IN	NSEC	*.wild NS RRSIG NSEC
+ns.sub		IN	A	192.0.2.5
+*.wild		IN	TXT	"wildcard"
+		IN	NSEC	example.org. TXT RRSIG NSEC
+`
+
+func TestZoneAnswerNSEC(t *testing.T) {
+	z, err := ReadZone(strings.NewReader(testSignedZone), "example.org.", "testSignedZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := &DNSKEY{Hdr: RR_Header{Name: "example.org.", Rrtype: TypeDNSKEY, Class: ClassINET}, Flags: 257, Protocol: 3, Algorithm: ECDSAP256SHA256}
+	priv, err := key.Generate(256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, _ := NewRR("ns1.example.org. 3600 IN A 192.0.2.1")
+	sig := &RRSIG{Hdr: RR_Header{Name: "ns1.example.org.", Rrtype: TypeRRSIG, Class: ClassINET, Ttl: 3600}, KeyTag: key.KeyTag(), SignerName: key.Hdr.Name, Algorithm: key.Algorithm, Expiration: 2000000000, Inception: 1500000000}
+	if err := sig.Sign(priv.(crypto.Signer), []RR{a}); err != nil {
+		t.Fatal(err)
+	}
+	z.Insert(sig)
+
+	tests := []struct {
+		qname string
+		qtype uint16
+		do    bool
+		rcode int
+		nsec  []string // the owners of the NSEC records in the authority section
+	}{
+		{"ns1.example.org.", TypeA, false, RcodeSuccess, nil},
+		{"ns1.example.org.", TypeA, true, RcodeSuccess, nil},
+		// NXDOMAIN, proving the name and the wildcard do not exist.
+		{"b.example.org.", TypeA, false, RcodeNameError, nil},
+		{"b.example.org.", TypeA, true, RcodeNameError, []string{"example.org."}},
+		{"z.example.org.", TypeA, true, RcodeNameError, []string{"*.wild.example.org.", "example.org."}},
+		// NODATA.
+		{"ns1.example.org.", TypeAAAA, true, RcodeSuccess, []string{"ns1.example.org."}},
+		// Wildcard answer and wildcard NODATA.
+		{"x.wild.example.org.", TypeTXT, true, RcodeSuccess, []string{"*.wild.example.org."}},
+		{"x.wild.example.org.", TypeA, true, RcodeSuccess, []string{"*.wild.example.org."}},
+		// Referral to an unsigned delegation, proving there is no DS.
+		{"www.sub.example.org.", TypeA, true, RcodeSuccess, []string{"sub.example.org."}},
+	}
+	for _, tc := range tests {
+		req := new(Msg)
+		req.SetQuestion(tc.qname, tc.qtype)
+		req.SetEdns0(4096, tc.do)
+		m := z.Answer(req)
+		name := tc.qname + "/" + TypeToString[tc.qtype]
+		if m.Rcode != tc.rcode {
+			t.Errorf("%s: got rcode %s, want %s", name, RcodeToString[m.Rcode], RcodeToString[tc.rcode])
+		}
+		if opt := m.IsEdns0(); opt == nil || opt.Do() != tc.do {
+			t.Errorf("%s: expected an OPT with the DO bit %t", name, tc.do)
+		}
+		var nsec []string
+		for _, rr := range m.Ns {
+			if rr.Header().Rrtype == TypeNSEC {
+				nsec = append(nsec, rr.Header().Name)
+			}
+		}
+		if strings.Join(nsec, " ") != strings.Join(tc.nsec, " ") {
+			t.Errorf("%s: got NSEC records for %v, want %v", name, nsec, tc.nsec)
+		}
+		if tc.qname == "ns1.example.org." && tc.qtype == TypeA {
+			if want := 1 + btoi(tc.do); len(m.Answer) != want {
+				t.Errorf("%s: got %d records in the answer, want %d", name, len(m.Answer), want)
+			}
+		}
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestZoneAnswerNSEC3(t *testing.T) {
+	z, err := ReadZone(strings.NewReader(testZone), "example.org.", "testZone")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Build the NSEC3 chain over the names in the zone, without opt-out.
+	z.Insert(&NSEC3PARAM{Hdr: RR_Header{Name: "example.org.", Rrtype: TypeNSEC3PARAM, Class: ClassINET}, Hash: SHA1, Iterations: 0, Salt: "AB"})
+	var hashes []string
+	for name := range z.nodes {
+		if !IsSubDomain("sub.example.org.", name) || name == "sub.example.org." {
+			hashes = append(hashes, HashName(name, SHA1, 0, "AB"))
+		}
+	}
+	sort.Strings(hashes)
+	for i, h := range hashes {
+		z.Insert(&NSEC3{Hdr: RR_Header{Name: strings.ToLower(h) + ".example.org.", Rrtype: TypeNSEC3, Class: ClassINET, Ttl: 300},
+			Hash: SHA1, Iterations: 0, SaltLength: 1, Salt: "AB", HashLength: 20, NextDomain: hashes[(i+1)%len(hashes)]})
+	}
+
+	type proof struct {
+		match bool
+		name  string
+	}
+	tests := []struct {
+		qname  string
+		qtype  uint16
+		proofs []proof
+	}{
+		// Closest encloser, next closer name and wildcard.
+		{"nope.example.org.", TypeA, []proof{{true, "example.org."}, {false, "nope.example.org."}, {false, "*.example.org."}}},
+		{"x.y.ent.example.org.", TypeA, []proof{{true, "ent.example.org."}, {false, "y.ent.example.org."}, {false, "*.ent.example.org."}}},
+		{"web.example.org.", TypeAAAA, []proof{{true, "web.example.org."}}},
+		{"ent.example.org.", TypeA, []proof{{true, "ent.example.org."}}},
+		{"x.wild.example.org.", TypeTXT, []proof{{false, "x.wild.example.org."}}},
+		{"y.x.wild.example.org.", TypeA, []proof{{true, "wild.example.org."}, {false, "x.wild.example.org."}, {true, "*.wild.example.org."}}},
+		{"sub.example.org.", TypeDS, nil},
+	}
+	for _, tc := range tests {
+		req := new(Msg)
+		req.SetQuestion(tc.qname, tc.qtype)
+		req.SetEdns0(4096, true)
+		m := z.Answer(req)
+		name := tc.qname + "/" + TypeToString[tc.qtype]
+		var nsec3 []*NSEC3
+		for _, rr := range m.Ns {
+			if rr, ok := rr.(*NSEC3); ok {
+				nsec3 = append(nsec3, rr)
+			}
+		}
+		// A single NSEC3 may be part of more than one proof, but each
+		// must be needed.
+		used := make(map[*NSEC3]bool)
+		for _, p := range tc.proofs {
+			found := false
+			for _, rr := range nsec3 {
+				if p.match && rr.Match(p.name) || !p.match && rr.Cover(p.name) {
+					found, used[rr] = true, true
+				}
+			}
+			if !found {
+				t.Errorf("%s: no NSEC3 that matches (%t) or covers %s", name, p.match, p.name)
+			}
+		}
+		if len(used) != len(nsec3) {
+			t.Errorf("%s: got %d NSEC3 records, %d needed", name, len(nsec3), len(used))
+		}
+	}
+}
+
+func TestCanonicalNameCompare(t *testing.T) {
+	// The example of RFC 4034 section 6.1.
+	names := []string{"example.", "a.example.", "yljkjljk.a.example.", "Z.a.example.", "zABC.a.EXAMPLE.", "z.example.", "\\001.z.example.", "*.z.example.", "\\200.z.example."}
+	for i := range names {
+		for j := range names {
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := canonicalNameCompare(names[i], names[j]); got != want {
+				t.Errorf("compare %s and %s: got %d, want %d", names[i], names[j], got, want)
+			}
+		}
+	}
+}
@@ -12,7 +12,9 @@ var TypeToRR = map[uint16]func() RR{
 	TypeA:          func() RR { return new(A) },
 	TypeAAAA:       func() RR { return new(AAAA) },
 	TypeAFSDB:      func() RR { return new(AFSDB) },
+	TypeAMTRELAY:   func() RR { return new(AMTRELAY) },
 	TypeANY:        func() RR { return new(ANY) },
+	TypeAPL:        func() RR { return new(APL) },
 	TypeAVC:        func() RR { return new(AVC) },
 	TypeCAA:        func() RR { return new(CAA) },
 	TypeCDNSKEY:    func() RR { return new(CDNSKEY) },
@@ -25,6 +27,7 @@ var TypeToRR = map[uint16]func() RR{
 	TypeDNAME:      func() RR { return new(DNAME) },
 	TypeDNSKEY:     func() RR { return new(DNSKEY) },
 	TypeDS:         func() RR { return new(DS) },
+	TypeDSYNC:      func() RR { return new(DSYNC) },
 	TypeEID:        func() RR { return new(EID) },
 	TypeEUI48:      func() RR { return new(EUI48) },
 	TypeEUI64:      func() RR { return new(EUI64) },
@@ -32,6 +35,8 @@ var TypeToRR = map[uint16]func() RR{
 	TypeGPOS:       func() RR { return new(GPOS) },
 	TypeHINFO:      func() RR { return new(HINFO) },
 	TypeHIP:        func() RR { return new(HIP) },
+	TypeHTTPS:      func() RR { return new(HTTPS) },
+	TypeIPSECKEY:   func() RR { return new(IPSECKEY) },
 	TypeKEY:        func() RR { return new(KEY) },
 	TypeKX:         func() RR { return new(KX) },
 	TypeL32:        func() RR { return new(L32) },
@@ -58,6 +63,7 @@ var TypeToRR = map[uint16]func() RR{
 	TypeOPT:        func() RR { return new(OPT) },
 	TypePTR:        func() RR { return new(PTR) },
 	TypePX:         func() RR { return new(PX) },
+	TypeRESINFO:    func() RR { return new(RESINFO) },
 	TypeRKEY:       func() RR { return new(RKEY) },
 	TypeRP:         func() RR { return new(RP) },
 	TypeRRSIG:      func() RR { return new(RRSIG) },
@@ -68,6 +74,7 @@ var TypeToRR = map[uint16]func() RR{
 	TypeSPF:        func() RR { return new(SPF) },
 	TypeSRV:        func() RR { return new(SRV) },
 	TypeSSHFP:      func() RR { return new(SSHFP) },
+	TypeSVCB:       func() RR { return new(SVCB) },
 	TypeTA:         func() RR { return new(TA) },
 	TypeTALINK:     func() RR { return new(TALINK) },
 	TypeTKEY:       func() RR { return new(TKEY) },
@@ -77,7 +84,9 @@ var TypeToRR = map[uint16]func() RR{
 	TypeUID:        func() RR { return new(UID) },
 	TypeUINFO:      func() RR { return new(UINFO) },
 	TypeURI:        func() RR { return new(URI) },
+	TypeWALLET:     func() RR { return new(WALLET) },
 	TypeX25:        func() RR { return new(X25) },
+	TypeZONEMD:     func() RR { return new(ZONEMD) },
 }
 
 // TypeToString is a map of strings for each RR type.
@@ -85,7 +94,9 @@ var TypeToString = map[uint16]string{
 	TypeA:          "A",
 	TypeAAAA:       "AAAA",
 	TypeAFSDB:      "AFSDB",
+	TypeAMTRELAY:   "AMTRELAY",
 	TypeANY:        "ANY",
+	TypeAPL:        "APL",
 	TypeATMA:       "ATMA",
 	TypeAVC:        "AVC",
 	TypeAXFR:       "AXFR",
@@ -100,6 +111,7 @@ var TypeToString = map[uint16]string{
 	TypeDNAME:      "DNAME",
 	TypeDNSKEY:     "DNSKEY",
 	TypeDS:         "DS",
+	TypeDSYNC:      "DSYNC",
 	TypeEID:        "EID",
 	TypeEUI48:      "EUI48",
 	TypeEUI64:      "EUI64",
@@ -107,6 +119,8 @@ var TypeToString = map[uint16]string{
 	TypeGPOS:       "GPOS",
 	TypeHINFO:      "HINFO",
 	TypeHIP:        "HIP",
+	TypeHTTPS:      "HTTPS",
+	TypeIPSECKEY:   "IPSECKEY",
 	TypeISDN:       "ISDN",
 	TypeIXFR:       "IXFR",
 	TypeKEY:        "KEY",
@@ -133,12 +147,14 @@ var TypeToString = map[uint16]string{
 	TypeNSEC3:      "NSEC3",
 	TypeNSEC3PARAM: "NSEC3PARAM",
 	TypeNULL:       "NULL",
+	TypeNXNAME:     "NXNAME",
 	TypeNXT:        "NXT",
 	TypeNone:       "None",
 	TypeOPENPGPKEY: "OPENPGPKEY",
 	TypeOPT:        "OPT",
 	TypePTR:        "PTR",
 	TypePX:         "PX",
+	TypeRESINFO:    "RESINFO",
 	TypeRKEY:       "RKEY",
 	TypeRP:         "RP",
 	TypeRRSIG:      "RRSIG",
@@ -150,6 +166,7 @@ var TypeToString = map[uint16]string{
 	TypeSPF:        "SPF",
 	TypeSRV:        "SRV",
 	TypeSSHFP:      "SSHFP",
+	TypeSVCB:       "SVCB",
 	TypeTA:         "TA",
 	TypeTALINK:     "TALINK",
 	TypeTKEY:       "TKEY",
@@ -160,14 +177,18 @@ var TypeToString = map[uint16]string{
 	TypeUINFO:      "UINFO",
 	TypeUNSPEC:     "UNSPEC",
 	TypeURI:        "URI",
+	TypeWALLET:     "WALLET",
 	TypeX25:        "X25",
+	TypeZONEMD:     "ZONEMD",
 	TypeNSAPPTR:    "NSAP-PTR",
 }
 
 func (rr *A) Header() *RR_Header          { return &rr.Hdr }
 func (rr *AAAA) Header() *RR_Header       { return &rr.Hdr }
 func (rr *AFSDB) Header() *RR_Header      { return &rr.Hdr }
+func (rr *AMTRELAY) Header() *RR_Header   { return &rr.Hdr }
 func (rr *ANY) Header() *RR_Header        { return &rr.Hdr }
+func (rr *APL) Header() *RR_Header        { return &rr.Hdr }
 func (rr *AVC) Header() *RR_Header        { return &rr.Hdr }
 func (rr *CAA) Header() *RR_Header        { return &rr.Hdr }
 func (rr *CDNSKEY) Header() *RR_Header    { return &rr.Hdr }
@@ -180,6 +201,7 @@ func (rr *DLV) Header() *RR_Header        { return &rr.Hdr }
 func (rr *DNAME) Header() *RR_Header      { return &rr.Hdr }
 func (rr *DNSKEY) Header() *RR_Header     { return &rr.Hdr }
 func (rr *DS) Header() *RR_Header         { return &rr.Hdr }
+func (rr *DSYNC) Header() *RR_Header      { return &rr.Hdr }
 func (rr *EID) Header() *RR_Header        { return &rr.Hdr }
 func (rr *EUI48) Header() *RR_Header      { return &rr.Hdr }
 func (rr *EUI64) Header() *RR_Header      { return &rr.Hdr }
@@ -187,6 +209,8 @@ func (rr *GID) Header() *RR_Header        { return &rr.Hdr }
 func (rr *GPOS) Header() *RR_Header       { return &rr.Hdr }
 func (rr *HINFO) Header() *RR_Header      { return &rr.Hdr }
 func (rr *HIP) Header() *RR_Header        { return &rr.Hdr }
+func (rr *HTTPS) Header() *RR_Header      { return &rr.Hdr }
+func (rr *IPSECKEY) Header() *RR_Header   { return &rr.Hdr }
 func (rr *KEY) Header() *RR_Header        { return &rr.Hdr }
 func (rr *KX) Header() *RR_Header         { return &rr.Hdr }
 func (rr *L32) Header() *RR_Header        { return &rr.Hdr }
@@ -213,6 +237,7 @@ func (rr *OPENPGPKEY) Header() *RR_Header { return &rr.Hdr }
 func (rr *OPT) Header() *RR_Header        { return &rr.Hdr }
 func (rr *PTR) Header() *RR_Header        { return &rr.Hdr }
 func (rr *PX) Header() *RR_Header         { return &rr.Hdr }
+func (rr *RESINFO) Header() *RR_Header    { return &rr.Hdr }
 func (rr *RFC3597) Header() *RR_Header    { return &rr.Hdr }
 func (rr *RKEY) Header() *RR_Header       { return &rr.Hdr }
 func (rr *RP) Header() *RR_Header         { return &rr.Hdr }
@@ -224,6 +249,7 @@ func (rr *SOA) Header() *RR_Header        { return &rr.Hdr }
 func (rr *SPF) Header() *RR_Header        { return &rr.Hdr }
 func (rr *SRV) Header() *RR_Header        { return &rr.Hdr }
 func (rr *SSHFP) Header() *RR_Header      { return &rr.Hdr }
+func (rr *SVCB) Header() *RR_Header       { return &rr.Hdr }
 func (rr *TA) Header() *RR_Header         { return &rr.Hdr }
 func (rr *TALINK) Header() *RR_Header     { return &rr.Hdr }
 func (rr *TKEY) Header() *RR_Header       { return &rr.Hdr }
@@ -233,7 +259,9 @@ func (rr *TXT) Header() *RR_Header        { return &rr.Hdr }
 func (rr *UID) Header() *RR_Header        { return &rr.Hdr }
 func (rr *UINFO) Header() *RR_Header      { return &rr.Hdr }
 func (rr *URI) Header() *RR_Header        { return &rr.Hdr }
+func (rr *WALLET) Header() *RR_Header     { return &rr.Hdr }
 func (rr *X25) Header() *RR_Header        { return &rr.Hdr }
+func (rr *ZONEMD) Header() *RR_Header     { return &rr.Hdr }
 
 // len() functions
 func (rr *A) len() int {
@@ -252,10 +280,31 @@ func (rr *AFSDB) len() int {
 	l += len(rr.Hostname) + 1
 	return l
 }
+func (rr *AMTRELAY) len() int {
+	l := rr.Hdr.len()
+	l++ // Precedence
+	l++ // GatewayType
+	switch rr.GatewayType & 0x7f {
+	case IPSECGatewayIPv4:
+		l += net.IPv4len
+	case IPSECGatewayIPv6:
+		l += net.IPv6len
+	case IPSECGatewayHost:
+		l += len(rr.GatewayHost) + 1
+	}
+	return l
+}
 func (rr *ANY) len() int {
 	l := rr.Hdr.len()
 	return l
 }
+func (rr *APL) len() int {
+	l := rr.Hdr.len()
+	for _, x := range rr.Prefixes {
+		l += x.len()
+	}
+	return l
+}
 func (rr *AVC) len() int {
 	l := rr.Hdr.len()
 	for _, x := range rr.Txt {
@@ -309,6 +358,14 @@ func (rr *DS) len() int {
 	l += len(rr.Digest)/2 + 1
 	return l
 }
+func (rr *DSYNC) len() int {
+	l := rr.Hdr.len()
+	l += 2 // Type
+	l++    // Scheme
+	l += 2 // Port
+	l += len(rr.Target) + 1
+	return l
+}
 func (rr *EID) len() int {
 	l := rr.Hdr.len()
 	l += len(rr.Endpoint)/2 + 1
@@ -354,6 +411,22 @@ func (rr *HIP) len() int {
 	}
 	return l
 }
+func (rr *IPSECKEY) len() int {
+	l := rr.Hdr.len()
+	l++ // Precedence
+	l++ // GatewayType
+	l++ // Algorithm
+	switch rr.GatewayType & 0x7f {
+	case IPSECGatewayIPv4:
+		l += net.IPv4len
+	case IPSECGatewayIPv6:
+		l += net.IPv6len
+	case IPSECGatewayHost:
+		l += len(rr.GatewayHost) + 1
+	}
+	l += base64.StdEncoding.DecodedLen(len(rr.PublicKey))
+	return l
+}
 func (rr *KX) len() int {
 	l := rr.Hdr.len()
 	l += 2 // Preference
@@ -490,6 +563,13 @@ func (rr *PX) len() int {
 	l += len(rr.Mapx400) + 1
 	return l
 }
+func (rr *RESINFO) len() int {
+	l := rr.Hdr.len()
+	for _, x := range rr.Txt {
+		l += len(x) + 1
+	}
+	return l
+}
 func (rr *RFC3597) len() int {
 	l := rr.Hdr.len()
 	l += len(rr.Rdata)/2 + 1
@@ -569,6 +649,15 @@ func (rr *SSHFP) len() int {
 	l += len(rr.FingerPrint)/2 + 1
 	return l
 }
+func (rr *SVCB) len() int {
+	l := rr.Hdr.len()
+	l += 2 // Priority
+	l += len(rr.Target) + 1
+	for _, x := range rr.Value {
+		l += 4 + x.len()
+	}
+	return l
+}
 func (rr *TA) len() int {
 	l := rr.Hdr.len()
 	l += 2 // KeyTag
@@ -641,11 +730,26 @@ func (rr *URI) len() int {
 	l += len(rr.Target)
 	return l
 }
+func (rr *WALLET) len() int {
+	l := rr.Hdr.len()
+	for _, x := range rr.Txt {
+		l += len(x) + 1
+	}
+	return l
+}
 func (rr *X25) len() int {
 	l := rr.Hdr.len()
 	l += len(rr.PSDNAddress) + 1
 	return l
 }
+func (rr *ZONEMD) len() int {
+	l := rr.Hdr.len()
+	l += 4 // Serial
+	l++    // Scheme
+	l++    // Hash
+	l += len(rr.Digest)/2 + 1
+	return l
+}
 
 // copy() functions
 func (rr *A) copy() RR {
@@ -657,9 +761,19 @@ func (rr *AAAA) copy() RR {
 func (rr *AFSDB) copy() RR {
 	return &AFSDB{*rr.Hdr.copyHeader(), rr.Subtype, rr.Hostname}
 }
+func (rr *AMTRELAY) copy() RR {
+	return &AMTRELAY{*rr.Hdr.copyHeader(), rr.Precedence, rr.GatewayType, copyIP(rr.GatewayAddr), rr.GatewayHost}
+}
 func (rr *ANY) copy() RR {
 	return &ANY{*rr.Hdr.copyHeader()}
 }
+func (rr *APL) copy() RR {
+	Prefixes := make([]APLPrefix, len(rr.Prefixes))
+	for i, e := range rr.Prefixes {
+		Prefixes[i] = e.copy()
+	}
+	return &APL{*rr.Hdr.copyHeader(), Prefixes}
+}
 func (rr *AVC) copy() RR {
 	Txt := make([]string, len(rr.Txt))
 	copy(Txt, rr.Txt)
@@ -691,6 +805,9 @@ func (rr *DNSKEY) copy() RR {
 func (rr *DS) copy() RR {
 	return &DS{*rr.Hdr.copyHeader(), rr.KeyTag, rr.Algorithm, rr.DigestType, rr.Digest}
 }
+func (rr *DSYNC) copy() RR {
+	return &DSYNC{*rr.Hdr.copyHeader(), rr.Type, rr.Scheme, rr.Port, rr.Target}
+}
 func (rr *EID) copy() RR {
 	return &EID{*rr.Hdr.copyHeader(), rr.Endpoint}
 }
@@ -714,6 +831,9 @@ func (rr *HIP) copy() RR {
 	copy(RendezvousServers, rr.RendezvousServers)
 	return &HIP{*rr.Hdr.copyHeader(), rr.HitLength, rr.PublicKeyAlgorithm, rr.PublicKeyLength, rr.Hit, rr.PublicKey, RendezvousServers}
 }
+func (rr *IPSECKEY) copy() RR {
+	return &IPSECKEY{*rr.Hdr.copyHeader(), rr.Precedence, rr.GatewayType, rr.Algorithm, copyIP(rr.GatewayAddr), rr.GatewayHost, rr.PublicKey}
+}
 func (rr *KX) copy() RR {
 	return &KX{*rr.Hdr.copyHeader(), rr.Preference, rr.Exchanger}
 }
@@ -797,6 +917,11 @@ func (rr *PTR) copy() RR {
 func (rr *PX) copy() RR {
 	return &PX{*rr.Hdr.copyHeader(), rr.Preference, rr.Map822, rr.Mapx400}
 }
+func (rr *RESINFO) copy() RR {
+	Txt := make([]string, len(rr.Txt))
+	copy(Txt, rr.Txt)
+	return &RESINFO{*rr.Hdr.copyHeader(), Txt}
+}
 func (rr *RFC3597) copy() RR {
 	return &RFC3597{*rr.Hdr.copyHeader(), rr.Rdata}
 }
@@ -829,6 +954,13 @@ func (rr *SRV) copy() RR {
 func (rr *SSHFP) copy() RR {
 	return &SSHFP{*rr.Hdr.copyHeader(), rr.Algorithm, rr.Type, rr.FingerPrint}
 }
+func (rr *SVCB) copy() RR {
+	Value := make([]SVCBKeyValue, len(rr.Value))
+	for i, e := range rr.Value {
+		Value[i] = e.copy()
+	}
+	return &SVCB{*rr.Hdr.copyHeader(), rr.Priority, rr.Target, Value}
+}
 func (rr *TA) copy() RR {
 	return &TA{*rr.Hdr.copyHeader(), rr.KeyTag, rr.Algorithm, rr.DigestType, rr.Digest}
 }
@@ -858,6 +990,14 @@ func (rr *UINFO) copy() RR {
 func (rr *URI) copy() RR {
 	return &URI{*rr.Hdr.copyHeader(), rr.Priority, rr.Weight, rr.Target}
 }
+func (rr *WALLET) copy() RR {
+	Txt := make([]string, len(rr.Txt))
+	copy(Txt, rr.Txt)
+	return &WALLET{*rr.Hdr.copyHeader(), Txt}
+}
 func (rr *X25) copy() RR {
 	return &X25{*rr.Hdr.copyHeader(), rr.PSDNAddress}
 }
+func (rr *ZONEMD) copy() RR {
+	return &ZONEMD{*rr.Hdr.copyHeader(), rr.Serial, rr.Scheme, rr.Hash, rr.Digest}
+}